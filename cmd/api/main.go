@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
+	"url-shortener/internal/analytics"
 	"url-shortener/internal/api"
+	"url-shortener/internal/blocklist"
 	"url-shortener/internal/config"
+	"url-shortener/internal/ratelimit"
 	"url-shortener/internal/repositories"
+	"url-shortener/internal/validation"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -20,6 +25,13 @@ import (
 // @contact.name Bruno Piffer Stephan
 // @contact.email brunopstephan@gmail.com
 
+// @securityDefinitions.basic BasicAuth
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description API key issued through /admin/keys, as "Bearer <key>"
+
 func main() {
 	if err := run(); err != nil {
 		slog.Error("Failed initializing the application", "error", err)
@@ -31,14 +43,76 @@ func main() {
 func run() error {
 	redisAddr := config.Config.RedisHost + ":" + config.Config.RedisPort
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
-		Password: config.Config.RedisPwd,
-		DB:       config.Config.RedisDb,
+	storage, err := repositories.NewStorage(repositories.StorageOptions{
+		Backend: config.Config.StorageBackend,
+		Redis: &redis.Options{
+			Addr:     redisAddr,
+			Password: config.Config.RedisPwd,
+			DB:       config.Config.RedisDb,
+		},
+		BoltPath: config.Config.BoltPath,
+		Codes:    config.Config.Codes,
 	})
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+	slog.Info("Storage backend selected", "backend", config.Config.StorageBackend)
+
+	if migrator, ok := storage.Urls.(repositories.LegacyMigrator); ok {
+		migrated, err := migrator.MigrateLegacyURLs(context.Background())
+		if err != nil {
+			return err
+		}
+		if migrated > 0 {
+			slog.Info("Legacy urls migrated to links", "count", migrated)
+		}
+	}
+
+	recorder := analytics.NewRecorder(storage.Stats, storage.Urls, config.Config.AnalyticsBufferSize, config.Config.CountryHeader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go repositories.RunExpirySweeper(ctx, storage.Urls, config.Config.ExpirySweepInterval)
+	go recorder.Run(ctx)
 
-	urlRepository := repositories.NewUrlRepository(rdb)
-	handler := api.NewHandler(urlRepository)
+	// limits only hold across replicas when they share the redis backend
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if storage.Redis != nil {
+		limiter = ratelimit.NewRedisLimiter(storage.Redis)
+	}
+
+	var blocked validation.Blocklist
+	if config.Config.BlocklistPath != "" {
+		list, err := blocklist.New(config.Config.BlocklistPath)
+		if err != nil {
+			return err
+		}
+		go list.Run(ctx, config.Config.BlocklistReloadInterval)
+		blocked = list
+		slog.Info("Blocklist loaded", "path", config.Config.BlocklistPath)
+	}
+
+	handler := api.NewHandler(storage.Urls, storage.Stats, storage.Keys, storage.Tenants, storage.Domains, recorder, limiter, blocked, api.Options{
+		Port:          config.Config.Port,
+		BasicAuthUser: config.Config.BasicAuthUser,
+		BasicAuthPwd:  config.Config.BasicAuthPwd,
+		BaseURL:       config.Config.BaseURL,
+		URLPolicy:     config.Config.URLPolicy,
+		CountryHeader: config.Config.CountryHeader,
+
+		RedirectStatus:  config.Config.RedirectStatus,
+		RedirectNoCache: config.Config.RedirectNoCache,
+		LegacyAPIRoutes: config.Config.LegacyAPIRoutes,
+		TrustedProxies:  config.Config.TrustedProxies,
+
+		RateLimitShorten:     config.Config.RateLimitShorten,
+		RateLimitShortenBulk: config.Config.RateLimitShortenBulk,
+		RateLimitRedirect:    config.Config.RateLimitRedirect,
+		RateLimitAdmin:       config.Config.RateLimitAdmin,
+		RateLimitUnlock:      config.Config.RateLimitUnlock,
+		RateLimitUnlockLink:  config.Config.RateLimitUnlockLink,
+	})
 	s := http.Server{
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
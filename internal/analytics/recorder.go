@@ -0,0 +1,166 @@
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"url-shortener/internal/repositories"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	batchSize     = 100
+	flushInterval = time.Second
+	flushTimeout  = 5 * time.Second
+)
+
+// Tracker records redirects without blocking the caller.
+type Tracker interface {
+	Track(r *http.Request, code string)
+}
+
+type variantKey struct{}
+
+// WithVariant returns the request carrying the variant the visitor was sent
+// to, recorded along with the click by Track.
+func WithVariant(r *http.Request, variant string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), variantKey{}, variant))
+}
+
+// variantFrom returns the variant set by WithVariant, empty when none was.
+func variantFrom(ctx context.Context) string {
+	variant, _ := ctx.Value(variantKey{}).(string)
+	return variant
+}
+
+// ClickCounter keeps the click count shown on each link.
+type ClickCounter interface {
+	IncrementClicks(ctx context.Context, clicks map[string]int64) error
+}
+
+// Recorder is a Tracker that buffers clicks in memory and writes them to
+// the stats store in batches from a background goroutine, see Run.
+type Recorder struct {
+	store         repositories.StatsContract
+	counter       ClickCounter
+	clicks        chan repositories.Click
+	countryHeader string
+}
+
+// NewRecorder creates a Recorder holding up to bufferSize pending clicks,
+// reading the visitor country from countryHeader (set by the reverse proxy).
+func NewRecorder(store repositories.StatsContract, counter ClickCounter, bufferSize int, countryHeader string) *Recorder {
+	return &Recorder{
+		store:         store,
+		counter:       counter,
+		clicks:        make(chan repositories.Click, bufferSize),
+		countryHeader: countryHeader,
+	}
+}
+
+// Track enqueues a click built from the request. When the buffer is full the
+// click is dropped, so a slow stats store never slows down redirects.
+func (rec *Recorder) Track(r *http.Request, code string) {
+	click := repositories.Click{
+		Code:      code,
+		Timestamp: time.Now(),
+		Referrer:  referrerHost(r),
+		UserAgent: r.UserAgent(),
+		Country:   r.Header.Get(rec.countryHeader),
+		RequestID: middleware.GetReqID(r.Context()),
+		VisitorID: visitorID(r),
+		Variant:   variantFrom(r.Context()),
+	}
+
+	select {
+	case rec.clicks <- click:
+	default:
+		slog.Warn("analytics buffer full, dropping click", "code", code)
+	}
+}
+
+// Run writes the buffered clicks until ctx is canceled, flushing what is
+// left in the buffer before returning.
+func (rec *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]repositories.Click, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		// not derived from ctx so the last batch is still written on shutdown
+		flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+
+		if err := rec.store.RecordClicks(flushCtx, batch); err != nil {
+			slog.Error("error recording clicks", "error", err, "count", len(batch))
+		}
+
+		counts := map[string]int64{}
+		for _, click := range batch {
+			counts[click.Code]++
+		}
+		if err := rec.counter.IncrementClicks(flushCtx, counts); err != nil {
+			slog.Error("error incrementing clicks", "error", err, "count", len(batch))
+		}
+
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case click := <-rec.clicks:
+			batch = append(batch, click)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case click := <-rec.clicks:
+					batch = append(batch, click)
+					if len(batch) >= batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// referrerHost is the host of the Referer header, lowercased, so the
+// referrers of a link are counted per site rather than per page.
+func referrerHost(r *http.Request) string {
+	referrer, err := url.Parse(r.Referer())
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(referrer.Hostname())
+}
+
+// visitorID hashes the client address and user agent so unique visitors can
+// be counted without storing IPs.
+func visitorID(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	sum := sha256.Sum256([]byte(host + "|" + r.UserAgent()))
+	return hex.EncodeToString(sum[:16])
+}
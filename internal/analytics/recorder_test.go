@@ -0,0 +1,86 @@
+package analytics
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"url-shortener/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStatsStore struct {
+	mu     sync.Mutex
+	clicks []repositories.Click
+}
+
+func (f *fakeStatsStore) RecordClicks(ctx context.Context, clicks []repositories.Click) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clicks = append(f.clicks, clicks...)
+	return nil
+}
+
+func (f *fakeStatsStore) GetStats(ctx context.Context, code string) (repositories.URLStats, error) {
+	return repositories.URLStats{}, nil
+}
+
+type fakeClickCounter struct {
+	mu     sync.Mutex
+	clicks map[string]int64
+}
+
+func (f *fakeClickCounter) IncrementClicks(ctx context.Context, clicks map[string]int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for code, count := range clicks {
+		f.clicks[code] += count
+	}
+	return nil
+}
+
+func TestRecorder_TrackAndFlushOnShutdown(t *testing.T) {
+	store := &fakeStatsStore{}
+	counter := &fakeClickCounter{clicks: map[string]int64{}}
+	recorder := NewRecorder(store, counter, 10, "X-Country-Code")
+
+	req := httptest.NewRequest("GET", "/api/abc", nil)
+	req.Header.Set("Referer", "https://News.example/some/page?utm=1")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Country-Code", "BR")
+
+	recorder.Track(req, "abc")
+	recorder.Track(WithVariant(req, "b"), "abc")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder.Run(ctx)
+
+	assert.Len(t, store.clicks, 2)
+	click := store.clicks[0]
+	assert.Equal(t, "abc", click.Code)
+	assert.Equal(t, "news.example", click.Referrer)
+	assert.Equal(t, "test-agent", click.UserAgent)
+	assert.Equal(t, "BR", click.Country)
+	assert.NotEmpty(t, click.VisitorID)
+	assert.Equal(t, click.VisitorID, store.clicks[1].VisitorID)
+	assert.Empty(t, click.Variant)
+	assert.Equal(t, "b", store.clicks[1].Variant)
+	assert.Equal(t, map[string]int64{"abc": 2}, counter.clicks)
+}
+
+func TestRecorder_DropsWhenBufferIsFull(t *testing.T) {
+	store := &fakeStatsStore{}
+	recorder := NewRecorder(store, &fakeClickCounter{clicks: map[string]int64{}}, 1, "X-Country-Code")
+
+	req := httptest.NewRequest("GET", "/api/abc", nil)
+	recorder.Track(req, "abc")
+	recorder.Track(req, "abc")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder.Run(ctx)
+
+	assert.Len(t, store.clicks, 1)
+}
@@ -2,11 +2,17 @@ package api
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
-	"url-shortener/internal/config"
+	"strings"
+	"url-shortener/internal/analytics"
+	"url-shortener/internal/auth"
 	"url-shortener/internal/handlers"
+	"url-shortener/internal/ratelimit"
 	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+	"url-shortener/internal/validation"
 
 	_ "url-shortener/docs"
 
@@ -15,14 +21,39 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func NewHandler(db repositories.UrlContract) http.Handler {
+// Options holds the settings the routes are built with, see the matching
+// config fields.
+type Options struct {
+	Port          int
+	BasicAuthUser string
+	BasicAuthPwd  string
+	BaseURL       string
+	URLPolicy     validation.Policy
+	CountryHeader string
+
+	RedirectStatus  int
+	RedirectNoCache bool
+	LegacyAPIRoutes bool
+	TrustedProxies  []*net.IPNet
+
+	RateLimitShorten     ratelimit.Limit
+	RateLimitShortenBulk ratelimit.Limit
+	RateLimitRedirect    ratelimit.Limit
+	RateLimitAdmin       ratelimit.Limit
+	RateLimitUnlock      ratelimit.Limit
+	RateLimitUnlockLink  ratelimit.Limit
+}
+
+// NewHandler builds the routes, blocked being nil when no blocklist is set.
+func NewHandler(db repositories.UrlContract, stats repositories.StatsContract, keys repositories.ApiKeyContract, tenants repositories.TenantContract, domains repositories.DomainContract, tracker analytics.Tracker, limiter ratelimit.Limiter, blocked validation.Blocklist, opts Options) http.Handler {
 	r := chi.NewMux()
 
+	r.Use(ratelimit.RealIP(opts.TrustedProxies))
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Logger)
 	r.Use(middleware.RequestID)
 
-	port := config.Config.Port
+	port := opts.Port
 	_url := "http://localhost:" + strconv.Itoa(port) + "/swagger/doc.json"
 	fmt.Println("Swagger UI is available at " + _url)
 
@@ -30,21 +61,108 @@ func NewHandler(db repositories.UrlContract) http.Handler {
 		httpSwagger.URL(_url), //The url pointing to API definition
 	))
 
+	authenticator := auth.NewAuthenticator(keys, opts.BasicAuthUser, opts.BasicAuthPwd)
+	require := authenticator.Require
+
+	policy := opts.URLPolicy
+	policy.Blocklist = blocked
+
+	limit := func(route string, l ratelimit.Limit) func(http.Handler) http.Handler {
+		return ratelimit.Middleware(limiter, route, l)
+	}
+
+	r.Get("/healthz", handlers.HandleHealthz())
+
+	// short links are looked up on the domain the request was sent to
+	resolveHost := handlers.ResolveHost(domains)
+
+	// links serves the short links, at the root
+	links := func(r chi.Router) {
+		r.With(limit("redirect", opts.RateLimitRedirect), resolveHost).
+			Get("/{code}", handlers.HandleGetShortenedURL(db, tracker, blocked, handlers.RedirectOptions{
+				Status:        opts.RedirectStatus,
+				NoCache:       opts.RedirectNoCache,
+				CountryHeader: opts.CountryHeader,
+			}))
+		r.With(limit("redirect", opts.RateLimitRedirect), resolveHost).
+			Post("/{code}", handlers.HandleUnlockShortenedURL(db, tracker, blocked, limiter, opts.RateLimitUnlock, opts.RateLimitUnlockLink, opts.CountryHeader))
+	}
+
+	// jsonAPI serves the JSON API, under /api/v1
+	jsonAPI := func(r chi.Router) {
+		r.With(require(auth.ScopeLinksCreate), limit("shorten", opts.RateLimitShorten)).
+			Post("/shorten", handlers.HandlePostShortenedURL(db, domains, policy))
+		r.With(require(auth.ScopeLinksCreate), limit("shorten_bulk", opts.RateLimitShortenBulk)).
+			Post("/shorten/bulk", handlers.HandlePostBulkShortenedURL(db, domains, policy))
+		r.With(limit("qr", opts.RateLimitRedirect), resolveHost).
+			Get("/{code}/qr", handlers.HandleGetQRCode(db, blocked, opts.BaseURL))
+	}
+
+	r.Group(links)
 	r.Route("/api", func(r chi.Router) {
-		r.Post("/shorten", handlers.HandlePostShortenedURL(db))
-		r.Get("/{code}", handlers.HandleGetShortenedURL(db))
+		r.Route("/v1", jsonAPI)
+
+		// the links and the API as served before the versioned API, links
+		// shortened back then keep redirecting
+		if opts.LegacyAPIRoutes {
+			jsonAPI(r)
+			links(r)
+		}
 	})
 
-	r.Group(func(r chi.Router) {
-		r.Use(middleware.BasicAuth("Restricted", map[string]string{
-			config.Config.BasicAuthUser: config.Config.BasicAuthPwd,
-		}))
+	// tenant API keys only reach the links of their tenant
+	ownLink := handlers.RequireOwnLink(db)
 
-		r.Route("/admin", func(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(require(auth.ScopeLinksRead), limit("admin", opts.RateLimitAdmin))
 			r.Get("/all", handlers.HandleGetAllUrls(db))
-			r.Delete("/{code}", handlers.HandleDeleteShortenedURL(db))
-			r.Put("/{code}", handlers.HandleUpdateShortenedURL(db))
+			r.With(ownLink).Get("/{code}", handlers.HandleGetLink(db))
+			r.With(ownLink).Get("/{code}/stats", handlers.HandleGetURLStats(db, stats))
+			r.With(ownLink).Get("/{code}/rules", handlers.HandleGetLinkRules(db))
+
+			r.Get("/tenants/{id}", handlers.HandleGetTenant(tenants))
+			r.Get("/domains", handlers.HandleGetDomains(domains))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(require(auth.ScopeLinksAdmin), limit("admin", opts.RateLimitAdmin))
+			r.With(ownLink).Delete("/{code}", handlers.HandleDeleteShortenedURL(db))
+			r.With(ownLink).Put("/{code}", handlers.HandleUpdateShortenedURL(db, domains, policy))
+			r.With(ownLink).Put("/{code}/rules", handlers.HandlePutLinkRules(db, domains, policy))
+
+			r.Post("/keys", handlers.HandlePostApiKey(keys, tenants))
+			r.Get("/keys", handlers.HandleGetApiKeys(keys))
+			r.Delete("/keys/{id}", handlers.HandleDeleteApiKey(keys))
+
+			r.With(auth.RequireNoTenant).Post("/tenants", handlers.HandlePostTenant(tenants))
+			r.With(auth.RequireNoTenant).Get("/tenants", handlers.HandleGetTenants(tenants))
+			r.With(auth.RequireNoTenant).Put("/tenants/{id}", handlers.HandlePutTenant(tenants))
+
+			r.With(auth.RequireNoTenant).Post("/domains", handlers.HandlePostDomain(domains))
+			r.With(auth.RequireNoTenant).Delete("/domains/{name}", handlers.HandleDeleteDomain(domains))
+			r.With(auth.RequireNoTenant).Put("/domains/{name}/default", handlers.HandlePutDefaultDomain(domains))
 		})
 	})
+
+	checkReservedRoutes(r)
 	return r
 }
+
+// checkReservedRoutes panics on a top-level route that isn't reserved, as
+// it would make the link with the same code unreachable.
+func checkReservedRoutes(r chi.Routes) {
+	err := chi.Walk(r, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment == "" || strings.HasPrefix(segment, "{") {
+			return nil
+		}
+		if !utils.IsReserved(segment) {
+			return fmt.Errorf("route %s %s isn't reserved, add %q to the reserved aliases", method, route, segment)
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"url-shortener/internal/ratelimit"
+	"url-shortener/internal/repositories"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopTracker struct{}
+
+func (noopTracker) Track(r *http.Request, code string) {}
+
+// newTestHandler builds the routes over a memory storage holding the link
+// abc123.
+func newTestHandler(t *testing.T, legacyAPIRoutes bool) http.Handler {
+	t.Helper()
+
+	storage, err := repositories.NewStorage(repositories.StorageOptions{Backend: repositories.BackendMemory})
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	require.NoError(t, storage.Urls.SaveURLWithCode(context.Background(), "abc123", repositories.Link{URL: "https://example.com"}))
+
+	return NewHandler(storage.Urls, storage.Stats, storage.Keys, storage.Tenants, storage.Domains, noopTracker{}, ratelimit.NewMemoryLimiter(), nil, Options{
+		BasicAuthUser:   "admin",
+		BasicAuthPwd:    "secret",
+		BaseURL:         "http://localhost:9000",
+		RedirectStatus:  http.StatusMovedPermanently,
+		LegacyAPIRoutes: legacyAPIRoutes,
+	})
+}
+
+func serve(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name            string
+		legacyAPIRoutes bool
+		method          string
+		target          string
+		body            string
+		expectedCode    int
+	}{
+		{name: "link at the root", method: "GET", target: "/abc123", expectedCode: http.StatusMovedPermanently},
+		{name: "unknown link at the root", method: "GET", target: "/nope123", expectedCode: http.StatusNotFound},
+		{name: "shorten", method: "POST", target: "/api/v1/shorten", body: `{"url":"https://example.com/new"}`, expectedCode: http.StatusCreated},
+		{name: "qr", method: "GET", target: "/api/v1/abc123/qr", expectedCode: http.StatusOK},
+		{name: "healthz", method: "GET", target: "/healthz", expectedCode: http.StatusOK},
+		{name: "legacy link", legacyAPIRoutes: true, method: "GET", target: "/api/abc123", expectedCode: http.StatusMovedPermanently},
+		{name: "legacy shorten", legacyAPIRoutes: true, method: "POST", target: "/api/shorten", body: `{"url":"https://example.com/new"}`, expectedCode: http.StatusCreated},
+		{name: "legacy qr", legacyAPIRoutes: true, method: "GET", target: "/api/abc123/qr", expectedCode: http.StatusOK},
+		{name: "versioned shorten along legacy routes", legacyAPIRoutes: true, method: "POST", target: "/api/v1/shorten", body: `{"url":"https://example.com/new"}`, expectedCode: http.StatusCreated},
+		{name: "legacy link off", method: "GET", target: "/api/abc123", expectedCode: http.StatusNotFound},
+		{name: "legacy shorten off", method: "POST", target: "/api/shorten", body: `{"url":"https://example.com/new"}`, expectedCode: http.StatusNotFound},
+		{name: "legacy qr off", method: "GET", target: "/api/abc123/qr", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(newTestHandler(t, tt.legacyAPIRoutes), tt.method, tt.target, tt.body)
+
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedCode == http.StatusMovedPermanently {
+				assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestReservedAliasRejected(t *testing.T) {
+	handler := newTestHandler(t, true)
+
+	for _, alias := range []string{"api", "admin", "Swagger", "healthz"} {
+		t.Run(alias, func(t *testing.T) {
+			w := serve(handler, "POST", "/api/v1/shorten", `{"url":"https://example.com","alias":"`+alias+`"}`)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestCheckReservedRoutes(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := chi.NewRouter()
+	r.Get("/{code}", noop)
+	r.Get("/healthz", noop)
+	r.Route("/admin", func(r chi.Router) {
+		r.Get("/all", noop)
+	})
+	assert.NotPanics(t, func() { checkReservedRoutes(r) })
+
+	r.Get("/status", noop)
+	assert.PanicsWithError(t, `route GET /status isn't reserved, add "status" to the reserved aliases`, func() { checkReservedRoutes(r) })
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateScopes(t *testing.T) {
+	scopes, err := ValidateScopes([]string{ScopeLinksRead, ScopeLinksCreate, ScopeLinksRead})
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeLinksRead, ScopeLinksCreate}, scopes)
+
+	_, err = ValidateScopes(nil)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	_, err = ValidateScopes([]string{"links:delete"})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+}
+
+func TestHasScope(t *testing.T) {
+	assert.True(t, HasScope([]string{ScopeLinksRead}, ScopeLinksRead))
+	assert.False(t, HasScope([]string{ScopeLinksRead}, ScopeLinksCreate))
+	assert.True(t, HasScope([]string{ScopeLinksAdmin}, ScopeLinksCreate))
+	assert.False(t, HasScope(nil, ScopeLinksRead))
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, apiKey, err := GenerateKey("ci", []string{ScopeLinksRead}, time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, HashKey(key), apiKey.Hash)
+	assert.NotContains(t, apiKey.Hash, key)
+	assert.Equal(t, key[:visiblePrefixLength], apiKey.Prefix)
+	assert.Equal(t, "ci", apiKey.Name)
+	assert.NotEmpty(t, apiKey.ID)
+
+	other, _, err := GenerateKey("ci", []string{ScopeLinksRead}, time.Now())
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("open sesame")
+	require.NoError(t, err)
+	assert.NotEqual(t, "open sesame", hash)
+
+	ok, err := CheckPassword(hash, "open sesame")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = CheckPassword(hash, "open sesamE")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = HashPassword("abc")
+	assert.ErrorIs(t, err, ErrPasswordLength)
+	_, err = HashPassword(strings.Repeat("a", PasswordMaxLength+1))
+	assert.ErrorIs(t, err, ErrPasswordLength)
+
+	_, err = CheckPassword("not a hash", "open sesame")
+	assert.Error(t, err)
+}
+
+func TestAuthenticatorRequire(t *testing.T) {
+	ctx := context.Background()
+	keys := repositories.NewMemoryApiKeyRepository()
+
+	readKey, readApiKey, err := GenerateKey("reader", []string{ScopeLinksRead}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, keys.SaveApiKey(ctx, readApiKey))
+
+	revokedKey, revokedApiKey, err := GenerateKey("revoked", []string{ScopeLinksAdmin}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, keys.SaveApiKey(ctx, revokedApiKey))
+	_, err = keys.RevokeApiKey(ctx, revokedApiKey.ID)
+	require.NoError(t, err)
+
+	var principal Principal
+	handler := NewAuthenticator(keys, "admin", "secret").Require(ScopeLinksRead)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ = PrincipalFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	tests := []struct {
+		name          string
+		header        string
+		value         string
+		basicUser     string
+		basicPwd      string
+		expectedCode  int
+		expectedName  string
+		expectedKeyID string
+	}{
+		{name: "no credentials", expectedCode: http.StatusUnauthorized},
+		{name: "bearer key", header: "Authorization", value: "Bearer " + readKey, expectedCode: http.StatusOK, expectedName: "reader", expectedKeyID: readApiKey.ID},
+		{name: "x-api-key header", header: "X-API-Key", value: readKey, expectedCode: http.StatusOK, expectedName: "reader", expectedKeyID: readApiKey.ID},
+		{name: "unknown key", header: "Authorization", value: "Bearer usk_unknown", expectedCode: http.StatusUnauthorized},
+		{name: "revoked key", header: "X-API-Key", value: revokedKey, expectedCode: http.StatusUnauthorized},
+		{name: "basic auth", basicUser: "admin", basicPwd: "secret", expectedCode: http.StatusOK, expectedName: "admin"},
+		{name: "wrong basic auth", basicUser: "admin", basicPwd: "wrong", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal = Principal{}
+			req := httptest.NewRequest("GET", "/admin/all", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			if tt.basicUser != "" {
+				req.SetBasicAuth(tt.basicUser, tt.basicPwd)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedName, principal.Name)
+			assert.Equal(t, tt.expectedKeyID, principal.KeyID)
+		})
+	}
+}
+
+func TestAuthenticatorRequire_MissingScope(t *testing.T) {
+	keys := repositories.NewMemoryApiKeyRepository()
+	key, apiKey, err := GenerateKey("reader", []string{ScopeLinksRead}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, keys.SaveApiKey(context.Background(), apiKey))
+
+	handler := NewAuthenticator(keys, "", "").Require(ScopeLinksAdmin)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("handler must not be called")
+		}),
+	)
+
+	req := httptest.NewRequest("DELETE", "/admin/abc", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"missing scope links:admin"}`, w.Body.String())
+}
+
+func TestRequireNoTenant(t *testing.T) {
+	ctx := context.Background()
+	keys := repositories.NewMemoryApiKeyRepository()
+
+	rootKey, rootApiKey, err := GenerateKey("root", []string{ScopeLinksAdmin}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, keys.SaveApiKey(ctx, rootApiKey))
+
+	tenantKey, tenantApiKey, err := GenerateKey("acme", []string{ScopeLinksAdmin}, time.Now())
+	require.NoError(t, err)
+	tenantApiKey.Tenant = "acme"
+	require.NoError(t, keys.SaveApiKey(ctx, tenantApiKey))
+
+	var principal Principal
+	handler := NewAuthenticator(keys, "admin", "secret").Require(ScopeLinksAdmin)(RequireNoTenant(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ = PrincipalFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}),
+	))
+
+	req := httptest.NewRequest("POST", "/admin/tenants", nil)
+	req.Header.Set("Authorization", "Bearer "+rootKey)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, principal.Tenant)
+
+	req = httptest.NewRequest("POST", "/admin/tenants", nil)
+	req.SetBasicAuth("admin", "secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("POST", "/admin/tenants", nil)
+	req.Header.Set("Authorization", "Bearer "+tenantKey)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"not allowed for tenant credentials"}`, w.Body.String())
+}
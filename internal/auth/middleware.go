@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+)
+
+// Principal is who made an authenticated request.
+type Principal struct {
+	// Name is the API key name, or the basic auth username.
+	Name string
+	// KeyID is empty when authenticated through basic auth.
+	KeyID  string
+	Scopes []string
+	// Tenant limits the principal to the links of the tenant, empty for the
+	// basic auth admin and the keys allowed across tenants.
+	Tenant string
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the principal set by Authenticator.Require.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// Authenticator checks the API key of the requests, sent either as a
+// "Authorization: Bearer" or a "X-API-Key" header. The basic auth admin
+// credentials are still accepted and allow every scope.
+type Authenticator struct {
+	keys      repositories.ApiKeyContract
+	basicUser string
+	basicPwd  string
+}
+
+// NewAuthenticator returns an authenticator, basic auth is disabled when
+// basicUser is empty.
+func NewAuthenticator(keys repositories.ApiKeyContract, basicUser string, basicPwd string) *Authenticator {
+	return &Authenticator{keys: keys, basicUser: basicUser, basicPwd: basicPwd}
+}
+
+// Require rejects the requests that aren't authenticated with 401, and the
+// ones whose credentials don't allow the scope with 403.
+func (a *Authenticator) Require(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := a.authenticate(r)
+			if err != nil {
+				if errors.Is(err, errUnauthorized) {
+					w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+					utils.SendJSON(w, utils.ApiResponse{Error: "unauthorized"}, http.StatusUnauthorized)
+					return
+				}
+				slog.Error("error authenticating request", "error", err)
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "something went wrong",
+				}, http.StatusInternalServerError)
+				return
+			}
+
+			if !HasScope(principal.Scopes, scope) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "missing scope " + scope,
+				}, http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), principalKey{}, principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireNoTenant rejects with 403 the principals limited to a tenant, for
+// the routes managing the whole deployment. It must run after Require.
+func RequireNoTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := PrincipalFromContext(r.Context()); !ok || principal.Tenant != "" {
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "not allowed for tenant credentials",
+			}, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+var errUnauthorized = errors.New("unauthorized")
+
+func (a *Authenticator) authenticate(r *http.Request) (Principal, error) {
+	if key := requestKey(r); key != "" {
+		apiKey, err := a.keys.GetApiKeyByHash(r.Context(), HashKey(key))
+		if err != nil {
+			if errors.Is(err, repositories.ErrKeyNotFound) {
+				return Principal{}, errUnauthorized
+			}
+			return Principal{}, err
+		}
+		if apiKey.Revoked() {
+			return Principal{}, errUnauthorized
+		}
+
+		return Principal{Name: apiKey.Name, KeyID: apiKey.ID, Scopes: apiKey.Scopes, Tenant: apiKey.Tenant}, nil
+	}
+
+	user, pwd, ok := r.BasicAuth()
+	if ok && a.basicUser != "" &&
+		subtle.ConstantTimeCompare([]byte(user), []byte(a.basicUser)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(pwd), []byte(a.basicPwd)) == 1 {
+		return Principal{Name: user, Scopes: []string{ScopeLinksAdmin}}, nil
+	}
+
+	return Principal{}, errUnauthorized
+}
+
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
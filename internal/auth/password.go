@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordMinLength = 4
+	// PasswordMaxLength is the most bcrypt reads, longer passwords would be
+	// silently truncated.
+	PasswordMaxLength = 72
+)
+
+var ErrPasswordLength = fmt.Errorf("password must be %d to %d bytes long", PasswordMinLength, PasswordMaxLength)
+
+// HashPassword returns the bcrypt hash a link password is stored as. Unlike
+// API keys, passwords are chosen by people, so a slow hash is needed.
+func HashPassword(password string) (string, error) {
+	if len(password) < PasswordMinLength || len(password) > PasswordMaxLength {
+		return "", ErrPasswordLength
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether the password matches the hash.
+func CheckPassword(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check password: %w", err)
+}
@@ -0,0 +1,89 @@
+package blocklist
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Blocklist holds the rules of a local file, swapped atomically when the file
+// changes, see Run.
+type Blocklist struct {
+	path  string
+	rules atomic.Pointer[Rules]
+
+	// mu serializes the reloads, modTime being the one of the loaded file
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// New loads the rules of the file at path.
+func New(path string) (*Blocklist, error) {
+	b := &Blocklist{path: path}
+	if _, err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Blocked returns the rule blocking the URL, if any.
+func (b *Blocklist) Blocked(raw string) (string, bool) {
+	return b.rules.Load().Match(raw)
+}
+
+// Reload loads the file again when it changed since the last load. On error
+// the current rules are kept.
+func (b *Blocklist) Reload() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return false, err
+	}
+	if b.rules.Load() != nil && info.ModTime().Equal(b.modTime) && info.Size() == b.size {
+		return false, nil
+	}
+
+	f, err := os.Open(b.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	rules, err := Parse(f)
+	if err != nil {
+		return false, err
+	}
+
+	b.rules.Store(rules)
+	b.modTime = info.ModTime()
+	b.size = info.Size()
+	return true, nil
+}
+
+// Run checks the file for changes every interval until ctx is canceled.
+func (b *Blocklist) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := b.Reload()
+			if err != nil {
+				slog.Error("error reloading blocklist, keeping the current rules", "path", b.path, "error", err)
+				continue
+			}
+			if reloaded {
+				slog.Info("blocklist reloaded", "path", b.path, "rules", b.rules.Load().Len())
+			}
+		}
+	}
+}
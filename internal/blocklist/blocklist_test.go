@@ -0,0 +1,91 @@
+package blocklist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	rules, err := Parse(strings.NewReader(`
+# phishing feed
+domain:Evil.com
+bad.org
+url:HTTPS://Example.com:443/login?b=2&a=1
+regex:^https?://[^/]+/.*\.exe$
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 4, rules.Len())
+
+	tests := []struct {
+		url     string
+		rule    string
+		blocked bool
+	}{
+		{url: "https://evil.com", rule: "domain:evil.com", blocked: true},
+		{url: "https://login.evil.com/x", rule: "domain:evil.com", blocked: true},
+		{url: "https://notevil.com"},
+		{url: "http://bad.org", rule: "domain:bad.org", blocked: true},
+		{url: "https://example.com/login?a=1&b=2", rule: "url:https://example.com/login?a=1&b=2", blocked: true},
+		{url: "https://example.com/login"},
+		{url: "https://files.example.com/setup.exe", rule: `regex:^https?://[^/]+/.*\.exe$`, blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rule, blocked := rules.Match(tt.url)
+			assert.Equal(t, tt.blocked, blocked)
+			assert.Equal(t, tt.rule, rule)
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse(strings.NewReader("evil.com\nregex:(unclosed\n"))
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = Parse(strings.NewReader("url:\n"))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestBlocklistReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("evil.com\n"), 0o644))
+
+	list, err := New(path)
+	require.NoError(t, err)
+
+	_, blocked := list.Blocked("https://evil.com")
+	assert.True(t, blocked)
+
+	reloaded, err := list.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "an unchanged file is not reloaded")
+
+	require.NoError(t, os.WriteFile(path, []byte("evil.com\nworse.com\n"), 0o644))
+	reloaded, err = list.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	_, blocked = list.Blocked("https://worse.com")
+	assert.True(t, blocked)
+
+	// an invalid file keeps the current rules
+	require.NoError(t, os.WriteFile(path, []byte("regex:(\n"), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	_, err = list.Reload()
+	assert.Error(t, err)
+
+	_, blocked = list.Blocked("https://worse.com")
+	assert.True(t, blocked)
+}
+
+func TestNew_MissingFile(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
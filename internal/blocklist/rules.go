@@ -0,0 +1,107 @@
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"url-shortener/internal/utils"
+)
+
+// Rules is a parsed blocklist. Each line of the source holds one rule:
+//
+//	domain:example.com        the domain and its subdomains
+//	url:https://a.com/x       this exact URL, compared once normalized
+//	regex:^https?://.*\.zip$  URLs matching the expression
+//
+// A line without a prefix is a domain rule, blank lines and lines starting
+// with # are skipped.
+type Rules struct {
+	domains map[string]bool
+	urls    map[string]bool
+	regexes []*regexp.Regexp
+}
+
+// Parse reads the rules, failing on the first invalid line.
+func Parse(r io.Reader) (*Rules, error) {
+	rules := &Rules{
+		domains: map[string]bool{},
+		urls:    map[string]bool{},
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		kind, pattern, found := strings.Cut(entry, ":")
+		if !found || !isKind(kind) {
+			kind, pattern = "domain", entry
+		}
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("line %d: empty %s rule", line, kind)
+		}
+
+		switch kind {
+		case "domain":
+			rules.domains[strings.TrimSuffix(strings.ToLower(pattern), ".")] = true
+		case "url":
+			rules.urls[utils.NormalizeURL(pattern)] = true
+		case "regex":
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rules.regexes = append(rules.regexes, re)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func isKind(kind string) bool {
+	return kind == "domain" || kind == "url" || kind == "regex"
+}
+
+// Len returns the number of rules.
+func (r *Rules) Len() int {
+	return len(r.domains) + len(r.urls) + len(r.regexes)
+}
+
+// Match returns the rule blocking the URL, if any.
+func (r *Rules) Match(raw string) (string, bool) {
+	if r.urls[utils.NormalizeURL(raw)] {
+		return "url:" + raw, true
+	}
+
+	if u, err := url.Parse(raw); err == nil {
+		host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+		// walk up the labels so a domain rule covers its subdomains
+		for host != "" {
+			if r.domains[host] {
+				return "domain:" + host, true
+			}
+			_, parent, found := strings.Cut(host, ".")
+			if !found {
+				break
+			}
+			host = parent
+		}
+	}
+
+	for _, re := range r.regexes {
+		if re.MatchString(raw) {
+			return "regex:" + re.String(), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,209 @@
+// Package codegen generates the codes of new links.
+package codegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"url-shortener/internal/utils"
+)
+
+const (
+	StrategyRandom  = "random"
+	StrategyCounter = "counter"
+	StrategyHash    = "hash"
+
+	DefaultLength = 8
+	MinLength     = 4
+	MaxLength     = 32
+
+	// growAfter is the attempt from which codes grow one character longer.
+	growAfter = 2
+)
+
+// Alphabet presets, codes are made of their characters.
+const (
+	Base62 = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// Friendly leaves out the characters mistaken for each other when read
+	// aloud or handwritten: 0/o, 1/i/l and the uppercase letters.
+	Friendly  = "23456789abcdefghjkmnpqrstuvwxyz"
+	Lowercase = "abcdefghijklmnopqrstuvwxyz0123456789"
+	Numeric   = "0123456789"
+)
+
+// Presets maps the preset names to their alphabet.
+var Presets = map[string]string{
+	"base62":    Base62,
+	"friendly":  Friendly,
+	"lowercase": Lowercase,
+	"numeric":   Numeric,
+}
+
+var (
+	// ErrSpaceExhausted is returned when a strategy has no codes left to give.
+	ErrSpaceExhausted = errors.New("code space exhausted")
+	// ErrAlphabetInvalid is returned for a custom alphabet that isn't made
+	// of 2 to 64 distinct letters, numbers, - or _.
+	ErrAlphabetInvalid = errors.New("code alphabet must be a preset or 2 to 64 distinct letters, numbers, - or _")
+)
+
+// Generator proposes codes for new links. Storages only save a candidate
+// if its code is free, in the same atomic step, and ask for the next
+// attempt on collisions, so no strategy can overwrite an existing code.
+// Strategies never propose the reserved words of the routes either.
+type Generator interface {
+	// Generate returns the candidate code of the given attempt, from 0, to
+	// save a link to target.
+	Generate(ctx context.Context, target string, attempt int) (string, error)
+}
+
+// NextFunc returns the next value of a counter shared by every replica,
+// starting at 1.
+type NextFunc func(ctx context.Context) (uint64, error)
+
+type Options struct {
+	// Strategy is one of StrategyRandom, StrategyCounter or StrategyHash.
+	Strategy string
+	// Alphabet is the name of one of the Presets or the characters of the
+	// codes, Base62 when empty.
+	Alphabet string
+	// Length is the length of the codes, which grow past it as the
+	// keyspace fills up.
+	Length int
+	// Key obfuscates counter codes, which are sequential when empty.
+	Key string
+}
+
+// New returns the generator of the strategy, next backing the counter one.
+func New(opts Options, next NextFunc) (Generator, error) {
+	alphabet, err := ParseAlphabet(opts.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Length == 0 {
+		opts.Length = DefaultLength
+	}
+	if opts.Length < MinLength || opts.Length > MaxLength {
+		return nil, fmt.Errorf("code length must be between %d and %d", MinLength, MaxLength)
+	}
+
+	switch opts.Strategy {
+	case StrategyRandom, "":
+		return NewRandom(alphabet, opts.Length), nil
+	case StrategyCounter:
+		return NewCounter(next, alphabet, opts.Length, opts.Key), nil
+	case StrategyHash:
+		return NewHash(alphabet, opts.Length), nil
+	}
+
+	return nil, fmt.Errorf("unknown code strategy %q", opts.Strategy)
+}
+
+// ParseAlphabet returns the alphabet of a preset name, or the value itself
+// as a custom alphabet. Custom alphabets are limited to the characters
+// allowed in aliases, so codes are safe in paths.
+func ParseAlphabet(value string) (string, error) {
+	if value == "" {
+		return Base62, nil
+	}
+	if alphabet, ok := Presets[strings.ToLower(value)]; ok {
+		return alphabet, nil
+	}
+
+	if len(value) < 2 || len(value) > 64 {
+		return "", ErrAlphabetInvalid
+	}
+	seen := map[rune]bool{}
+	for _, c := range value {
+		valid := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+		if !valid || seen[c] {
+			return "", ErrAlphabetInvalid
+		}
+		seen[c] = true
+	}
+
+	return value, nil
+}
+
+// growingLength is the length of the random and hash codes. A link whose
+// first growAfter candidates were all taken is the sign that the keyspace
+// of the current length is filling up, so from then on every code is one
+// character longer. It starts over from the configured length with the
+// process, growing back on the first collisions.
+type growingLength struct {
+	length atomic.Int64
+}
+
+func newGrowingLength(length int) *growingLength {
+	g := &growingLength{}
+	g.length.Store(int64(length))
+	return g
+}
+
+func (g *growingLength) get(attempt int) int {
+	length := g.length.Load()
+	if attempt == growAfter && length < MaxLength {
+		// concurrent saves seeing the same length only grow it once
+		g.length.CompareAndSwap(length, length+1)
+		length = g.length.Load()
+	}
+	return int(length)
+}
+
+// readCode reads a code of the given length from src, the reserved ones a
+// route would shadow being skipped for the next code of the stream.
+func readCode(src io.Reader, alphabet string, length int) (string, error) {
+	for {
+		code, err := readChars(src, alphabet, length)
+		if err != nil || !utils.IsReserved(code) {
+			return code, err
+		}
+	}
+}
+
+// readChars reads length characters of the alphabet from src. Bytes past
+// the last multiple of the alphabet size are skipped, keeping every
+// character equally likely.
+func readChars(src io.Reader, alphabet string, length int) (string, error) {
+	limit := 256 - 256%len(alphabet)
+	code := make([]byte, 0, length)
+	buf := make([]byte, length*2)
+	for len(code) < length {
+		if _, err := io.ReadFull(src, buf); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			code = append(code, alphabet[int(b)%len(alphabet)])
+			if len(code) == length {
+				break
+			}
+		}
+	}
+
+	return string(code), nil
+}
+
+// encode writes n in the alphabet base, left padded to length.
+func encode(n uint64, alphabet string, length int) string {
+	base := uint64(len(alphabet))
+	var digits []byte
+	for n > 0 {
+		digits = append(digits, alphabet[n%base])
+		n /= base
+	}
+	for len(digits) < length {
+		digits = append(digits, alphabet[0])
+	}
+
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+	return string(digits)
+}
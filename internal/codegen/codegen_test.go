@@ -0,0 +1,194 @@
+package codegen
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	gen, err := New(Options{}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &random{}, gen)
+
+	_, err = New(Options{Length: 3}, nil)
+	assert.EqualError(t, err, "code length must be between 4 and 32")
+
+	_, err = New(Options{Strategy: "sequential"}, nil)
+	assert.EqualError(t, err, `unknown code strategy "sequential"`)
+
+	_, err = New(Options{Alphabet: "abca"}, nil)
+	assert.ErrorIs(t, err, ErrAlphabetInvalid)
+}
+
+func TestParseAlphabet(t *testing.T) {
+	tests := map[string]string{
+		"":          Base62,
+		"base62":    Base62,
+		"Friendly":  Friendly,
+		"lowercase": Lowercase,
+		"numeric":   Numeric,
+		"ab-_":      "ab-_",
+	}
+	for value, expected := range tests {
+		alphabet, err := ParseAlphabet(value)
+		require.NoError(t, err)
+		assert.Equal(t, expected, alphabet)
+	}
+
+	for _, value := range []string{"a", "aba", "ab/", "abç", strings.Repeat("a", 65)} {
+		_, err := ParseAlphabet(value)
+		assert.ErrorIs(t, err, ErrAlphabetInvalid, value)
+	}
+}
+
+func TestFriendlyHasNoLookAlikes(t *testing.T) {
+	assert.False(t, strings.ContainsAny(Friendly, "0oO1iIlL"))
+}
+
+func TestRandom(t *testing.T) {
+	ctx := context.Background()
+	gen := NewRandom(Base62, DefaultLength)
+
+	seen := map[string]bool{}
+	for range 1000 {
+		code, err := gen.Generate(ctx, "https://example.com", 0)
+		require.NoError(t, err)
+		assert.Len(t, code, DefaultLength)
+		assert.Regexp(t, "^[a-zA-Z0-9]+$", code)
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+
+	code, err := NewRandom(Numeric, 6).Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Regexp(t, "^[0-9]{6}$", code)
+}
+
+func TestRandomGrows(t *testing.T) {
+	ctx := context.Background()
+	gen := NewRandom(Friendly, 4)
+
+	for attempt := range growAfter {
+		code, err := gen.Generate(ctx, "https://example.com", attempt)
+		require.NoError(t, err)
+		assert.Len(t, code, 4)
+	}
+
+	// a link needing growAfter attempts grows the codes of every later link
+	code, err := gen.Generate(ctx, "https://example.com", growAfter)
+	require.NoError(t, err)
+	assert.Len(t, code, 5)
+
+	code, err = gen.Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Len(t, code, 5)
+}
+
+func TestHash(t *testing.T) {
+	ctx := context.Background()
+	gen := NewHash(Base62, 6)
+
+	first, err := gen.Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Len(t, first, 6)
+
+	again, err := gen.Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	retry, err := gen.Generate(ctx, "https://example.com", 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, retry)
+
+	again, err = gen.Generate(ctx, "https://example.com", 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, retry, again)
+
+	other, err := gen.Generate(ctx, "https://example.org", 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other)
+}
+
+func sequence(start uint64) NextFunc {
+	n := start - 1
+	return func(ctx context.Context) (uint64, error) {
+		n++
+		return n, nil
+	}
+}
+
+func TestCounter(t *testing.T) {
+	ctx := context.Background()
+	gen := NewCounter(sequence(1), Base62, 4, "")
+
+	for _, expected := range []string{"aaab", "aaac", "aaad"} {
+		code, err := gen.Generate(ctx, "", 0)
+		require.NoError(t, err)
+		assert.Equal(t, expected, code)
+	}
+
+	code, err := NewCounter(sequence(62*62*62*62), Base62, 4, "").Generate(ctx, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "baaaa", code)
+
+	code, err = NewCounter(sequence(42), Numeric, 4, "").Generate(ctx, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "0042", code)
+}
+
+func TestCounterObfuscated(t *testing.T) {
+	ctx := context.Background()
+
+	// 4 numeric characters hold 12 bits of obfuscated values, the next
+	// ones growing to 5 characters, all of them distinct
+	gen := NewCounter(sequence(1), Numeric, 4, "secret")
+	seen := map[string]bool{}
+	for n := 1; n < 1<<14; n++ {
+		code, err := gen.Generate(ctx, "", 0)
+		require.NoError(t, err)
+		if n < 1<<12 {
+			assert.Len(t, code, 4)
+		} else {
+			assert.Len(t, code, 5)
+		}
+		require.False(t, seen[code], "code %q generated twice", code)
+		seen[code] = true
+	}
+
+	gen = NewCounter(sequence(1), Numeric, 4, "secret")
+	var codes []string
+	for range 3 {
+		code, err := gen.Generate(ctx, "", 0)
+		require.NoError(t, err)
+		codes = append(codes, code)
+	}
+	assert.NotEqual(t, []string{"0001", "0002", "0003"}, codes)
+
+	other, err := NewCounter(sequence(1), Base62, 4, "other").Generate(ctx, "", 0)
+	require.NoError(t, err)
+	first, err := NewCounter(sequence(1), Base62, 4, "secret").Generate(ctx, "", 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other)
+
+	_, err = NewCounter(sequence(1<<62), Base62, 4, "secret").Generate(ctx, "", 0)
+	assert.ErrorIs(t, err, ErrSpaceExhausted)
+}
+
+func TestReservedCodesSkipped(t *testing.T) {
+	// "admin" in the alphabet, then "bbbbb", each read from a buffer of
+	// twice the code length
+	src := bytes.NewReader([]byte{0, 3, 12, 8, 13, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1})
+	code, err := readCode(src, Base62, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "bbbbb", code)
+
+	// "admin" is the value 214 with the alphabet of its letters
+	code, err = NewCounter(sequence(214), "adimn", 5, "").Generate(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, encode(215, "adimn", 5), code)
+}
@@ -0,0 +1,91 @@
+package codegen
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"url-shortener/internal/utils"
+)
+
+const feistelRounds = 4
+
+type counter struct {
+	next     NextFunc
+	alphabet string
+	length   int
+	key      []byte
+}
+
+// NewCounter returns the next counter value as a code. Plain counter codes
+// are short and sequential, growing past length as needed. With a key the
+// values go through a keyed Feistel network, a permutation of the values
+// fitting in length characters, so codes look random yet never repeat.
+// Once the counter outgrows them, codes are one character longer and the
+// permutation covers the values fitting in it.
+func NewCounter(next NextFunc, alphabet string, length int, key string) Generator {
+	return &counter{next: next, alphabet: alphabet, length: length, key: []byte(key)}
+}
+
+func (g *counter) Generate(ctx context.Context, target string, attempt int) (string, error) {
+	for {
+		n, err := g.next(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the next code: %w", err)
+		}
+
+		code, err := g.code(n)
+		if err != nil || !utils.IsReserved(code) {
+			return code, err
+		}
+		// the value is skipped, a route would shadow its code
+	}
+}
+
+// code encodes the counter value n.
+func (g *counter) code(n uint64) (string, error) {
+	if len(g.key) == 0 {
+		return encode(n, g.alphabet, g.length), nil
+	}
+
+	// the values of a length are those past the shorter lengths, so codes
+	// of different lengths, always distinct, never map the same value
+	for length := g.length; length <= MaxLength; length++ {
+		if bits := g.bits(length); n < 1<<bits {
+			return encode(g.permute(n, bits), g.alphabet, length), nil
+		}
+	}
+
+	return "", ErrSpaceExhausted
+}
+
+// bits is the even width of the values obfuscated into codes of length,
+// the widest that still fits in length characters.
+func (g *counter) bits(length int) int {
+	bits := int(math.Floor(float64(length) * math.Log2(float64(len(g.alphabet)))))
+	return min(bits, 62) &^ 1
+}
+
+// permute runs a balanced Feistel network over n, rounds keyed by an HMAC
+// of the key. Every value below 1<<bits maps to a distinct value in that
+// same range.
+func (g *counter) permute(n uint64, bits int) uint64 {
+	half := bits / 2
+	mask := uint64(1)<<half - 1
+	left, right := n>>half, n&mask
+
+	var block [9]byte
+	for round := range feistelRounds {
+		block[0] = byte(round)
+		binary.BigEndian.PutUint64(block[1:], right)
+		mac := hmac.New(sha256.New, g.key)
+		mac.Write(block[:])
+		f := binary.BigEndian.Uint64(mac.Sum(nil)) & mask
+
+		left, right = right, left^f
+	}
+
+	return left<<half | right
+}
@@ -0,0 +1,36 @@
+package codegen
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	mathrand "math/rand/v2"
+)
+
+type hash struct {
+	alphabet string
+	length   *growingLength
+}
+
+// NewHash returns codes derived from the sha256 of the target, so until
+// codes grow the first link to a target always gets the same code. Later
+// attempts, as when the target was already shortened, hash it along with a
+// random salt.
+func NewHash(alphabet string, length int) Generator {
+	return &hash{alphabet: alphabet, length: newGrowingLength(length)}
+}
+
+func (g *hash) Generate(ctx context.Context, target string, attempt int) (string, error) {
+	seed := []byte(target)
+	if attempt > 0 {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		seed = append(seed, salt...)
+	}
+
+	// the hash seeds a stream long enough for any code length
+	return readCode(mathrand.NewChaCha8(sha256.Sum256(seed)), g.alphabet, g.length.get(attempt))
+}
@@ -0,0 +1,21 @@
+package codegen
+
+import (
+	"context"
+	"crypto/rand"
+)
+
+type random struct {
+	alphabet string
+	length   *growingLength
+}
+
+// NewRandom returns codes read from crypto/rand, so they can't be guessed
+// from the previous ones.
+func NewRandom(alphabet string, length int) Generator {
+	return &random{alphabet: alphabet, length: newGrowingLength(length)}
+}
+
+func (g *random) Generate(ctx context.Context, target string, attempt int) (string, error) {
+	return readCode(rand.Reader, g.alphabet, g.length.get(attempt))
+}
@@ -2,8 +2,16 @@ package config
 
 import (
 	"log/slog"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/codegen"
+	"url-shortener/internal/ratelimit"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/validation"
 
 	"github.com/joho/godotenv"
 )
@@ -17,6 +25,60 @@ type config struct {
 	BasicAuthPwd  string
 	AppPort       int
 	Port          int
+	// ExpirySweepInterval is how often expired URLs are removed from storage.
+	ExpirySweepInterval time.Duration
+	// AnalyticsBufferSize is how many clicks can wait to be recorded before
+	// new ones start being dropped.
+	AnalyticsBufferSize int
+	// CountryHeader is the request header the reverse proxy sets with the
+	// visitor country.
+	CountryHeader string
+	// StorageBackend selects where the URLs are stored: redis, memory or bolt.
+	StorageBackend string
+	// BoltPath is the file used by the bolt storage backend.
+	BoltPath string
+	// Codes selects how the codes of new links are generated.
+	Codes codegen.Options
+	// RateLimitShorten, RateLimitShortenBulk, RateLimitRedirect and
+	// RateLimitAdmin limit the requests per API key, or per IP for anonymous
+	// requests, of each route.
+	RateLimitShorten     ratelimit.Limit
+	RateLimitShortenBulk ratelimit.Limit
+	RateLimitRedirect    ratelimit.Limit
+	RateLimitAdmin       ratelimit.Limit
+	// RateLimitUnlock limits the password attempts per link and client IP.
+	RateLimitUnlock ratelimit.Limit
+	// BaseURL is the public URL the short links are served from.
+	BaseURL string
+	// URLPolicy holds the rules the target URLs must follow, the BaseURL host
+	// being rejected to avoid redirect loops.
+	URLPolicy validation.Policy
+	// BlocklistPath is the file of the malicious URL rules, none when empty.
+	BlocklistPath string
+	// BlocklistReloadInterval is how often the blocklist file is checked for
+	// changes.
+	BlocklistReloadInterval time.Duration
+	// RedirectStatus is the status of the redirects of the links that don't
+	// set their own.
+	RedirectStatus int
+	// RedirectNoCache sends no-store Cache-Control headers on every redirect.
+	RedirectNoCache bool
+	// LegacyAPIRoutes keeps serving the short links and the JSON API under
+	// /api, as before they moved to the root and /api/v1.
+	LegacyAPIRoutes bool
+	// TrustedProxies are the proxies whose X-Real-IP header holds the
+	// client IP, the peer address being used for any other request.
+	TrustedProxies []*net.IPNet
+	// RateLimitUnlockLink limits the password attempts per link, whatever
+	// the client IP.
+	RateLimitUnlockLink ratelimit.Limit
+}
+
+func getEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
 }
 
 func getConfig() config {
@@ -50,6 +112,118 @@ func getConfig() config {
 		panic(err)
 	}
 
+	expirySweepInterval, err := time.ParseDuration(getEnv("EXPIRY_SWEEP_INTERVAL", "1m"))
+	if err != nil {
+		slog.Error("error converting expiry sweep interval to duration", "error", err)
+		panic(err)
+	}
+
+	analyticsBufferSize, err := strconv.Atoi(getEnv("ANALYTICS_BUFFER_SIZE", "1024"))
+	if err != nil {
+		slog.Error("error converting analytics buffer size to int", "error", err)
+		panic(err)
+	}
+
+	rateLimitShorten, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_SHORTEN", "60/1m"))
+	if err != nil {
+		slog.Error("error parsing shorten rate limit", "error", err)
+		panic(err)
+	}
+
+	rateLimitShortenBulk, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_SHORTEN_BULK", "10/1m"))
+	if err != nil {
+		slog.Error("error parsing bulk shorten rate limit", "error", err)
+		panic(err)
+	}
+
+	rateLimitRedirect, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_REDIRECT", "600/1m"))
+	if err != nil {
+		slog.Error("error parsing redirect rate limit", "error", err)
+		panic(err)
+	}
+
+	rateLimitAdmin, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_ADMIN", "off"))
+	if err != nil {
+		slog.Error("error parsing admin rate limit", "error", err)
+		panic(err)
+	}
+
+	baseURL := getEnv("BASE_URL", "http://localhost:"+strconv.Itoa(port))
+	parsedBaseURL, err := url.Parse(baseURL)
+	if err != nil || parsedBaseURL.Hostname() == "" {
+		slog.Error("error parsing base url", "error", err)
+		panic("invalid BASE_URL " + baseURL)
+	}
+
+	urlMaxLength, err := strconv.Atoi(getEnv("URL_MAX_LENGTH", strconv.Itoa(validation.DefaultMaxLength)))
+	if err != nil {
+		slog.Error("error converting url max length to int", "error", err)
+		panic(err)
+	}
+
+	urlPolicy := validation.Policy{
+		Schemes:   validation.ParseList(getEnv("URL_ALLOWED_SCHEMES", "http,https")),
+		MaxLength: urlMaxLength,
+		OwnHosts:  []string{strings.ToLower(parsedBaseURL.Hostname())},
+		Allow:     validation.ParseList(os.Getenv("URL_ALLOWED_DOMAINS")),
+		Deny:      validation.ParseList(os.Getenv("URL_DENIED_DOMAINS")),
+	}
+
+	blocklistReloadInterval, err := time.ParseDuration(getEnv("BLOCKLIST_RELOAD_INTERVAL", "30s"))
+	if err != nil {
+		slog.Error("error converting blocklist reload interval to duration", "error", err)
+		panic(err)
+	}
+
+	redirectStatus, err := strconv.Atoi(getEnv("REDIRECT_STATUS", "301"))
+	if err != nil || !repositories.ValidRedirectStatus(redirectStatus) {
+		slog.Error("error converting redirect status, must be 301, 302, 307 or 308", "error", err)
+		panic("invalid REDIRECT_STATUS")
+	}
+
+	redirectNoCache, err := strconv.ParseBool(getEnv("REDIRECT_NO_CACHE", "false"))
+	if err != nil {
+		slog.Error("error converting redirect no cache to bool", "error", err)
+		panic(err)
+	}
+
+	legacyAPIRoutes, err := strconv.ParseBool(getEnv("LEGACY_API_ROUTES", "true"))
+	if err != nil {
+		slog.Error("error converting legacy api routes to bool", "error", err)
+		panic(err)
+	}
+
+	trustedProxies, err := ratelimit.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		slog.Error("error parsing trusted proxies", "error", err)
+		panic(err)
+	}
+
+	rateLimitUnlock, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_UNLOCK", "5/15m"))
+	if err != nil {
+		slog.Error("error parsing unlock rate limit", "error", err)
+		panic(err)
+	}
+
+	rateLimitUnlockLink, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_UNLOCK_LINK", "100/1h"))
+	if err != nil {
+		slog.Error("error parsing unlock link rate limit", "error", err)
+		panic(err)
+	}
+
+	codeLength, err := strconv.Atoi(getEnv("CODE_LENGTH", strconv.Itoa(codegen.DefaultLength)))
+	if err != nil {
+		slog.Error("error converting code length to int", "error", err)
+		panic(err)
+	}
+
+	codes := codegen.Options{
+		Strategy: getEnv("CODE_STRATEGY", codegen.StrategyRandom),
+		Alphabet: getEnv("CODE_ALPHABET", "base62"),
+		Length:   codeLength,
+		Key:      os.Getenv("CODE_KEY"),
+	}
+
 	return config{
 		RedisHost:     redisHost,
 		RedisPort:     redisPort,
@@ -59,6 +233,28 @@ func getConfig() config {
 		BasicAuthPwd:  basicAuthPwd,
 		AppPort:       appPort,
 		Port:          port,
+
+		ExpirySweepInterval:  expirySweepInterval,
+		AnalyticsBufferSize:  analyticsBufferSize,
+		CountryHeader:        getEnv("COUNTRY_HEADER", "X-Country-Code"),
+		StorageBackend:       getEnv("STORAGE_BACKEND", "redis"),
+		BoltPath:             getEnv("BOLT_PATH", "data/shortener.db"),
+		Codes:                codes,
+		RateLimitShorten:     rateLimitShorten,
+		RateLimitShortenBulk: rateLimitShortenBulk,
+		RateLimitRedirect:    rateLimitRedirect,
+		RateLimitAdmin:       rateLimitAdmin,
+		RateLimitUnlock:      rateLimitUnlock,
+		BaseURL:              strings.TrimSuffix(baseURL, "/"),
+		URLPolicy:            urlPolicy,
+
+		BlocklistPath:           os.Getenv("BLOCKLIST_PATH"),
+		BlocklistReloadInterval: blocklistReloadInterval,
+		RedirectStatus:          redirectStatus,
+		RedirectNoCache:         redirectNoCache,
+		LegacyAPIRoutes:         legacyAPIRoutes,
+		TrustedProxies:          trustedProxies,
+		RateLimitUnlockLink:     rateLimitUnlockLink,
 	}
 }
 
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"url-shortener/internal/auth"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxApiKeyNameLength = 100
+
+type postApiKeyBody struct {
+	Name   string   `json:"name" example:"marketing"`
+	Scopes []string `json:"scopes" example:"links:create,links:read"`
+	// Tenant limits the key to the links of the tenant. Keys issued with
+	// tenant credentials always belong to their tenant.
+	Tenant string `json:"tenant" example:"acme"`
+}
+
+type postApiKeyResponse struct {
+	// Key is only returned once, on creation.
+	Key    string              `json:"key"`
+	ApiKey repositories.ApiKey `json:"api_key"`
+}
+
+// HandlePostApiKey godoc
+// @Summary Issue API key
+// @Description Issue an API key with the scopes passed: links:create, links:read or links:admin.
+// @Description The key is only returned in this response, just its hash is stored.
+// @Description A key issued for a tenant only sees and creates the links of the tenant, tenant API keys only issuing keys for their tenant.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param data body postApiKeyBody true "API Key Post Body"
+// @Success 201 {object} utils.ApiResponse{data=postApiKeyResponse}
+// @Failure 400 {object} utils.ApiResponse{error=string}
+// @Failure 422 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/keys [post]
+func HandlePostApiKey(keys repositories.ApiKeyContract, tenants repositories.TenantContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body postApiKeyBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: "invalid request body"}, http.StatusUnprocessableEntity)
+			return
+		}
+
+		name := strings.TrimSpace(body.Name)
+		if name == "" {
+			utils.SendJSON(w, utils.ApiResponse{Error: "name is required"}, http.StatusBadRequest)
+			return
+		}
+		if len(name) > maxApiKeyNameLength {
+			utils.SendJSON(w, utils.ApiResponse{Error: fmt.Sprintf("name must be at most %d characters long", maxApiKeyNameLength)}, http.StatusBadRequest)
+			return
+		}
+
+		scopes, err := auth.ValidateScopes(body.Scopes)
+		if err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		tenant := principalTenant(r)
+		switch {
+		case tenant != "" && body.Tenant != "" && body.Tenant != tenant:
+			utils.SendJSON(w, utils.ApiResponse{Error: "tenant credentials can only issue keys for their tenant"}, http.StatusForbidden)
+			return
+		case tenant == "" && body.Tenant != "":
+			if _, err := tenants.GetTenant(r.Context(), body.Tenant); err != nil {
+				if errors.Is(err, repositories.ErrTenantNotFound) {
+					utils.SendJSON(w, utils.ApiResponse{Error: "tenant not found"}, http.StatusBadRequest)
+					return
+				}
+
+				slog.Error("error get tenant", "error", err)
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "something went wrong",
+				}, http.StatusInternalServerError)
+				return
+			}
+			tenant = body.Tenant
+		}
+
+		key, apiKey, err := auth.GenerateKey(name, scopes, time.Now())
+		apiKey.Tenant = tenant
+		if err == nil {
+			err = keys.SaveApiKey(r.Context(), apiKey)
+		}
+		if err != nil {
+			slog.Error("error saving api key", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{
+			Data: postApiKeyResponse{Key: key, ApiKey: apiKey},
+		}, http.StatusCreated)
+	}
+}
+
+// HandleGetApiKeys godoc
+// @Summary List API keys
+// @Description List the issued API keys, revoked ones included, without the keys themselves.
+// @Description Tenant API keys only list the keys of their tenant.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Success 200 {object} utils.ApiResponse{data=[]repositories.ApiKey}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/keys [get]
+func HandleGetApiKeys(keys repositories.ApiKeyContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKeys, err := keys.ListApiKeys(r.Context())
+		if err != nil {
+			slog.Error("error get api keys", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		if tenant := principalTenant(r); tenant != "" {
+			apiKeys = slices.DeleteFunc(apiKeys, func(apiKey repositories.ApiKey) bool {
+				return apiKey.Tenant != tenant
+			})
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: apiKeys}, http.StatusOK)
+	}
+}
+
+// HandleDeleteApiKey godoc
+// @Summary Revoke API key
+// @Description Revoke the API key that match the id passed, it stays listed as revoked.
+// @Description Tenant API keys only revoke the keys of their tenant.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param id path string true "API key id"
+// @Success 204 {object} utils.ApiResponse{}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/keys/{id} [delete]
+func HandleDeleteApiKey(keys repositories.ApiKeyContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		if tenant := principalTenant(r); tenant != "" {
+			apiKeys, err := keys.ListApiKeys(r.Context())
+			if err != nil {
+				slog.Error("error get api keys", "error", err)
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "something went wrong",
+				}, http.StatusInternalServerError)
+				return
+			}
+			if !slices.ContainsFunc(apiKeys, func(apiKey repositories.ApiKey) bool {
+				return apiKey.ID == id && apiKey.Tenant == tenant
+			}) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "api key not found",
+				}, http.StatusNotFound)
+				return
+			}
+		}
+
+		if _, err := keys.RevokeApiKey(r.Context(), id); err != nil {
+			if errors.Is(err, repositories.ErrKeyNotFound) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "api key not found",
+				}, http.StatusNotFound)
+				return
+			}
+
+			slog.Error("error revoking api key", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{}, http.StatusNoContent)
+	}
+}
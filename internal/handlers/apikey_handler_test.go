@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/auth"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockApiKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockApiKeyRepository) SaveApiKey(ctx context.Context, key repositories.ApiKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockApiKeyRepository) GetApiKeyByHash(ctx context.Context, hash string) (repositories.ApiKey, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(repositories.ApiKey), args.Error(1)
+}
+
+func (m *MockApiKeyRepository) ListApiKeys(ctx context.Context) ([]repositories.ApiKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]repositories.ApiKey), args.Error(1)
+}
+
+func (m *MockApiKeyRepository) RevokeApiKey(ctx context.Context, id string) (repositories.ApiKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(repositories.ApiKey), args.Error(1)
+}
+
+func TestPostApiKey(t *testing.T) {
+	mockStore := new(MockApiKeyRepository)
+	mockStore.On("SaveApiKey", mock.Anything, mock.MatchedBy(func(key repositories.ApiKey) bool {
+		return key.Name == "marketing" && len(key.Scopes) == 1 && key.Scopes[0] == auth.ScopeLinksCreate
+	})).Return(nil)
+	handler := HandlePostApiKey(mockStore, nil)
+
+	body, _ := json.Marshal(postApiKeyBody{Name: " marketing ", Scopes: []string{auth.ScopeLinksCreate}})
+	req := httptest.NewRequest("POST", "/admin/keys", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp struct {
+		Data struct {
+			Key    string         `json:"key"`
+			ApiKey map[string]any `json:"api_key"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Data.Key)
+	assert.Equal(t, "marketing", resp.Data.ApiKey["name"])
+	assert.NotContains(t, resp.Data.ApiKey, "hash")
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestPostApiKey_BadRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     postApiKeyBody
+		expected string
+	}{
+		{name: "no name", body: postApiKeyBody{Scopes: []string{auth.ScopeLinksRead}}, expected: "name is required"},
+		{name: "no scopes", body: postApiKeyBody{Name: "ci"}, expected: "at least one scope is required: invalid scope"},
+		{name: "unknown scope", body: postApiKeyBody{Name: "ci", Scopes: []string{"everything"}}, expected: `unknown scope "everything": invalid scope`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockApiKeyRepository)
+			handler := HandlePostApiKey(mockStore, nil)
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", "/admin/keys", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			expectedBody, _ := json.Marshal(utils.ApiResponse{Error: tt.expected})
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetApiKeys(t *testing.T) {
+	keys := []repositories.ApiKey{{
+		ID:        "k1",
+		Name:      "ci",
+		Prefix:    "usk_123456",
+		Hash:      "secret hash",
+		Scopes:    []string{auth.ScopeLinksRead},
+		CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	mockStore := new(MockApiKeyRepository)
+	mockStore.On("ListApiKeys", mock.Anything).Return(keys, nil)
+	handler := HandleGetApiKeys(mockStore)
+
+	req := httptest.NewRequest("GET", "/admin/keys", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[{"id":"k1","name":"ci","prefix":"usk_123456","scopes":["links:read"],"created_at":"2026-01-01T00:00:00Z"}]}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestDeleteApiKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockError    error
+		expectedCode int
+	}{
+		{name: "revoked", expectedCode: http.StatusNoContent},
+		{name: "not found", mockError: repositories.ErrKeyNotFound, expectedCode: http.StatusNotFound},
+		{name: "something went wrong", mockError: assert.AnError, expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockApiKeyRepository)
+			mockStore.On("RevokeApiKey", mock.Anything, "k1").Return(repositories.ApiKey{}, tt.mockError)
+
+			router := chi.NewRouter()
+			router.Delete("/admin/keys/{id}", HandleDeleteApiKey(mockStore))
+
+			req := httptest.NewRequest(http.MethodDelete, "/admin/keys/k1", nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/auth"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+	"url-shortener/internal/validation"
+)
+
+const (
+	maxBulkItems = 1000
+	// maxBulkPasswords bounds the items setting a password, each one taking
+	// a slow bcrypt hash that must fit in the write timeout.
+	maxBulkPasswords = 20
+	// maxBulkBodySize bounds the request body, well above what maxBulkItems
+	// items need.
+	maxBulkBodySize = 10 << 20
+	// csvTagSeparator splits the tags column of CSV items.
+	csvTagSeparator = ";"
+)
+
+var (
+	errTooManyItems     = fmt.Errorf("at most %d items are allowed", maxBulkItems)
+	errTooManyPasswords = fmt.Errorf("at most %d items may set a password", maxBulkPasswords)
+)
+
+// bulkItem is an item of a bulk request, err being set when it couldn't be
+// read, which doesn't fail the other items.
+type bulkItem struct {
+	body postBody
+	err  error
+}
+
+type bulkResult struct {
+	// Index is the position of the item in the request, starting at 0.
+	Index int    `json:"index"`
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Reason is set along with the error of a rejected URL.
+	Reason string `json:"reason,omitempty"`
+	// Reused is set when the code is of an existing link, see postBody.Reuse.
+	Reused bool `json:"reused,omitempty"`
+}
+
+type bulkResponse struct {
+	Created int          `json:"created"`
+	Reused  int          `json:"reused"`
+	Failed  int          `json:"failed"`
+	Results []bulkResult `json:"results"`
+}
+
+// HandlePostBulkShortenedURL godoc
+// @Summary Post shortened URLs in bulk
+// @Description Shorten up to 1000 URLs at once, sent as a JSON array (application/json), NDJSON (application/x-ndjson) or CSV (text/csv).
+// @Description Items take the same fields as /api/v1/shorten. CSV needs a header row with the url column and optionally alias, title, tags (separated by ;), expires_in, expires_at, host and reuse.
+// @Description Every item gets its own result with either the code or the error, an item failing doesn't fail the others.
+// @Description At most 20 items may set a password, hashing them is slow.
+// @Description Requires the links:create scope.
+// @Security ApiKeyAuth
+// @Tags API
+// @Accept json,text/csv,application/x-ndjson
+// @Param Authorization header string true "Bearer API key"
+// @Param data body []postBody true "Shortened URLs"
+// @Success 200 {object} utils.ApiResponse{data=bulkResponse}
+// @Failure 400 {object} utils.ApiResponse{error=string}
+// @Failure 413 {object} utils.ApiResponse{error=string}
+// @Failure 415 {object} utils.ApiResponse{error=string}
+// @Failure 422 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401 {object} utils.ApiResponse{error=string}
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Failure 429 {object} utils.ApiResponse{error=string}
+// @Router /api/v1/shorten/bulk [post]
+func HandlePostBulkShortenedURL(db repositories.UrlContract, domains repositories.DomainContract, policy validation.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBulkBodySize)
+
+		items, err := readBulkItems(r)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesErr):
+				utils.SendJSON(w, utils.ApiResponse{Error: "request body too large"}, http.StatusRequestEntityTooLarge)
+			case errors.Is(err, errUnsupportedMediaType):
+				utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusUnsupportedMediaType)
+			case errors.Is(err, errTooManyItems):
+				utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusRequestEntityTooLarge)
+			default:
+				utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusUnprocessableEntity)
+			}
+			return
+		}
+
+		if len(items) == 0 {
+			utils.SendJSON(w, utils.ApiResponse{Error: "at least one item is required"}, http.StatusBadRequest)
+			return
+		}
+
+		passwords := 0
+		for _, item := range items {
+			if item.err == nil && item.body.Password != "" {
+				passwords++
+			}
+		}
+		if passwords > maxBulkPasswords {
+			utils.SendJSON(w, utils.ApiResponse{Error: errTooManyPasswords.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		var creator, tenant string
+		if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+			creator, tenant = principal.Name, principal.Tenant
+		}
+
+		registered, err := domains.ListDomains(r.Context())
+		if err != nil {
+			slog.Error("error get domains", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+		policy := domainPolicy(policy, registered)
+
+		now := time.Now()
+		results := make([]bulkResult, len(items))
+		links := make([]repositories.Link, 0, len(items))
+		// indexes maps the links to save back to their item
+		indexes := make([]int, 0, len(items))
+		for i, item := range items {
+			results[i].Index = i
+			if item.err != nil {
+				results[i].Error = item.err.Error()
+				continue
+			}
+
+			link, err := item.body.link(now, policy)
+			if err != nil {
+				resp := errorResponse(err)
+				results[i].Error, results[i].Reason = resp.Error, resp.Reason
+				continue
+			}
+			link.Code = item.body.Alias
+			link.Creator = creator
+			link.Tenant = tenant
+
+			if link.Host, err = linkHost(registered, item.body.Host); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			if err := checkLegacyCode(r.Context(), db, registered, link.Host, link.Code); err != nil {
+				if errors.Is(err, repositories.ErrCodeTaken) {
+					results[i].Error = "alias already in use"
+				} else {
+					slog.Error("error get url", "error", err)
+					results[i].Error = "something went wrong"
+				}
+				continue
+			}
+
+			reused, err := reusableCode(r.Context(), db, item.body, link)
+			if err != nil {
+				slog.Error("error finding url", "error", err)
+				results[i].Error = "something went wrong"
+				continue
+			}
+			if reused != "" {
+				results[i].Code = reused
+				results[i].Reused = true
+				continue
+			}
+
+			links = append(links, link)
+			indexes = append(indexes, i)
+		}
+
+		if len(links) > 0 {
+			saved, err := db.SaveURLs(r.Context(), links)
+			if err != nil {
+				slog.Error("error saving urls", "error", err)
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "something went wrong",
+				}, http.StatusInternalServerError)
+				return
+			}
+
+			for j, result := range saved {
+				i := indexes[j]
+				switch {
+				case result.Err == nil:
+					results[i].Code = result.Code
+				case errors.Is(result.Err, repositories.ErrCodeTaken) && links[j].Code != "":
+					results[i].Error = "alias already in use"
+				case errors.Is(result.Err, repositories.ErrQuotaExceeded):
+					results[i].Error = "link quota exceeded"
+				default:
+					slog.Error("error saving url", "error", result.Err)
+					results[i].Error = "something went wrong"
+				}
+			}
+		}
+
+		resp := bulkResponse{Results: results}
+		for _, result := range results {
+			switch {
+			case result.Error != "":
+				resp.Failed++
+			case result.Reused:
+				resp.Reused++
+			default:
+				resp.Created++
+			}
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: resp}, http.StatusOK)
+	}
+}
+
+var errUnsupportedMediaType = errors.New("content type must be application/json, application/x-ndjson or text/csv")
+
+func readBulkItems(r *http.Request) ([]bulkItem, error) {
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, errUnsupportedMediaType
+		}
+	}
+
+	switch mediaType {
+	case "application/json":
+		return readJSONItems(r.Body)
+	case "application/x-ndjson", "application/jsonl":
+		return readNDJSONItems(r.Body)
+	case "text/csv":
+		return readCSVItems(r.Body)
+	}
+	return nil, errUnsupportedMediaType
+}
+
+func readJSONItems(body io.Reader) ([]bulkItem, error) {
+	var bodies []postBody
+	if err := json.NewDecoder(body).Decode(&bodies); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, err
+		}
+		return nil, errors.New("invalid request body")
+	}
+	if len(bodies) > maxBulkItems {
+		return nil, errTooManyItems
+	}
+
+	items := make([]bulkItem, len(bodies))
+	for i, body := range bodies {
+		items[i].body = body
+	}
+	return items, nil
+}
+
+// readNDJSONItems reads one item per line, blank lines being skipped. A
+// line that isn't valid JSON only fails its own item.
+func readNDJSONItems(body io.Reader) ([]bulkItem, error) {
+	var items []bulkItem
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, maxBulkBodySize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(items) == maxBulkItems {
+			return nil, errTooManyItems
+		}
+
+		var item bulkItem
+		if err := json.Unmarshal(line, &item.body); err != nil {
+			item.err = errors.New("invalid item")
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// readCSVItems reads the items from a CSV with a header row naming the
+// columns, which may come in any order. A row without as many columns as
+// the header only fails its own item.
+func readCSVItems(body io.Reader) ([]bulkItem, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, csvError(err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "url", "alias", "title", "tags", "expires_in", "expires_at", "redirect_status", "no_cache", "password", "max_clicks", "reuse", "host",
+			"forward_query", "utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("unknown csv column %q", name)
+		}
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, errors.New("csv url column is required")
+	}
+
+	var items []bulkItem
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		if len(items) == maxBulkItems {
+			return nil, errTooManyItems
+		}
+		if len(record) != len(header) {
+			items = append(items, bulkItem{err: fmt.Errorf("expected %d columns, got %d", len(header), len(record))})
+			continue
+		}
+
+		items = append(items, csvItem(record, columns))
+	}
+
+	return items, nil
+}
+
+func csvItem(record []string, columns map[string]int) bulkItem {
+	get := func(column string) string {
+		if i, ok := columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	item := bulkItem{body: postBody{
+		URL:      get("url"),
+		Alias:    get("alias"),
+		Title:    get("title"),
+		Password: get("password"),
+		Host:     get("host"),
+	}}
+
+	if tags := get("tags"); tags != "" {
+		item.body.Tags = strings.Split(tags, csvTagSeparator)
+	}
+
+	if expiresIn := get("expires_in"); expiresIn != "" {
+		seconds, err := strconv.ParseInt(expiresIn, 10, 64)
+		if err != nil {
+			item.err = errors.New("expires_in must be a number of seconds")
+			return item
+		}
+		item.body.ExpiresIn = seconds
+	}
+
+	if status := get("redirect_status"); status != "" {
+		value, err := strconv.Atoi(status)
+		if err != nil {
+			item.err = errInvalidRedirectStatus
+			return item
+		}
+		item.body.RedirectStatus = value
+	}
+
+	if noCache := get("no_cache"); noCache != "" {
+		value, err := strconv.ParseBool(noCache)
+		if err != nil {
+			item.err = errors.New("no_cache must be true or false")
+			return item
+		}
+		item.body.NoCache = value
+	}
+
+	if maxClicks := get("max_clicks"); maxClicks != "" {
+		value, err := strconv.ParseInt(maxClicks, 10, 64)
+		if err != nil {
+			item.err = errInvalidMaxClicks
+			return item
+		}
+		item.body.MaxClicks = value
+	}
+
+	utm := utmParams{
+		Source:   get("utm_source"),
+		Medium:   get("utm_medium"),
+		Campaign: get("utm_campaign"),
+		Term:     get("utm_term"),
+		Content:  get("utm_content"),
+	}
+	if utm != (utmParams{}) {
+		item.body.UTM = &utm
+	}
+
+	if forward := get("forward_query"); forward != "" {
+		value, err := strconv.ParseBool(forward)
+		if err != nil {
+			item.err = errors.New("forward_query must be true or false")
+			return item
+		}
+		item.body.ForwardQuery = value
+	}
+
+	if reuse := get("reuse"); reuse != "" {
+		value, err := strconv.ParseBool(reuse)
+		if err != nil {
+			item.err = errors.New("reuse must be true or false")
+			return item
+		}
+		item.body.Reuse = value
+	}
+
+	if expiresAt := get("expires_at"); expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			item.err = errors.New("expires_at must be an RFC 3339 date")
+			return item
+		}
+		item.body.ExpiresAt = &t
+	}
+
+	return item
+}
+
+func csvError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("invalid csv on line %d", parseErr.Line)
+	}
+	return errors.New("invalid csv")
+}
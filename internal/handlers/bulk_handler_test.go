@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+	"url-shortener/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostBulkShortenedURL(t *testing.T) {
+	saved := []repositories.Link{
+		{URL: "https://first.com", Tags: []string{"launch", "docs"}},
+		{Code: "taken", URL: "https://second.com"},
+		{URL: "https://third.com"},
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body: `[
+				{"url":"https://first.com","tags":["launch","docs"]},
+				{"url":"https://second.com","alias":"taken"},
+				{"url":""},
+				{"url":"https://third.com"},
+				{"url":"https://fourth.com","alias":"a"}
+			]`,
+		},
+		{
+			name:        "ndjson",
+			contentType: "application/x-ndjson",
+			body: `{"url":"https://first.com","tags":["launch","docs"]}
+{"url":"https://second.com","alias":"taken"}
+not json
+
+{"url":"https://third.com"}
+{"url":"https://fourth.com","alias":"a"}
+`,
+		},
+		{
+			name:        "csv",
+			contentType: "text/csv; charset=utf-8",
+			body: `url,alias,tags
+https://first.com,,launch;docs
+https://second.com,taken,
+,,
+https://third.com,,
+https://fourth.com,a,
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("SaveURLs", mock.Anything, saved).Return([]repositories.SaveResult{
+				{Code: "abc12345"},
+				{Err: repositories.ErrCodeTaken},
+				{Code: "def12345"},
+			}, nil)
+			handler := HandlePostBulkShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+			req := httptest.NewRequest("POST", "/api/shorten/bulk", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var resp struct {
+				Data bulkResponse `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, 2, resp.Data.Created)
+			assert.Equal(t, 3, resp.Data.Failed)
+			require.Len(t, resp.Data.Results, 5)
+			assert.Equal(t, bulkResult{Index: 0, Code: "abc12345"}, resp.Data.Results[0])
+			assert.Equal(t, bulkResult{Index: 1, Error: "alias already in use"}, resp.Data.Results[1])
+			assert.Equal(t, 2, resp.Data.Results[2].Index)
+			assert.NotEmpty(t, resp.Data.Results[2].Error)
+			assert.Equal(t, bulkResult{Index: 3, Code: "def12345"}, resp.Data.Results[3])
+			assert.Equal(t, bulkResult{Index: 4, Error: utils.ErrAliasInvalid.Error()}, resp.Data.Results[4])
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostBulkShortenedURL_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		body         string
+		expectedCode int
+		expected     string
+	}{
+		{name: "empty", contentType: "application/json", body: `[]`, expectedCode: http.StatusBadRequest, expected: "at least one item is required"},
+		{name: "invalid json", contentType: "application/json", body: `{"url":"https://example.com"}`, expectedCode: http.StatusUnprocessableEntity, expected: "invalid request body"},
+		{name: "unsupported type", contentType: "text/plain", body: `https://example.com`, expectedCode: http.StatusUnsupportedMediaType, expected: errUnsupportedMediaType.Error()},
+		{name: "csv without url", contentType: "text/csv", body: "alias\nabc\n", expectedCode: http.StatusUnprocessableEntity, expected: "csv url column is required"},
+		{name: "csv unknown column", contentType: "text/csv", body: "url,owner\nhttps://example.com,me\n", expectedCode: http.StatusUnprocessableEntity, expected: `unknown csv column "owner"`},
+		{name: "too many items", contentType: "application/x-ndjson", body: strings.Repeat(`{"url":"https://example.com"}`+"\n", maxBulkItems+1), expectedCode: http.StatusRequestEntityTooLarge, expected: errTooManyItems.Error()},
+		{name: "too many passwords", contentType: "application/x-ndjson", body: strings.Repeat(`{"url":"https://example.com","password":"open sesame"}`+"\n", maxBulkPasswords+1), expectedCode: http.StatusBadRequest, expected: errTooManyPasswords.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			handler := HandlePostBulkShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+			req := httptest.NewRequest("POST", "/api/shorten/bulk", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			expectedBody, _ := json.Marshal(utils.ApiResponse{Error: tt.expected})
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostBulkShortenedURL_Reuse(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("FindURL", mock.Anything, "", "", "https://existing.com").Return(repositories.Link{Code: "existing"}, nil)
+	mockStore.On("FindURL", mock.Anything, "", "", "https://new.com").Return(repositories.Link{}, repositories.ErrNotFound)
+	mockStore.On("SaveURLs", mock.Anything, []repositories.Link{{URL: "https://new.com"}}).
+		Return([]repositories.SaveResult{{Code: "abc12345"}}, nil)
+	handler := HandlePostBulkShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	body := "url,reuse\nhttps://existing.com,true\nhttps://new.com,true\n"
+	req := httptest.NewRequest("POST", "/api/shorten/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"created":1,"reused":1,"failed":0,"results":[
+		{"index":0,"code":"existing","reused":true},
+		{"index":1,"code":"abc12345"}
+	]}}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestPostBulkShortenedURL_RaggedCSVRow(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveURLs", mock.Anything, []repositories.Link{{URL: "https://first.com"}, {URL: "https://third.com"}}).
+		Return([]repositories.SaveResult{{Code: "abc12345"}, {Code: "def12345"}}, nil)
+	handler := HandlePostBulkShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	body := "url,alias\nhttps://first.com,\nhttps://second.com,second,extra\nhttps://third.com,\n"
+	req := httptest.NewRequest("POST", "/api/shorten/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"created":2,"reused":0,"failed":1,"results":[
+		{"index":0,"code":"abc12345"},
+		{"index":1,"error":"expected 2 columns, got 3"},
+		{"index":2,"code":"def12345"}
+	]}}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestPostBulkShortenedURL_RejectedURL(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveURLs", mock.Anything, []repositories.Link{{URL: "https://example.com"}}).
+		Return([]repositories.SaveResult{{Code: "abc12345"}}, nil)
+	handler := HandlePostBulkShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	body := `[{"url":"https://example.com"},{"url":"http://192.168.0.1"}]`
+	req := httptest.NewRequest("POST", "/api/shorten/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"created":1,"reused":0,"failed":1,"results":[
+		{"index":0,"code":"abc12345"},
+		{"index":1,"error":"URL must not point to a private or local address","reason":"private_address"}
+	]}}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
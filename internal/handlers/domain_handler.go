@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+	"url-shortener/internal/validation"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxDomainLength = 253
+
+var domainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+var (
+	// errDomainNotRegistered is returned when a link is bound to a domain
+	// that isn't registered.
+	errDomainNotRegistered = errors.New("domain is not registered")
+	// errNoDefaultDomain is returned when a link is created without a host
+	// while domains are registered but none is the default.
+	errNoDefaultDomain = errors.New("no domain is the default, a host is required")
+)
+
+// normalizeDomain lowercases the domain and drops its trailing dot and port.
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	return strings.TrimSuffix(domain, ".")
+}
+
+// requestHost is the domain the request was sent to.
+type requestHost struct {
+	name     string
+	isLegacy bool
+}
+
+type requestHostKey struct{}
+
+// requestHostFrom returns the domain set by ResolveHost, false when no
+// domain is registered.
+func requestHostFrom(ctx context.Context) (requestHost, bool) {
+	host, ok := ctx.Value(requestHostKey{}).(requestHost)
+	return host, ok
+}
+
+// ResolveHost matches the Host header of the request against the registered
+// domains, so the links are looked up on the domain they are bound to, and
+// responds 404 to hosts that aren't registered. Every host serves the links
+// without a host as long as no domain is registered.
+func ResolveHost(domains repositories.DomainContract) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			registered, err := domains.ListDomains(r.Context())
+			if err != nil {
+				slog.Error("error get domains", "error", err)
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "something went wrong",
+				}, http.StatusInternalServerError)
+				return
+			}
+			if len(registered) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			name := normalizeDomain(r.Host)
+			for _, domain := range registered {
+				if domain.Name == name {
+					ctx := context.WithValue(r.Context(), requestHostKey{}, requestHost{name: name, isLegacy: domain.Legacy})
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				}
+			}
+
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "domain not found",
+			}, http.StatusNotFound)
+		})
+	}
+}
+
+// lookupLink gets the link with the code on the domain of the request. The
+// legacy domain serves the links without a host, then the ones bound to it.
+func lookupLink(r *http.Request, db repositories.UrlContract, code string) (repositories.Link, error) {
+	host, ok := requestHostFrom(r.Context())
+	if !ok {
+		return db.GetURL(r.Context(), code)
+	}
+	if !host.isLegacy {
+		return db.GetURL(r.Context(), repositories.LinkKey(host.name, code))
+	}
+
+	link, err := db.GetURL(r.Context(), code)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return db.GetURL(r.Context(), repositories.LinkKey(host.name, code))
+	}
+	return link, err
+}
+
+// shortURLBase is the base of the short URLs on the domain of the request,
+// baseURL when no domain is registered.
+func shortURLBase(r *http.Request, baseURL string) string {
+	host, ok := requestHostFrom(r.Context())
+	if !ok {
+		return baseURL
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" {
+		return "https://" + host.name
+	}
+	return base.Scheme + "://" + host.name
+}
+
+// adminLinkKey is the key of the link the admin routes address, the host
+// query param picking the domain the link is bound to. Only the links
+// created before any domain was registered have none.
+func adminLinkKey(r *http.Request) string {
+	return repositories.LinkKey(normalizeDomain(r.URL.Query().Get("host")), chi.URLParam(r, "code"))
+}
+
+// linkHost resolves the domain a new link is bound to among the registered
+// ones, the default one when none is asked. Links are only saved without a
+// host while no domain is registered.
+func linkHost(registered []repositories.Domain, domain string) (string, error) {
+	domain = normalizeDomain(domain)
+	if domain == "" && len(registered) == 0 {
+		return "", nil
+	}
+
+	for _, d := range registered {
+		if d.Name == domain || domain == "" && d.Default {
+			return d.Name, nil
+		}
+	}
+
+	if domain == "" {
+		return "", errNoDefaultDomain
+	}
+	return "", errDomainNotRegistered
+}
+
+// checkLegacyCode returns repositories.ErrCodeTaken when the alias of a link
+// bound to the legacy domain is already the code of a link without a host,
+// which would shadow it there.
+func checkLegacyCode(ctx context.Context, db repositories.UrlContract, registered []repositories.Domain, host, alias string) error {
+	if alias == "" || !slices.ContainsFunc(registered, func(d repositories.Domain) bool { return d.Name == host && d.Legacy }) {
+		return nil
+	}
+
+	_, err := db.GetURL(ctx, alias)
+	switch {
+	case err == nil || errors.Is(err, repositories.ErrExpired):
+		return fmt.Errorf("failed to save code %q: %w", alias, repositories.ErrCodeTaken)
+	case errors.Is(err, repositories.ErrNotFound):
+		return nil
+	}
+	return err
+}
+
+// domainPolicy adds the registered domains to the hosts the policy rejects,
+// so no target redirects back to one of them.
+func domainPolicy(policy validation.Policy, registered []repositories.Domain) validation.Policy {
+	hosts := make([]string, len(registered))
+	for i, domain := range registered {
+		hosts[i] = domain.Name
+	}
+	return policy.WithOwnHosts(hosts...)
+}
+
+// loadDomainPolicy is domainPolicy for the handlers that don't list the
+// domains otherwise.
+func loadDomainPolicy(ctx context.Context, domains repositories.DomainContract, policy validation.Policy) (validation.Policy, error) {
+	registered, err := domains.ListDomains(ctx)
+	if err != nil {
+		return validation.Policy{}, err
+	}
+	return domainPolicy(policy, registered), nil
+}
+
+type postDomainBody struct {
+	Name string `json:"name" example:"go.acme.io"`
+	// Default makes the domain the default one, which the first domain
+	// registered always becomes.
+	Default bool `json:"default"`
+}
+
+// HandlePostDomain godoc
+// @Summary Register domain
+// @Description Register a domain serving short links, links being bound to it with the host field.
+// @Description The first domain registered becomes the default one, the links created without a host being bound to it, and the legacy one, serving the links created before any domain was registered.
+// @Description Once a domain is registered, the redirects of hosts that aren't respond 404. Not allowed for tenant API keys.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Accept json
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param data body postDomainBody true "Domain Post Body"
+// @Success 201 {object} utils.ApiResponse{data=repositories.Domain}
+// @Failure 400 {object} utils.ApiResponse{error=string}
+// @Failure 409 {object} utils.ApiResponse{error=string}
+// @Failure 422 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/domains [post]
+func HandlePostDomain(domains repositories.DomainContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body postDomainBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: "invalid request body"}, http.StatusUnprocessableEntity)
+			return
+		}
+
+		name := normalizeDomain(body.Name)
+		if len(name) > maxDomainLength || !domainPattern.MatchString(name) {
+			utils.SendJSON(w, utils.ApiResponse{Error: "name must be a valid domain name"}, http.StatusBadRequest)
+			return
+		}
+
+		domain := repositories.Domain{Name: name, CreatedAt: time.Now()}
+		err := domains.SaveDomain(r.Context(), domain)
+		if err == nil && body.Default {
+			err = domains.SetDefaultDomain(r.Context(), name)
+		}
+		if err != nil {
+			if errors.Is(err, repositories.ErrDomainExists) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "domain already exists",
+				}, http.StatusConflict)
+				return
+			}
+
+			slog.Error("error saving domain", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		// the first domain is the default one whether asked or not
+		registered, err := domains.ListDomains(r.Context())
+		if err != nil {
+			slog.Error("error get domains", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+		for _, d := range registered {
+			if d.Name == name {
+				domain = d
+			}
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: domain}, http.StatusCreated)
+	}
+}
+
+// HandleGetDomains godoc
+// @Summary List domains
+// @Description List the registered domains, the default one flagged
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Success 200 {object} utils.ApiResponse{data=[]repositories.Domain}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/domains [get]
+func HandleGetDomains(domains repositories.DomainContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		registered, err := domains.ListDomains(r.Context())
+		if err != nil {
+			slog.Error("error get domains", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: registered}, http.StatusOK)
+	}
+}
+
+// HandleDeleteDomain godoc
+// @Summary Unregister domain
+// @Description Unregister the domain, its links are kept and served again if it is registered back.
+// @Description Unregistering the legacy domain leaves the links created before any domain was registered unreachable until it is registered back.
+// @Description Without a default domain new links need a host.
+// @Description Not allowed for tenant API keys.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param name path string true "Domain name"
+// @Success 204 {object} utils.ApiResponse{}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/domains/{name} [delete]
+func HandleDeleteDomain(domains repositories.DomainContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := normalizeDomain(chi.URLParam(r, "name"))
+
+		if err := domains.DeleteDomain(r.Context(), name); err != nil {
+			if errors.Is(err, repositories.ErrDomainNotFound) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "domain not found",
+				}, http.StatusNotFound)
+				return
+			}
+
+			slog.Error("error delete domain", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{}, http.StatusNoContent)
+	}
+}
+
+// HandlePutDefaultDomain godoc
+// @Summary Set default domain
+// @Description Make the domain the default one, the links created without a host being bound to it from now on. Existing links keep their domain.
+// @Description Not allowed for tenant API keys.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param name path string true "Domain name"
+// @Success 204 {object} utils.ApiResponse{}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/domains/{name}/default [put]
+func HandlePutDefaultDomain(domains repositories.DomainContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := normalizeDomain(chi.URLParam(r, "name"))
+
+		if err := domains.SetDefaultDomain(r.Context(), name); err != nil {
+			if errors.Is(err, repositories.ErrDomainNotFound) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "domain not found",
+				}, http.StatusNotFound)
+				return
+			}
+
+			slog.Error("error setting default domain", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{}, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDomains registers the domains, the first one being the default.
+func newTestDomains(t *testing.T, names ...string) repositories.DomainContract {
+	domains := repositories.NewMemoryDomainRepository()
+	for _, name := range names {
+		require.NoError(t, domains.SaveDomain(context.Background(), repositories.Domain{Name: name}))
+	}
+	return domains
+}
+
+func TestResolveHost(t *testing.T) {
+	tests := []struct {
+		name         string
+		domains      []string
+		host         string
+		expectedCode int
+		expectedKey  string
+	}{
+		{name: "no domain registered", host: "anything.com", expectedCode: http.StatusMovedPermanently, expectedKey: "abc"},
+		{name: "default domain", domains: []string{"go.acme.io", "acme.link"}, host: "go.acme.io", expectedCode: http.StatusMovedPermanently, expectedKey: "abc"},
+		{name: "other domain", domains: []string{"go.acme.io", "acme.link"}, host: "ACME.link:443", expectedCode: http.StatusMovedPermanently, expectedKey: "acme.link/abc"},
+		{name: "unregistered host", domains: []string{"go.acme.io"}, host: "evil.com", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			if tt.expectedKey != "" {
+				mockStore.On("GetURL", mock.Anything, tt.expectedKey).Return(repositories.Link{Code: "abc", URL: "https://example.com"}, nil)
+			}
+			mockTracker := new(MockTracker)
+			mockTracker.On("Track", mock.Anything, mock.Anything).Return()
+
+			router := chi.NewRouter()
+			router.With(ResolveHost(newTestDomains(t, tt.domains...))).
+				Get("/api/{code}", HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{}))
+
+			req := httptest.NewRequest("GET", "/api/abc", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusNotFound {
+				assert.JSONEq(t, `{"error":"domain not found"}`, w.Body.String())
+			}
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestResolveHost_DefaultFallback(t *testing.T) {
+	// a link bound to the legacy domain, rather than saved without a host
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "abc").Return(repositories.Link{}, repositories.ErrNotFound)
+	mockStore.On("GetURL", mock.Anything, "go.acme.io/abc").Return(repositories.Link{Code: "abc", Host: "go.acme.io", URL: "https://example.com"}, nil)
+	mockTracker := new(MockTracker)
+	mockTracker.On("Track", mock.Anything, "go.acme.io/abc").Return()
+
+	router := chi.NewRouter()
+	router.With(ResolveHost(newTestDomains(t, "go.acme.io"))).
+		Get("/api/{code}", HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{}))
+
+	req := httptest.NewRequest("GET", "/api/abc", nil)
+	req.Host = "go.acme.io"
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	mockStore.AssertExpectations(t)
+	mockTracker.AssertExpectations(t)
+}
+
+func TestResolveHost_DefaultSwitch(t *testing.T) {
+	domains := newTestDomains(t, "go.acme.io", "acme.link")
+	require.NoError(t, domains.SetDefaultDomain(context.Background(), "acme.link"))
+
+	// the links without a host stay on the legacy domain
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "abc").Return(repositories.Link{Code: "abc", URL: "https://example.com/legacy"}, nil)
+	mockStore.On("GetURL", mock.Anything, "acme.link/abc").Return(repositories.Link{Code: "abc", Host: "acme.link", URL: "https://example.com/new"}, nil)
+	mockTracker := new(MockTracker)
+	mockTracker.On("Track", mock.Anything, mock.Anything).Return()
+
+	router := chi.NewRouter()
+	router.With(ResolveHost(domains)).
+		Get("/api/{code}", HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{}))
+
+	for host, expected := range map[string]string{"go.acme.io": "https://example.com/legacy", "acme.link": "https://example.com/new"} {
+		req := httptest.NewRequest("GET", "/api/abc", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, expected, w.Header().Get("Location"))
+	}
+	mockStore.AssertExpectations(t)
+}
+
+func TestPostShortenedURL_Host(t *testing.T) {
+	domains := newTestDomains(t, "go.acme.io", "acme.link")
+
+	tests := []struct {
+		name         string
+		body         string
+		legacyLink   bool
+		expectedHost string
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "other domain", body: `{"url":"https://example.com","alias":"guide","host":"Acme.Link"}`, expectedHost: "acme.link", expectedCode: http.StatusCreated},
+		{name: "default domain", body: `{"url":"https://example.com","alias":"guide","host":"go.acme.io"}`, expectedHost: "go.acme.io", expectedCode: http.StatusCreated},
+		{name: "no domain", body: `{"url":"https://example.com","alias":"guide"}`, expectedHost: "go.acme.io", expectedCode: http.StatusCreated},
+		{name: "alias of a link without a host", body: `{"url":"https://example.com","alias":"guide"}`, legacyLink: true, expectedCode: http.StatusConflict, expectedBody: `{"error":"alias already in use"}`},
+		{name: "unregistered domain", body: `{"url":"https://example.com","host":"evil.com"}`, expectedCode: http.StatusBadRequest, expectedBody: `{"error":"domain is not registered"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			if tt.legacyLink {
+				mockStore.On("GetURL", mock.Anything, "guide").Return(repositories.Link{Code: "guide", URL: "https://example.com"}, nil)
+			} else {
+				mockStore.On("GetURL", mock.Anything, "guide").Return(repositories.Link{}, repositories.ErrNotFound).Maybe()
+			}
+			mockStore.On("SaveURLWithCode", mock.Anything, "guide", repositories.Link{URL: "https://example.com", Host: tt.expectedHost}).Return(nil)
+			handler := HandlePostShortenedURL(mockStore, domains, validation.Policy{})
+
+			req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+				mockStore.AssertNotCalled(t, "SaveURLWithCode", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				mockStore.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestPostShortenedURL_DefaultSwitch(t *testing.T) {
+	domains := newTestDomains(t, "go.acme.io", "acme.link")
+	require.NoError(t, domains.SetDefaultDomain(context.Background(), "acme.link"))
+
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveURLWithCode", mock.Anything, "guide", repositories.Link{URL: "https://example.com", Host: "acme.link"}).Return(nil)
+	handler := HandlePostShortenedURL(mockStore, domains, validation.Policy{})
+
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","alias":"guide"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStore.AssertExpectations(t)
+
+	// without a default there's no domain to bind the link to
+	require.NoError(t, domains.DeleteDomain(context.Background(), "acme.link"))
+	req = httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com"}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"no domain is the default, a host is required"}`, w.Body.String())
+}
+
+func TestRegisteredDomainsRejected(t *testing.T) {
+	domains := newTestDomains(t, "go.acme.io", "acme.link")
+
+	router := chi.NewRouter()
+	router.Post("/api/shorten", HandlePostShortenedURL(new(MockUrlRepository), domains, validation.Policy{}))
+	router.Put("/admin/{code}", HandleUpdateShortenedURL(new(MockUrlRepository), domains, validation.Policy{}))
+	router.Put("/admin/{code}/rules", HandlePutLinkRules(new(MockUrlRepository), domains, validation.Policy{}))
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		expected string
+	}{
+		{name: "shorten", method: "POST", target: "/api/shorten", body: `{"url":"https://acme.link/abc"}`, expected: "URL must not point to this shortener"},
+		{name: "update", method: "PUT", target: "/admin/abc", body: `{"new_url":"https://GO.acme.io/abc"}`, expected: "URL must not point to this shortener"},
+		{name: "rules", method: "PUT", target: "/admin/abc/rules", body: `{"rules":[{"url":"https://acme.link/abc","countries":["BR"]}]}`, expected: "rule 1: URL must not point to this shortener"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.JSONEq(t, `{"error":"`+tt.expected+`","reason":"own_domain"}`, w.Body.String())
+		})
+	}
+}
+
+func TestPostDomain(t *testing.T) {
+	domains := newTestDomains(t)
+	handler := HandlePostDomain(domains)
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedCode    int
+		expectedDefault bool
+	}{
+		{name: "first domain", body: `{"name":"Go.Acme.io."}`, expectedCode: http.StatusCreated, expectedDefault: true},
+		{name: "second domain", body: `{"name":"acme.link"}`, expectedCode: http.StatusCreated},
+		{name: "second domain as default", body: `{"name":"acme.dev","default":true}`, expectedCode: http.StatusCreated, expectedDefault: true},
+		{name: "already registered", body: `{"name":"go.acme.io"}`, expectedCode: http.StatusConflict},
+		{name: "invalid name", body: `{"name":"not a domain"}`, expectedCode: http.StatusBadRequest},
+		{name: "invalid body", body: `{`, expectedCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/domains", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusCreated {
+				var resp struct {
+					Data repositories.Domain `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.expectedDefault, resp.Data.Default)
+			}
+		})
+	}
+
+	registered, err := domains.ListDomains(context.Background())
+	require.NoError(t, err)
+	require.Len(t, registered, 3)
+	assert.Equal(t, "go.acme.io", registered[2].Name)
+	assert.False(t, registered[2].Default)
+}
+
+func TestDeleteAndDefaultDomain(t *testing.T) {
+	domains := newTestDomains(t, "go.acme.io", "acme.link")
+
+	router := chi.NewRouter()
+	router.Delete("/admin/domains/{name}", HandleDeleteDomain(domains))
+	router.Put("/admin/domains/{name}/default", HandlePutDefaultDomain(domains))
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		expectedCode int
+	}{
+		{name: "set default", method: "PUT", target: "/admin/domains/acme.link/default", expectedCode: http.StatusNoContent},
+		{name: "set unregistered default", method: "PUT", target: "/admin/domains/evil.com/default", expectedCode: http.StatusNotFound},
+		{name: "delete", method: "DELETE", target: "/admin/domains/go.acme.io", expectedCode: http.StatusNoContent},
+		{name: "delete unregistered", method: "DELETE", target: "/admin/domains/go.acme.io", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+
+	registered, err := domains.ListDomains(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []repositories.Domain{{Name: "acme.link", Default: true}}, registered)
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+	"url-shortener/internal/utils"
+)
+
+// HandleHealthz godoc
+// @Summary Health check
+// @Description Respond 200 while the server is up, for load balancers and orchestrators.
+// @Tags API
+// @Produce json
+// @Success 200 {object} utils.ApiResponse{data=string}
+// @Router /healthz [get]
+func HandleHealthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		utils.SendJSON(w, utils.ApiResponse{Data: "ok"}, http.StatusOK)
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"text/html", "application/json", "text/plain"}
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{accept: "", expected: "text/html"},
+		{accept: "*/*", expected: "text/html"},
+		{accept: "application/json", expected: "application/json"},
+		{accept: "text/plain", expected: "text/plain"},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", expected: "text/html"},
+		{accept: "application/json;q=0.5, text/plain", expected: "text/plain"},
+		{accept: "text/*;q=0.5, application/json", expected: "application/json"},
+		{accept: "text/html;q=0, */*", expected: "application/json"},
+		{accept: "image/png", expected: "text/html"},
+		{accept: "not a media type", expected: "text/html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.expected, negotiate(tt.accept, offers...))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"url-shortener/internal/qr"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+	"url-shortener/internal/validation"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// parseQROptions reads the rendering options from the query string.
+func parseQROptions(query url.Values) (qr.Options, error) {
+	opts := qr.DefaultOptions()
+
+	if size := query.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < qr.MinSize || n > qr.MaxSize {
+			return opts, fmt.Errorf("size must be between %d and %d", qr.MinSize, qr.MaxSize)
+		}
+		opts.Size = n
+	}
+
+	if level := query.Get("level"); level != "" {
+		level = strings.ToUpper(level)
+		if !qr.ValidLevel(level) {
+			return opts, errors.New("level must be L, M, Q or H")
+		}
+		opts.Level = level
+	}
+
+	if margin := query.Get("margin"); margin != "" {
+		n, err := strconv.Atoi(margin)
+		if err != nil || n < 0 || n > qr.MaxMargin {
+			return opts, fmt.Errorf("margin must be between 0 and %d", qr.MaxMargin)
+		}
+		opts.Margin = n
+	}
+
+	var err error
+	if fg := query.Get("fg"); fg != "" {
+		if opts.Foreground, err = qr.ParseColor(fg); err != nil {
+			return opts, errors.New("fg must be a hex color")
+		}
+	}
+	if bg := query.Get("bg"); bg != "" {
+		if opts.Background, err = qr.ParseColor(bg); err != nil {
+			return opts, errors.New("bg must be a hex color")
+		}
+	}
+
+	return opts, nil
+}
+
+// HandleGetQRCode godoc
+// @Summary Get shortened URL QR code
+// @Description Render a QR code of the full short URL as PNG or SVG. Expired, disabled and blocked links respond 410.
+// @Tags API
+// @Produce png,image/svg+xml,json
+// @Param code path string true "Shortened URL code"
+// @Param format query string false "Image format" Enums(png, svg) default(png)
+// @Param size query int false "Width and height in pixels" default(256) minimum(64) maximum(2048)
+// @Param level query string false "Error correction level" Enums(L, M, Q, H) default(M)
+// @Param margin query int false "Quiet zone around the code, in modules" default(4) minimum(0) maximum(16)
+// @Param fg query string false "Foreground hex color" default(000000)
+// @Param bg query string false "Background hex color" default(ffffff)
+// @Success 200 {file} binary
+// @Failure 400 {object} utils.ApiResponse{error=string}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 410 {object} utils.ApiResponse{error=string} "Expired, disabled or blocked"
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 429 {object} utils.ApiResponse{error=string}
+// @Router /api/v1/{code}/qr [get]
+func HandleGetQRCode(db repositories.UrlContract, blocklist validation.Blocklist, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := chi.URLParam(r, "code")
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "png"
+		}
+		if format != "png" && format != "svg" {
+			utils.SendJSON(w, utils.ApiResponse{Error: "format must be png or svg"}, http.StatusBadRequest)
+			return
+		}
+
+		opts, err := parseQROptions(r.URL.Query())
+		if err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		// no QR code for a link the redirect wouldn't follow
+		link, ok := getActiveLink(w, r, db, code)
+		if !ok {
+			return
+		}
+		if isBlocked(blocklist, link) {
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "url blocked",
+			}, http.StatusGone)
+			return
+		}
+
+		shortURL := shortURLBase(r, baseURL) + "/" + url.PathEscape(code)
+
+		var image []byte
+		contentType := "image/png"
+		if format == "svg" {
+			image, err = qr.SVG(shortURL, opts)
+			contentType = "image/svg+xml"
+		} else {
+			image, err = qr.PNG(shortURL, opts)
+		}
+		if err != nil {
+			if errors.Is(err, qr.ErrSizeTooSmall) {
+				utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+				return
+			}
+
+			slog.Error("error rendering qr code", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(image); err != nil {
+			slog.Error("error writing qr code", "error", err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"url-shortener/internal/repositories"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func serveQRCode(mockStore *MockUrlRepository, target string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Get("/api/{code}/qr", HandleGetQRCode(mockStore, stubBlocklist{"https://phishing.example/": true}, "https://sho.rt"))
+
+	req := httptest.NewRequest("GET", target, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetQRCode_PNG(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "abc12345").Return(repositories.Link{Code: "abc12345", URL: "https://example.com"}, nil)
+
+	w := serveQRCode(mockStore, "/api/abc12345/qr?size=128&level=h&margin=2&fg=%23112233&bg=fff")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+
+	img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 128, img.Bounds().Dx())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestGetQRCode_SVG(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "abc12345").Return(repositories.Link{Code: "abc12345", URL: "https://example.com"}, nil)
+
+	w := serveQRCode(mockStore, "/api/abc12345/qr?format=svg&fg=ff0000")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "<svg"))
+	assert.Contains(t, w.Body.String(), `fill="#ff0000"`)
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestGetQRCode_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		link         repositories.Link
+		getError     error
+		expectGet    bool
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "not found",
+			target:       "/api/abc12345/qr",
+			getError:     repositories.ErrNotFound,
+			expectGet:    true,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"url not found"}`,
+		},
+		{
+			name:         "expired",
+			target:       "/api/abc12345/qr",
+			getError:     repositories.ErrExpired,
+			expectGet:    true,
+			expectedCode: http.StatusGone,
+			expectedBody: `{"error":"url expired"}`,
+		},
+		{
+			name:         "disabled",
+			target:       "/api/abc12345/qr",
+			link:         repositories.Link{Code: "abc12345", URL: "https://example.com", Disabled: true},
+			expectGet:    true,
+			expectedCode: http.StatusGone,
+			expectedBody: `{"error":"url disabled"}`,
+		},
+		{
+			name:         "blocked",
+			target:       "/api/abc12345/qr",
+			link:         repositories.Link{Code: "abc12345", URL: "https://phishing.example/"},
+			expectGet:    true,
+			expectedCode: http.StatusGone,
+			expectedBody: `{"error":"url blocked"}`,
+		},
+		{
+			name:         "get failed",
+			target:       "/api/abc12345/qr",
+			getError:     assert.AnError,
+			expectGet:    true,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":"something went wrong"}`,
+		},
+		{
+			name:         "bad format",
+			target:       "/api/abc12345/qr?format=gif",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"format must be png or svg"}`,
+		},
+		{
+			name:         "bad size",
+			target:       "/api/abc12345/qr?size=10",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"size must be between 64 and 2048"}`,
+		},
+		{
+			name:         "bad level",
+			target:       "/api/abc12345/qr?level=X",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"level must be L, M, Q or H"}`,
+		},
+		{
+			name:         "bad margin",
+			target:       "/api/abc12345/qr?margin=-1",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"margin must be between 0 and 16"}`,
+		},
+		{
+			name:         "bad color",
+			target:       "/api/abc12345/qr?bg=white",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"bg must be a hex color"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			if tt.expectGet {
+				mockStore.On("GetURL", mock.Anything, "abc12345").Return(tt.link, tt.getError)
+			}
+
+			w := serveQRCode(mockStore, tt.target)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
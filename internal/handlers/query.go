@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"url-shortener/internal/repositories"
+)
+
+const (
+	maxQueryParams      = 30
+	maxQueryParamLength = 256
+)
+
+// ownQueryParams are read by the redirect handler itself, so they are
+// never forwarded to the target.
+var ownQueryParams = []string{"preview", "json"}
+
+// utmParams builds the utm_* params stored on a link.
+type utmParams struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+func (u utmParams) params() map[string]string {
+	params := map[string]string{}
+	for name, value := range map[string]string{
+		"utm_source":   u.Source,
+		"utm_medium":   u.Medium,
+		"utm_campaign": u.Campaign,
+		"utm_term":     u.Term,
+		"utm_content":  u.Content,
+	} {
+		if value != "" {
+			params[name] = value
+		}
+	}
+	return params
+}
+
+// buildQuery validates the params to store on a link along with the ones
+// built from utm, nil when there are none. A param set in both is an error,
+// as it's unclear which one is meant.
+func buildQuery(query map[string]string, utm *utmParams) (map[string]string, error) {
+	params := make(map[string]string, len(query))
+	for name, value := range query {
+		params[name] = value
+	}
+
+	if utm != nil {
+		for name, value := range utm.params() {
+			if _, ok := params[name]; ok {
+				return nil, fmt.Errorf("%s is set in both query and utm", name)
+			}
+			params[name] = value
+		}
+	}
+
+	if len(params) > maxQueryParams {
+		return nil, fmt.Errorf("at most %d query params are allowed", maxQueryParams)
+	}
+	for name, value := range params {
+		if name == "" {
+			return nil, errors.New("query param names can't be empty")
+		}
+		if len(name) > maxQueryParamLength || len(value) > maxQueryParamLength {
+			return nil, fmt.Errorf("query param %q must be at most %d characters long", name, maxQueryParamLength)
+		}
+	}
+
+	if len(params) == 0 {
+		return nil, nil
+	}
+	return params, nil
+}
+
+// forwardedQuery returns the query of the request to forward to the target
+// of the link, nil when the link doesn't forward it.
+func forwardedQuery(r *http.Request, link repositories.Link) url.Values {
+	if !link.ForwardQuery || r.URL.RawQuery == "" {
+		return nil
+	}
+
+	query := r.URL.Query()
+	for _, name := range ownQueryParams {
+		query.Del(name)
+	}
+	return query
+}
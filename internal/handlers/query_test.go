@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetShortenedURL_Query(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     repositories.Link
+		path     string
+		expected string
+	}{
+		{
+			name:     "stored params",
+			link:     repositories.Link{Code: "123", URL: "https://example.com/page?utm_source=old&id=1", Query: map[string]string{"utm_source": "news"}},
+			path:     "/api/123?ref=x",
+			expected: "https://example.com/page?id=1&utm_source=news",
+		},
+		{
+			name:     "forwarded params",
+			link:     repositories.Link{Code: "123", URL: "https://example.com/page", ForwardQuery: true},
+			path:     "/api/123?ref=x&preview=0",
+			expected: "https://example.com/page?ref=x",
+		},
+		{
+			name:     "stored params win",
+			link:     repositories.Link{Code: "123", URL: "https://example.com/page?id=1", Query: map[string]string{"utm_source": "news"}, ForwardQuery: true},
+			path:     "/api/123?utm_source=spoofed&id=2&ref=x",
+			expected: "https://example.com/page?id=1&ref=x&utm_source=news",
+		},
+		{
+			name:     "nothing to add",
+			link:     repositories.Link{Code: "123", URL: "https://example.com/page?b=2&a=1", ForwardQuery: true},
+			path:     "/api/123",
+			expected: "https://example.com/page?b=2&a=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(tt.link, nil)
+			mockTracker := new(MockTracker)
+			mockTracker.On("Track", mock.Anything, "123").Return()
+			handler := HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{})
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/api/{code}", handler.ServeHTTP)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusMovedPermanently, w.Code)
+			assert.Equal(t, tt.expected, w.Header().Get("Location"))
+
+			mockStore.AssertExpectations(t)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostShortenedURL_Query(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveShortenedURL", mock.Anything, repositories.Link{
+		URL:          "https://example.com",
+		Query:        map[string]string{"ref": "mail", "utm_source": "news", "utm_campaign": "launch"},
+		ForwardQuery: true,
+	}).Return("abc12345", nil)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","query":{"ref":"mail"},"utm":{"source":"news","campaign":"launch"},"forward_query":true}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"data":"abc12345"}`, w.Body.String())
+
+	req = httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","query":{"utm_source":"mail"},"utm":{"source":"news"}}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"utm_source is set in both query and utm"}`, w.Body.String())
+
+	req = httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","query":{"":"x"}}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"query param names can't be empty"}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
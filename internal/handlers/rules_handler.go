@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/targeting"
+	"url-shortener/internal/utils"
+	"url-shortener/internal/validation"
+)
+
+// validateRules normalizes the rules and checks their URLs against the
+// policy, like the URL of the link.
+func validateRules(rules []targeting.Rule, policy validation.Policy) ([]targeting.Rule, error) {
+	rules, err := targeting.Normalize(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, rule := range rules {
+		if err := policy.Validate(rule.URL); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+
+	return rules, nil
+}
+
+type rulesBody struct {
+	// Rules replace all the rules of the link, an empty list removing them.
+	Rules []targeting.Rule `json:"rules"`
+}
+
+// HandleGetLinkRules godoc
+// @Summary Get shortened URL targeting rules
+// @Description Get the targeting rules of the link, in the order they are evaluated
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param code path string true "Shortened URL code"
+// @Param host query string false "Domain the link is bound to, for the links that have one"
+// @Success 200 {object} utils.ApiResponse{data=[]targeting.Rule}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/{code}/rules [get]
+func HandleGetLinkRules(db repositories.UrlContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := adminLinkKey(r)
+
+		link, err := db.GetURL(r.Context(), code)
+		if err != nil && !errors.Is(err, repositories.ErrExpired) {
+			if errors.Is(err, repositories.ErrNotFound) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "url not found",
+				}, http.StatusNotFound)
+				return
+			}
+
+			slog.Error("error get url", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		rules := link.Rules
+		if rules == nil {
+			rules = []targeting.Rule{}
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: rules}, http.StatusOK)
+	}
+}
+
+// HandlePutLinkRules godoc
+// @Summary Replace shortened URL targeting rules
+// @Description Replace the targeting rules of the link. Each rule matches on platforms (ios, android, windows, macos, linux,
+// @Description mobile or desktop), languages, countries and a time window, and needs at least one of them.
+// @Description The first matching rule wins, the link URL being the fallback.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Accept json
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param code path string true "Shortened URL code"
+// @Param host query string false "Domain the link is bound to, for the links that have one"
+// @Param data body rulesBody true "Targeting rules"
+// @Success 200 {object} utils.ApiResponse{data=[]targeting.Rule}
+// @Failure 400 {object} utils.ApiResponse{error=string}
+// @Failure 422 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/{code}/rules [put]
+func HandlePutLinkRules(db repositories.UrlContract, domains repositories.DomainContract, policy validation.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := adminLinkKey(r)
+
+		var body rulesBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: "invalid request body"}, http.StatusUnprocessableEntity)
+			return
+		}
+
+		policy, err := loadDomainPolicy(r.Context(), domains, policy)
+		if err != nil {
+			slog.Error("error get domains", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		rules, err := validateRules(body.Rules, policy)
+		if err != nil {
+			utils.SendJSON(w, errorResponse(err), http.StatusBadRequest)
+			return
+		}
+		if len(rules) == 0 {
+			rules = nil
+		}
+
+		_, err = db.UpdateURL(r.Context(), code, func(link *repositories.Link) error {
+			link.Rules = rules
+			return nil
+		})
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "url not found",
+				}, http.StatusNotFound)
+				return
+			}
+			slog.Error("error saving url rules", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		if rules == nil {
+			rules = []targeting.Rule{}
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: rules}, http.StatusOK)
+	}
+}
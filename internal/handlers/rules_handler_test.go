@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/targeting"
+	"url-shortener/internal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetShortenedURL_Targeted(t *testing.T) {
+	link := repositories.Link{Code: "123", URL: "https://example.com", Rules: []targeting.Rule{
+		{URL: "https://apps.apple.com/app", Platforms: []string{"ios"}},
+		{URL: "https://example.com.br", Countries: []string{"BR"}},
+	}}
+
+	tests := []struct {
+		name      string
+		userAgent string
+		country   string
+		expected  string
+	}{
+		{name: "platform", userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)", country: "BR", expected: "https://apps.apple.com/app"},
+		{name: "country", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", country: "br", expected: "https://example.com.br"},
+		{name: "fallback", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", country: "US", expected: "https://example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(link, nil)
+			mockTracker := new(MockTracker)
+			mockTracker.On("Track", mock.Anything, "123").Return()
+			handler := HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{CountryHeader: "X-Country-Code"})
+
+			req := httptest.NewRequest("GET", "/api/123", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			req.Header.Set("X-Country-Code", tt.country)
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/api/{code}", handler.ServeHTTP)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusMovedPermanently, w.Code)
+			assert.Equal(t, tt.expected, w.Header().Get("Location"))
+			assert.Equal(t, noCacheControl, w.Header().Get("Cache-Control"))
+
+			mockStore.AssertExpectations(t)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetShortenedURL_TargetBlocked(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "123").Return(repositories.Link{Code: "123", URL: "https://example.com", Rules: []targeting.Rule{
+		{URL: "https://evil.com", Platforms: []string{"android"}},
+	}}, nil)
+	handler := HandleGetShortenedURL(mockStore, new(MockTracker), stubBlocklist{"https://evil.com": true}, RedirectOptions{})
+
+	req := httptest.NewRequest("GET", "/api/123", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 14; Pixel 8)")
+	w := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	router.Get("/api/{code}", handler.ServeHTTP)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "https://evil.com")
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestGetLinkRules(t *testing.T) {
+	tests := []struct {
+		name         string
+		link         repositories.Link
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "rules",
+			link:         repositories.Link{Code: "123", Rules: []targeting.Rule{{URL: "https://example.de", Countries: []string{"DE"}}}},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":[{"url":"https://example.de","countries":["DE"]}]}`,
+		},
+		{
+			name:         "none",
+			link:         repositories.Link{Code: "123"},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":[]}`,
+		},
+		{
+			name:         "not found",
+			err:          repositories.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"url not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(tt.link, tt.err)
+			handler := HandleGetLinkRules(mockStore)
+
+			req := httptest.NewRequest("GET", "/admin/123/rules", nil)
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/admin/{code}/rules", handler.ServeHTTP)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPutLinkRules(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		updateErr    error
+		callsUpdate  bool
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "valid",
+			body:         `{"rules":[{"url":"https://play.google.com/app","platforms":["Android"]},{"url":"https://example.com.br","countries":["br"],"window":{"hours":"09:00-18:00"}}]}`,
+			callsUpdate:  true,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":[{"url":"https://play.google.com/app","platforms":["android"]},{"url":"https://example.com.br","countries":["BR"],"window":{"hours":"09:00-18:00"}}]}`,
+		},
+		{
+			name:         "cleared",
+			body:         `{"rules":[]}`,
+			callsUpdate:  true,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":[]}`,
+		},
+		{
+			name:         "invalid rule",
+			body:         `{"rules":[{"url":"https://example.com"}]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"rule 1: at least one condition is required"}`,
+		},
+		{
+			name:         "rejected url",
+			body:         `{"rules":[{"url":"javascript:alert(1)","platforms":["ios"]}]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"rule 1: URL scheme must be one of http, https","reason":"scheme_not_allowed"}`,
+		},
+		{
+			name:         "invalid body",
+			body:         `{"rules":`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"invalid request body"}`,
+		},
+		{
+			name:         "not found",
+			body:         `{"rules":[]}`,
+			updateErr:    repositories.ErrNotFound,
+			callsUpdate:  true,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"url not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			if tt.callsUpdate {
+				mockStore.On("UpdateURL", mock.Anything, "123").Return(repositories.Link{Code: "123", URL: "https://example.com"}, tt.updateErr)
+			}
+			handler := HandlePutLinkRules(mockStore, newTestDomains(t), validation.Policy{})
+
+			req := httptest.NewRequest("PUT", "/admin/123/rules", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Put("/admin/{code}/rules", handler.ServeHTTP)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+)
+
+// HandleGetURLStats godoc
+// @Summary Get shortened URL stats
+// @Description Get the click stats of the shortened URL that match the code passed
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param code path string true "Shortened URL code"
+// @Param host query string false "Domain the link is bound to, for the links that have one"
+// @Success 200 {object} utils.ApiResponse{data=repositories.URLStats}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/{code}/stats [get]
+func HandleGetURLStats(db repositories.UrlContract, stats repositories.StatsContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := adminLinkKey(r)
+
+		// expired links keep their stats until they are swept
+		if _, err := db.GetURL(r.Context(), code); err != nil && !errors.Is(err, repositories.ErrExpired) {
+			if errors.Is(err, repositories.ErrNotFound) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "url not found",
+				}, http.StatusNotFound)
+				return
+			}
+
+			slog.Error("error get url", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		urlStats, err := stats.GetStats(r.Context(), code)
+		if err != nil {
+			slog.Error("error get url stats", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: urlStats}, http.StatusOK)
+	}
+}
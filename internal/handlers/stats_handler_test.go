@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStatsRepository struct {
+	mock.Mock
+}
+
+func (m *MockStatsRepository) RecordClicks(ctx context.Context, clicks []repositories.Click) error {
+	args := m.Called(ctx, clicks)
+	return args.Error(0)
+}
+
+func (m *MockStatsRepository) GetStats(ctx context.Context, code string) (repositories.URLStats, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(repositories.URLStats), args.Error(1)
+}
+
+func TestGetURLStats(t *testing.T) {
+	urlStats := repositories.URLStats{
+		TotalClicks:    3,
+		UniqueVisitors: 2,
+		Daily:          map[string]int64{"2026-01-01": 3},
+		Hourly:         map[string]int64{"2026-01-01T10": 1, "2026-01-01T11": 2},
+		Referrers:      map[string]int64{},
+		Countries:      map[string]int64{"BR": 3},
+	}
+	tests := []struct {
+		name         string
+		mockGetError error
+		callsStats   bool
+		mockStatsErr error
+		expectedCode int
+		expectedBody utils.ApiResponse
+	}{
+		{
+			name:         "valid request",
+			callsStats:   true,
+			expectedCode: http.StatusOK,
+			expectedBody: utils.ApiResponse{Data: urlStats},
+		},
+		{
+			name:         "expired url",
+			mockGetError: repositories.ErrExpired,
+			callsStats:   true,
+			expectedCode: http.StatusOK,
+			expectedBody: utils.ApiResponse{Data: urlStats},
+		},
+		{
+			name:         "url not found",
+			mockGetError: repositories.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: utils.ApiResponse{Error: "url not found"},
+		},
+		{
+			name:         "something went wrong",
+			callsStats:   true,
+			mockStatsErr: assert.AnError,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: utils.ApiResponse{Error: "something went wrong"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(repositories.Link{Code: "123", URL: "https://example.com"}, tt.mockGetError)
+			mockStats := new(MockStatsRepository)
+			if tt.callsStats {
+				mockStats.On("GetStats", mock.Anything, "123").Return(urlStats, tt.mockStatsErr)
+			}
+			handler := HandleGetURLStats(mockStore, mockStats)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/123/stats", nil)
+			rr := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/admin/{code}/stats", handler.ServeHTTP)
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			expectedBody, _ := json.Marshal(tt.expectedBody)
+			assert.JSONEq(t, string(expectedBody), rr.Body.String())
+
+			mockStore.AssertExpectations(t)
+			mockStats.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"url-shortener/internal/analytics"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/split"
+	"url-shortener/internal/targeting"
+	"url-shortener/internal/utils"
+	"url-shortener/internal/validation"
+)
+
+const (
+	// variantCookiePrefix followed by the code names the cookie keeping the
+	// variant a visitor was sent to.
+	variantCookiePrefix = "variant_"
+	variantCookieMaxAge = 30 * 24 * time.Hour
+)
+
+// resolveTarget sets the link URL to the one the visitor is redirected to.
+// The first matching targeting rule wins, otherwise split links send the
+// visitor to its variant, recorded in the returned request for the stats.
+// The stored and forwarded query params are then added to it.
+func resolveTarget(w http.ResponseWriter, r *http.Request, link *repositories.Link, countryHeader string) *http.Request {
+	forwarded := forwardedQuery(r, *link)
+
+	var target string
+	var matched bool
+	if len(link.Rules) > 0 {
+		target, matched = targeting.Match(link.Rules, targeting.NewVisitor(r, countryHeader, time.Now()))
+	}
+
+	switch {
+	case matched:
+		link.URL = target
+	case len(link.Variants) > 0:
+		variant := stickyVariant(w, r, *link)
+		link.URL = variant.URL
+		r = analytics.WithVariant(r, variant.Name)
+	}
+
+	link.URL = utils.MergeQuery(link.URL, link.Query, forwarded)
+	return r
+}
+
+// stickyVariant returns the variant kept in the visitor cookie, drawing a
+// new one when there is none or it was removed from the link, so repeat
+// visitors keep seeing the same variant.
+func stickyVariant(w http.ResponseWriter, r *http.Request, link repositories.Link) split.Variant {
+	name := variantCookiePrefix + link.Code
+	if cookie, err := r.Cookie(name); err == nil {
+		if variant, ok := split.Find(link.Variants, cookie.Value); ok {
+			return variant
+		}
+	}
+
+	variant := split.Pick(link.Variants)
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    variant.Name,
+		Path:     "/",
+		MaxAge:   int(variantCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return variant
+}
+
+// validateVariants checks the variants and their URLs against the policy,
+// like the URL of the link.
+func validateVariants(variants []split.Variant, policy validation.Policy) error {
+	if err := split.Validate(variants); err != nil {
+		return err
+	}
+
+	for i, variant := range variants {
+		if err := policy.Validate(variant.URL); err != nil {
+			return fmt.Errorf("variant %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/split"
+	"url-shortener/internal/targeting"
+	"url-shortener/internal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetShortenedURL_Split(t *testing.T) {
+	link := repositories.Link{Code: "123", URL: "https://example.com", Variants: []split.Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 1},
+		{Name: "b", URL: "https://example.com/b", Weight: 1},
+	}}
+
+	tests := []struct {
+		name      string
+		cookie    string
+		expected  []string
+		setCookie bool
+	}{
+		{name: "new visitor", expected: []string{"https://example.com/a", "https://example.com/b"}, setCookie: true},
+		{name: "repeat visitor", cookie: "b", expected: []string{"https://example.com/b"}},
+		{name: "removed variant", cookie: "c", expected: []string{"https://example.com/a", "https://example.com/b"}, setCookie: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(link, nil)
+			mockTracker := new(MockTracker)
+			mockTracker.On("Track", mock.Anything, "123").Return()
+			handler := HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{})
+
+			req := httptest.NewRequest("GET", "/api/123", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "variant_123", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/api/{code}", handler.ServeHTTP)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusMovedPermanently, w.Code)
+			assert.Contains(t, tt.expected, w.Header().Get("Location"))
+			assert.Equal(t, noCacheControl, w.Header().Get("Cache-Control"))
+
+			cookies := w.Result().Cookies()
+			if tt.setCookie {
+				require.Len(t, cookies, 1)
+				assert.Equal(t, "variant_123", cookies[0].Name)
+				assert.Equal(t, "https://example.com/"+cookies[0].Value, w.Header().Get("Location"))
+				assert.True(t, cookies[0].HttpOnly)
+			} else {
+				assert.Empty(t, cookies)
+			}
+
+			mockStore.AssertExpectations(t)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetShortenedURL_SplitAfterRules(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "123").Return(repositories.Link{
+		Code:  "123",
+		URL:   "https://example.com",
+		Rules: []targeting.Rule{{URL: "https://apps.apple.com/app", Platforms: []string{"ios"}}},
+		Variants: []split.Variant{
+			{Name: "a", URL: "https://example.com/a", Weight: 1},
+			{Name: "b", URL: "https://example.com/b", Weight: 1},
+		},
+	}, nil)
+	mockTracker := new(MockTracker)
+	mockTracker.On("Track", mock.Anything, "123").Return()
+	handler := HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{})
+
+	req := httptest.NewRequest("GET", "/api/123", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+	w := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	router.Get("/api/{code}", handler.ServeHTTP)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "https://apps.apple.com/app", w.Header().Get("Location"))
+	assert.Empty(t, w.Result().Cookies())
+
+	mockStore.AssertExpectations(t)
+	mockTracker.AssertExpectations(t)
+}
+
+func TestPostShortenedURL_Variants(t *testing.T) {
+	variants := []split.Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 70},
+		{Name: "b", URL: "https://example.com/b", Weight: 30},
+	}
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveShortenedURL", mock.Anything, repositories.Link{URL: "https://example.com", Variants: variants}).Return("abc12345", nil)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","variants":[{"name":"a","url":"https://example.com/a","weight":70},{"name":"b","url":"https://example.com/b","weight":30}]}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"data":"abc12345"}`, w.Body.String())
+
+	req = httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","variants":[{"name":"a","url":"https://example.com/a","weight":1},{"name":"b","url":"http://localhost","weight":1}]}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"variant 2: URL must not point to a private or local address","reason":"private_address"}`, w.Body.String())
+
+	req = httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","variants":[{"name":"a","url":"https://example.com/a","weight":1}]}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"at least two variants are required"}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
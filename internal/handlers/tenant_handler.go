@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"url-shortener/internal/auth"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxTenantNameLength = 100
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// principalTenant returns the tenant the request credentials are limited
+// to, empty when they aren't.
+func principalTenant(r *http.Request) string {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	return principal.Tenant
+}
+
+// RequireOwnLink responds 404 to tenant credentials for the links of the
+// other tenants, as if they didn't exist. It must run after Require, on
+// routes with a code param.
+func RequireOwnLink(db repositories.UrlContract) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := principalTenant(r)
+			if tenant == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			link, err := db.GetURL(r.Context(), adminLinkKey(r))
+			if err != nil && !errors.Is(err, repositories.ErrExpired) && !errors.Is(err, repositories.ErrNotFound) {
+				slog.Error("error get url", "error", err)
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "something went wrong",
+				}, http.StatusInternalServerError)
+				return
+			}
+			// links not found are zero, so never match the tenant
+			if link.Tenant != tenant {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "url not found",
+				}, http.StatusNotFound)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type postTenantBody struct {
+	ID   string `json:"id" example:"acme"`
+	Name string `json:"name" example:"Acme Inc."`
+	// MaxLinks caps how many links the tenant holds, zero meaning unlimited.
+	MaxLinks int64 `json:"max_links" example:"1000"`
+}
+
+type putTenantBody struct {
+	Name     *string `json:"name" example:"Acme Inc."`
+	MaxLinks *int64  `json:"max_links" example:"5000"`
+}
+
+func validateTenantName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if len(name) > maxTenantNameLength {
+		return "", fmt.Errorf("name must be at most %d characters long", maxTenantNameLength)
+	}
+	return name, nil
+}
+
+func validateMaxLinks(maxLinks int64) error {
+	if maxLinks < 0 {
+		return errors.New("max_links must not be negative")
+	}
+	return nil
+}
+
+// HandlePostTenant godoc
+// @Summary Create tenant
+// @Description Create a tenant, a workspace whose links are listed and managed apart through the API keys issued to it.
+// @Description The id is 1 to 32 lowercase letters, digits or dashes. A max_links of 0 doesn't limit the tenant links.
+// @Description Not allowed for tenant API keys.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Accept json
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param data body postTenantBody true "Tenant Post Body"
+// @Success 201 {object} utils.ApiResponse{data=repositories.Tenant}
+// @Failure 400 {object} utils.ApiResponse{error=string}
+// @Failure 409 {object} utils.ApiResponse{error=string}
+// @Failure 422 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/tenants [post]
+func HandlePostTenant(tenants repositories.TenantContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body postTenantBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: "invalid request body"}, http.StatusUnprocessableEntity)
+			return
+		}
+
+		if !tenantIDPattern.MatchString(body.ID) {
+			utils.SendJSON(w, utils.ApiResponse{Error: "id must be 1 to 32 lowercase letters, digits or dashes"}, http.StatusBadRequest)
+			return
+		}
+		name, err := validateTenantName(body.Name)
+		if err == nil {
+			err = validateMaxLinks(body.MaxLinks)
+		}
+		if err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		tenant := repositories.Tenant{ID: body.ID, Name: name, MaxLinks: body.MaxLinks, CreatedAt: now, UpdatedAt: now}
+		if err := tenants.SaveTenant(r.Context(), tenant); err != nil {
+			if errors.Is(err, repositories.ErrTenantExists) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "tenant already exists",
+				}, http.StatusConflict)
+				return
+			}
+
+			slog.Error("error saving tenant", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: tenant}, http.StatusCreated)
+	}
+}
+
+// HandleGetTenants godoc
+// @Summary List tenants
+// @Description List the tenants along with their count of links. Not allowed for tenant API keys.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Success 200 {object} utils.ApiResponse{data=[]repositories.Tenant}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/tenants [get]
+func HandleGetTenants(tenants repositories.TenantContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := tenants.ListTenants(r.Context())
+		if err != nil {
+			slog.Error("error get tenants", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: list}, http.StatusOK)
+	}
+}
+
+// HandleGetTenant godoc
+// @Summary Get tenant
+// @Description Get the tenant that match the id passed along with its count of links.
+// @Description Tenant API keys can only get their own tenant.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param id path string true "Tenant id"
+// @Success 200 {object} utils.ApiResponse{data=repositories.Tenant}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/tenants/{id} [get]
+func HandleGetTenant(tenants repositories.TenantContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if tenant := principalTenant(r); tenant != "" && tenant != id {
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "tenant not found",
+			}, http.StatusNotFound)
+			return
+		}
+
+		tenant, err := tenants.GetTenant(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, repositories.ErrTenantNotFound) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "tenant not found",
+				}, http.StatusNotFound)
+				return
+			}
+
+			slog.Error("error get tenant", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: tenant}, http.StatusOK)
+	}
+}
+
+// HandlePutTenant godoc
+// @Summary Update tenant
+// @Description Update the name or the link quota of the tenant, the fields left out are kept.
+// @Description Lowering max_links under the count of links only blocks new links. Not allowed for tenant API keys.
+// @Security BasicAuth
+// @Tags ADMIN
+// @Accept json
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param id path string true "Tenant id"
+// @Param data body putTenantBody true "Tenant Put Body"
+// @Success 200 {object} utils.ApiResponse{data=repositories.Tenant}
+// @Failure 400 {object} utils.ApiResponse{error=string}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 422 {object} utils.ApiResponse{error=string}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/tenants/{id} [put]
+func HandlePutTenant(tenants repositories.TenantContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		var body putTenantBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: "invalid request body"}, http.StatusUnprocessableEntity)
+			return
+		}
+
+		var name string
+		var err error
+		if body.Name != nil {
+			name, err = validateTenantName(*body.Name)
+		}
+		if err == nil && body.MaxLinks != nil {
+			err = validateMaxLinks(*body.MaxLinks)
+		}
+		if err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		tenant, err := tenants.UpdateTenant(r.Context(), id, func(tenant *repositories.Tenant) error {
+			if body.Name != nil {
+				tenant.Name = name
+			}
+			if body.MaxLinks != nil {
+				tenant.MaxLinks = *body.MaxLinks
+			}
+			return nil
+		})
+		if err != nil {
+			if errors.Is(err, repositories.ErrTenantNotFound) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "tenant not found",
+				}, http.StatusNotFound)
+				return
+			}
+
+			slog.Error("error updating tenant", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: tenant}, http.StatusOK)
+	}
+}
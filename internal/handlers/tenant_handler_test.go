@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/auth"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTenantRepository struct {
+	mock.Mock
+}
+
+func (m *MockTenantRepository) SaveTenant(ctx context.Context, tenant repositories.Tenant) error {
+	args := m.Called(ctx, tenant)
+	return args.Error(0)
+}
+
+func (m *MockTenantRepository) GetTenant(ctx context.Context, id string) (repositories.Tenant, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(repositories.Tenant), args.Error(1)
+}
+
+func (m *MockTenantRepository) ListTenants(ctx context.Context) ([]repositories.Tenant, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]repositories.Tenant), args.Error(1)
+}
+
+func (m *MockTenantRepository) UpdateTenant(ctx context.Context, id string, update func(tenant *repositories.Tenant) error) (repositories.Tenant, error) {
+	args := m.Called(ctx, id)
+	tenant := args.Get(0).(repositories.Tenant)
+	if err := args.Error(1); err != nil {
+		return repositories.Tenant{}, err
+	}
+	if err := update(&tenant); err != nil {
+		return repositories.Tenant{}, err
+	}
+	return tenant, nil
+}
+
+// newTenantKey saves an API key of the tenant, empty for a key allowed
+// across tenants, and returns it.
+func newTenantKey(t *testing.T, keys repositories.ApiKeyContract, tenant string, scope string) string {
+	key, apiKey, err := auth.GenerateKey("key-"+tenant, []string{scope}, time.Now())
+	require.NoError(t, err)
+	apiKey.Tenant = tenant
+	require.NoError(t, keys.SaveApiKey(context.Background(), apiKey))
+	return key
+}
+
+func TestPostTenant(t *testing.T) {
+	mockStore := new(MockTenantRepository)
+	mockStore.On("SaveTenant", mock.Anything, mock.MatchedBy(func(tenant repositories.Tenant) bool {
+		return tenant.ID == "acme" && tenant.Name == "Acme" && tenant.MaxLinks == 10 && !tenant.CreatedAt.IsZero()
+	})).Return(nil).Once()
+	mockStore.On("SaveTenant", mock.Anything, mock.Anything).Return(repositories.ErrTenantExists).Once()
+	handler := HandlePostTenant(mockStore)
+
+	req := httptest.NewRequest("POST", "/admin/tenants", bytes.NewBufferString(`{"id":"acme","name":" Acme ","max_links":10}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp struct {
+		Data repositories.Tenant `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "acme", resp.Data.ID)
+	assert.Equal(t, "Acme", resp.Data.Name)
+
+	req = httptest.NewRequest("POST", "/admin/tenants", bytes.NewBufferString(`{"id":"acme","name":"Acme"}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"tenant already exists"}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestPostTenant_BadRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{name: "no id", body: `{"name":"Acme"}`, expected: "id must be 1 to 32 lowercase letters, digits or dashes"},
+		{name: "uppercase id", body: `{"id":"Acme","name":"Acme"}`, expected: "id must be 1 to 32 lowercase letters, digits or dashes"},
+		{name: "leading dash", body: `{"id":"-acme","name":"Acme"}`, expected: "id must be 1 to 32 lowercase letters, digits or dashes"},
+		{name: "no name", body: `{"id":"acme","name":" "}`, expected: "name is required"},
+		{name: "negative quota", body: `{"id":"acme","name":"Acme","max_links":-1}`, expected: "max_links must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockTenantRepository)
+			handler := HandlePostTenant(mockStore)
+
+			req := httptest.NewRequest("POST", "/admin/tenants", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.JSONEq(t, `{"error":"`+tt.expected+`"}`, w.Body.String())
+			mockStore.AssertNotCalled(t, "SaveTenant")
+		})
+	}
+}
+
+func TestPutTenant(t *testing.T) {
+	mockStore := new(MockTenantRepository)
+	mockStore.On("UpdateTenant", mock.Anything, "acme").Return(repositories.Tenant{ID: "acme", Name: "Acme", MaxLinks: 10}, nil)
+	mockStore.On("UpdateTenant", mock.Anything, "unknown").Return(repositories.Tenant{}, repositories.ErrTenantNotFound)
+
+	router := chi.NewRouter()
+	router.Put("/admin/tenants/{id}", HandlePutTenant(mockStore))
+
+	req := httptest.NewRequest("PUT", "/admin/tenants/acme", bytes.NewBufferString(`{"max_links":0}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"id":"acme","name":"Acme","max_links":0,"links":0,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}}`, w.Body.String())
+
+	req = httptest.NewRequest("PUT", "/admin/tenants/unknown", bytes.NewBufferString(`{"name":"Unknown"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestGetTenant_OtherTenant(t *testing.T) {
+	keys := repositories.NewMemoryApiKeyRepository()
+	acmeKey := newTenantKey(t, keys, "acme", auth.ScopeLinksRead)
+
+	mockStore := new(MockTenantRepository)
+	mockStore.On("GetTenant", mock.Anything, "acme").Return(repositories.Tenant{ID: "acme", Name: "Acme", Links: 3}, nil)
+
+	router := chi.NewRouter()
+	router.With(auth.NewAuthenticator(keys, "", "").Require(auth.ScopeLinksRead)).
+		Get("/admin/tenants/{id}", HandleGetTenant(mockStore))
+
+	req := httptest.NewRequest("GET", "/admin/tenants/acme", nil)
+	req.Header.Set("Authorization", "Bearer "+acmeKey)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"links":3`)
+
+	req = httptest.NewRequest("GET", "/admin/tenants/globex", nil)
+	req.Header.Set("Authorization", "Bearer "+acmeKey)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"tenant not found"}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestTenantLinks(t *testing.T) {
+	keys := repositories.NewMemoryApiKeyRepository()
+	acmeKey := newTenantKey(t, keys, "acme", auth.ScopeLinksAdmin)
+	rootKey := newTenantKey(t, keys, "", auth.ScopeLinksAdmin)
+
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveShortenedURL", mock.Anything, repositories.Link{
+		URL:     "https://example.com",
+		Creator: "key-acme",
+		Tenant:  "acme",
+	}).Return("", repositories.ErrQuotaExceeded)
+	mockStore.On("ListURL", mock.Anything, repositories.ListOptions{Tenant: "acme"}).Return(repositories.LinkPage{}, nil)
+	mockStore.On("ListURL", mock.Anything, repositories.ListOptions{Tenant: "globex"}).Return(repositories.LinkPage{}, nil)
+	mockStore.On("GetURL", mock.Anything, "acme1").Return(repositories.Link{Code: "acme1", Tenant: "acme"}, nil)
+	mockStore.On("GetURL", mock.Anything, "globex1").Return(repositories.Link{Code: "globex1", Tenant: "globex"}, nil)
+	mockStore.On("DeleteURL", mock.Anything, "acme1").Return(nil)
+
+	router := chi.NewRouter()
+	router.Use(auth.NewAuthenticator(keys, "", "").Require(auth.ScopeLinksAdmin))
+	router.Post("/api/shorten", HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{}))
+	router.Get("/admin/all", HandleGetAllUrls(mockStore))
+	router.With(RequireOwnLink(mockStore)).Delete("/admin/{code}", HandleDeleteShortenedURL(mockStore))
+
+	tests := []struct {
+		name         string
+		key          string
+		method       string
+		target       string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "quota exceeded", key: acmeKey, method: "POST", target: "/api/shorten", body: `{"url":"https://example.com"}`, expectedCode: http.StatusForbidden, expectedBody: `{"error":"link quota exceeded"}`},
+		{name: "tenant listing", key: acmeKey, method: "GET", target: "/admin/all?tenant=globex", expectedCode: http.StatusOK},
+		{name: "root listing a tenant", key: rootKey, method: "GET", target: "/admin/all?tenant=globex", expectedCode: http.StatusOK},
+		{name: "other tenant link", key: acmeKey, method: "DELETE", target: "/admin/globex1", expectedCode: http.StatusNotFound, expectedBody: `{"error":"url not found"}`},
+		{name: "own link", key: acmeKey, method: "DELETE", target: "/admin/acme1", expectedCode: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+
+	mockStore.AssertExpectations(t)
+	mockStore.AssertNotCalled(t, "DeleteURL", mock.Anything, "globex1")
+}
+
+func TestApiKeys_Tenant(t *testing.T) {
+	keys := repositories.NewMemoryApiKeyRepository()
+	acmeKey := newTenantKey(t, keys, "acme", auth.ScopeLinksAdmin)
+	newTenantKey(t, keys, "globex", auth.ScopeLinksAdmin)
+
+	router := chi.NewRouter()
+	router.Use(auth.NewAuthenticator(keys, "", "").Require(auth.ScopeLinksAdmin))
+	router.Post("/admin/keys", HandlePostApiKey(keys, new(MockTenantRepository)))
+	router.Get("/admin/keys", HandleGetApiKeys(keys))
+	router.Delete("/admin/keys/{id}", HandleDeleteApiKey(keys))
+
+	send := func(method string, target string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+acmeKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/admin/keys", `{"name":"ci","scopes":["links:read"],"tenant":"globex"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = send("POST", "/admin/keys", `{"name":"ci","scopes":["links:read"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"tenant":"acme"`)
+
+	w = send("GET", "/admin/keys", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []repositories.ApiKey `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+	for _, apiKey := range resp.Data {
+		assert.Equal(t, "acme", apiKey.Tenant)
+	}
+
+	all, err := keys.ListApiKeys(context.Background())
+	require.NoError(t, err)
+	for _, apiKey := range all {
+		if apiKey.Tenant == "globex" {
+			w = send("DELETE", "/admin/keys/"+apiKey.ID, "")
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		}
+	}
+}
+
+func TestPostApiKey_UnknownTenant(t *testing.T) {
+	mockKeys := new(MockApiKeyRepository)
+	mockTenants := new(MockTenantRepository)
+	mockTenants.On("GetTenant", mock.Anything, "acme").Return(repositories.Tenant{}, repositories.ErrTenantNotFound)
+	handler := HandlePostApiKey(mockKeys, mockTenants)
+
+	req := httptest.NewRequest("POST", "/admin/keys", bytes.NewBufferString(`{"name":"ci","scopes":["links:read"],"tenant":"acme"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"tenant not found"}`, w.Body.String())
+	mockKeys.AssertNotCalled(t, "SaveApiKey")
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"url-shortener/internal/analytics"
+	"url-shortener/internal/auth"
+	"url-shortener/internal/ratelimit"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/validation"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxUnlockBodySize is plenty for a form holding a bcrypt sized password.
+const maxUnlockBodySize = 4 << 10
+
+type unlockPage struct {
+	Code  string
+	Error string
+}
+
+func sendUnlockForm(w http.ResponseWriter, code string, message string, status int) {
+	w.Header().Set("Cache-Control", noCacheControl)
+	sendHTML(w, "unlock.html", unlockPage{Code: code, Error: message}, status)
+}
+
+// allowUnlock counts an unlock attempt against the limit of the key, and
+// shows the form again when it ran out.
+func allowUnlock(w http.ResponseWriter, r *http.Request, limiter ratelimit.Limiter, key string, limit ratelimit.Limit, code string) bool {
+	if limit.Disabled() {
+		return true
+	}
+
+	result, err := limiter.Allow(r.Context(), key, limit)
+	if err != nil {
+		slog.Error("error checking unlock attempts", "error", err)
+		return true
+	}
+	if !result.Allowed {
+		minutes := int(math.Ceil(result.RetryAfter.Minutes()))
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		sendUnlockForm(w, code, fmt.Sprintf("Too many attempts, try again in %d minute(s).", minutes), http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// HandleUnlockShortenedURL godoc
+// @Summary Unlock a password protected link
+// @Description Check the password sent by the unlock form of a protected link and redirect to its URL when it matches.
+// @Description The attempts are limited per link and client IP, and per link whatever the IP.
+// @Tags API
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param code path string true "Shortened URL code"
+// @Param password formData string true "Password of the link"
+// @Success 303 "Redirect to the URL"
+// @Failure 401 "Wrong password, the form is shown again"
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 410 {object} utils.ApiResponse{error=string} "Expired or disabled"
+// @Failure 429 "Too many attempts, the form is shown again"
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Router /{code} [post]
+func HandleUnlockShortenedURL(db repositories.UrlContract, tracker analytics.Tracker, blocklist validation.Blocklist, limiter ratelimit.Limiter, limit ratelimit.Limit, linkLimit ratelimit.Limit, countryHeader string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := strings.TrimSuffix(chi.URLParam(r, "code"), previewSuffix)
+
+		link, ok := getActiveLink(w, r, db, code)
+		if !ok {
+			return
+		}
+
+		if !link.Protected() {
+			http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+			return
+		}
+
+		// every attempt counts, so guessing is slow even for a right guess;
+		// the per link limit holds against guesses spread over many IPs
+		if !allowUnlock(w, r, limiter, "unlock:"+link.Key()+":"+ratelimit.ClientIP(r), limit, code) ||
+			!allowUnlock(w, r, limiter, "unlock:"+link.Key(), linkLimit, code) {
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxUnlockBodySize)
+		if err := r.ParseForm(); err != nil {
+			sendUnlockForm(w, code, "Invalid form.", http.StatusBadRequest)
+			return
+		}
+
+		matches, err := auth.CheckPassword(link.PasswordHash, r.PostForm.Get("password"))
+		if err != nil {
+			slog.Error("error checking link password", "error", err)
+			sendUnlockForm(w, code, "Something went wrong, try again.", http.StatusInternalServerError)
+			return
+		}
+		if !matches {
+			sendUnlockForm(w, code, "Wrong password.", http.StatusUnauthorized)
+			return
+		}
+
+		r = resolveTarget(w, r, &link, countryHeader)
+
+		if isBlocked(blocklist, link) {
+			sendBlockedPage(w, link)
+			return
+		}
+
+		// the unlocked redirect must never be cached, or the next visitor
+		// would skip the password
+		w.Header().Set("Cache-Control", noCacheControl)
+		if !consumeClick(w, r, db, link) {
+			return
+		}
+		tracker.Track(r, link.Key())
+		http.Redirect(w, r, link.URL, http.StatusSeeOther)
+	}
+}
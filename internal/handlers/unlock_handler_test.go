@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/auth"
+	"url-shortener/internal/ratelimit"
+	"url-shortener/internal/repositories"
+	"url-shortener/internal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func protectedLink(t *testing.T) repositories.Link {
+	hash, err := auth.HashPassword("open sesame")
+	require.NoError(t, err)
+	return repositories.Link{Code: "123", URL: "https://example.com/secret", PasswordHash: hash}
+}
+
+func TestGetShortenedURL_Protected(t *testing.T) {
+	link := protectedLink(t)
+
+	tests := []struct {
+		name         string
+		target       string
+		accept       string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "unlock form",
+			target:       "/api/123",
+			expectedCode: http.StatusOK,
+			expectedBody: `<input type="password" name="password"`,
+		},
+		{
+			name:         "json",
+			target:       "/api/123?json=true",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"url is password protected, open it in a browser to enter the password"}`,
+		},
+		{
+			name:         "json preview",
+			target:       "/api/123+",
+			accept:       "application/json",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"url is password protected, open it in a browser to enter the password"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(link, nil)
+			mockTracker := new(MockTracker)
+			handler := HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{})
+
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/api/{code}", handler.ServeHTTP)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			assert.NotContains(t, w.Body.String(), link.URL)
+			assert.Empty(t, w.Header().Get("Location"))
+
+			mockStore.AssertExpectations(t)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
+
+func postUnlock(handler http.HandlerFunc, password string) *httptest.ResponseRecorder {
+	return postUnlockFrom(handler, password, "192.0.2.1:1234")
+}
+
+func postUnlockFrom(handler http.HandlerFunc, password string, remoteAddr string) *httptest.ResponseRecorder {
+	form := url.Values{"password": {password}}
+	req := httptest.NewRequest("POST", "/api/123", strings.NewReader(form.Encode()))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	router.Post("/api/{code}", handler.ServeHTTP)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUnlockShortenedURL(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "123").Return(protectedLink(t), nil)
+	mockTracker := new(MockTracker)
+	mockTracker.On("Track", mock.Anything, "123").Return().Once()
+	handler := HandleUnlockShortenedURL(mockStore, mockTracker, nil, ratelimit.NewMemoryLimiter(), ratelimit.Limit{Requests: 5, Window: time.Minute}, ratelimit.Limit{}, "")
+
+	w := postUnlock(handler, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Wrong password.")
+	assert.Empty(t, w.Header().Get("Location"))
+
+	w = postUnlock(handler, "open sesame")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "https://example.com/secret", w.Header().Get("Location"))
+	assert.Equal(t, "no-store, max-age=0", w.Header().Get("Cache-Control"))
+
+	mockStore.AssertExpectations(t)
+	mockTracker.AssertExpectations(t)
+}
+
+func TestUnlockShortenedURL_TooManyAttempts(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "123").Return(protectedLink(t), nil)
+	mockTracker := new(MockTracker)
+	handler := HandleUnlockShortenedURL(mockStore, mockTracker, nil, ratelimit.NewMemoryLimiter(), ratelimit.Limit{Requests: 2, Window: time.Hour}, ratelimit.Limit{}, "")
+
+	for i := 0; i < 2; i++ {
+		w := postUnlock(handler, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	// even the right password waits once the attempts ran out
+	w := postUnlock(handler, "open sesame")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "Too many attempts, try again in 30 minute(s).")
+	assert.Equal(t, "1800", w.Header().Get("Retry-After"))
+
+	mockStore.AssertExpectations(t)
+	mockTracker.AssertExpectations(t)
+}
+
+func TestUnlockShortenedURL_TooManyAttemptsPerLink(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "123").Return(protectedLink(t), nil)
+	mockTracker := new(MockTracker)
+	handler := HandleUnlockShortenedURL(mockStore, mockTracker, nil, ratelimit.NewMemoryLimiter(), ratelimit.Limit{Requests: 2, Window: time.Hour}, ratelimit.Limit{Requests: 3, Window: time.Hour}, "")
+
+	for i := 0; i < 3; i++ {
+		w := postUnlockFrom(handler, "wrong", fmt.Sprintf("192.0.2.%d:1234", i))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	// a fresh IP doesn't get more guesses once the link ran out
+	w := postUnlockFrom(handler, "open sesame", "192.0.2.100:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "Too many attempts")
+
+	mockStore.AssertExpectations(t)
+	mockTracker.AssertExpectations(t)
+}
+
+func TestUnlockShortenedURL_NotProtected(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "123").Return(repositories.Link{Code: "123", URL: "https://example.com"}, nil)
+	handler := HandleUnlockShortenedURL(mockStore, new(MockTracker), nil, ratelimit.NewMemoryLimiter(), ratelimit.Limit{}, ratelimit.Limit{}, "")
+
+	w := postUnlock(handler, "anything")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/api/123", w.Header().Get("Location"))
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestPostShortenedURL_Password(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveShortenedURL", mock.Anything, mock.MatchedBy(func(link repositories.Link) bool {
+		ok, err := auth.CheckPassword(link.PasswordHash, "open sesame")
+		return err == nil && ok && link.URL == "https://example.com"
+	})).Return("abc12345", nil)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://example.com","password":"open sesame"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"data":"abc12345"}`, w.Body.String())
+
+	req = httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://example.com","password":"abc"}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"password must be 4 to 72 bytes long"}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
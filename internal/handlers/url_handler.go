@@ -1,80 +1,531 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/analytics"
+	"url-shortener/internal/auth"
 	"url-shortener/internal/repositories"
+	"url-shortener/internal/split"
+	"url-shortener/internal/targeting"
 	"url-shortener/internal/utils"
+	"url-shortener/internal/validation"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/redis/go-redis/v9"
 )
 
+// getShortenedURLResponse is the preview of a link, rendered as JSON, HTML
+// or plain text.
 type getShortenedURLResponse struct {
-	URL string `json:"url"`
+	Code      string     `json:"code"`
+	URL       string     `json:"url"`
+	Domain    string     `json:"domain"`
+	Title     string     `json:"title,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	Clicks    int64      `json:"clicks"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Blocked is set when the URL became blocked after the link was created.
+	Blocked bool `json:"blocked,omitempty"`
+}
+
+func newPreview(link repositories.Link, blocked bool) getShortenedURLResponse {
+	var domain string
+	if parsed, err := url.Parse(link.URL); err == nil {
+		domain = parsed.Hostname()
+	}
+
+	return getShortenedURLResponse{
+		Code:      link.Code,
+		URL:       link.URL,
+		Domain:    domain,
+		Title:     link.Title,
+		CreatedAt: link.CreatedAt,
+		Clicks:    link.Clicks,
+		ExpiresAt: link.ExpiresAt,
+		Blocked:   blocked,
+	}
+}
+
+// text renders the preview as plain text, one "Field: value" per line.
+func (p getShortenedURLResponse) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Code: %s\n", p.Code)
+	if p.Title != "" {
+		fmt.Fprintf(&b, "Title: %s\n", p.Title)
+	}
+	fmt.Fprintf(&b, "URL: %s\n", p.URL)
+	fmt.Fprintf(&b, "Domain: %s\n", p.Domain)
+	fmt.Fprintf(&b, "Created: %s\n", p.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Clicks: %d\n", p.Clicks)
+	if p.ExpiresAt != nil {
+		fmt.Fprintf(&b, "Expires: %s\n", p.ExpiresAt.Format(time.RFC3339))
+	}
+	if p.Blocked {
+		b.WriteString("Blocked: true\n")
+	}
+	return b.String()
+}
+
+// sendPreview writes the preview in the representation the Accept header
+// prefers, HTML by default.
+func sendPreview(w http.ResponseWriter, r *http.Request, preview getShortenedURLResponse) {
+	w.Header().Add("Vary", "Accept")
+
+	switch negotiate(r.Header.Get("Accept"), "text/html", "application/json", "text/plain") {
+	case "application/json":
+		utils.SendJSON(w, utils.ApiResponse{Data: preview}, http.StatusOK)
+	case "text/plain":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, preview.text()); err != nil {
+			slog.Error("error writing preview", "error", err)
+		}
+	default:
+		sendHTML(w, "preview.html", preview, http.StatusOK)
+	}
+}
+
+// previewSuffix appended to a code asks for the preview of the link instead
+// of the redirect.
+const previewSuffix = "+"
+
+// RedirectOptions configure the redirects, Status and NoCache applying to
+// the links that don't set their own.
+type RedirectOptions struct {
+	// Status of the redirect, 301 when zero.
+	Status int
+	// NoCache sends no-store Cache-Control headers on every redirect.
+	NoCache bool
+	// CountryHeader is the header the reverse proxy sets with the visitor
+	// country, matched by the targeting rules.
+	CountryHeader string
+}
+
+// noCacheControl keeps browsers and proxies from caching the redirect.
+const noCacheControl = "no-store, max-age=0"
+
+type blockedPage struct {
+	Code string
+	URL  string
 }
 
 // HandleGetShortenedURL godoc
 // @Summary Get shortened URL
-// @Description Get the original URL from the shortened code
+// @Description Get the original URL from the shortened code, every redirect is recorded for the stats.
+// @Description A URL blocked after the link was created gets a warning page instead of the redirect.
+// @Description Appending + to the code, or passing preview=1, shows a preview of the link instead of redirecting,
+// @Description as HTML, JSON or plain text according to the Accept header. json=true always returns the JSON preview.
+// @Description Password protected links serve a form asking for the password instead, and respond 401 in JSON.
+// @Description Links with targeting rules redirect to the URL of the first rule matching the visitor platform, language, country and time.
+// @Description Split links send each visitor to one of their variants by weight, kept in a cookie for the next visits.
+// @Description The query params stored on the link are added to the target, and so is the query of the request for links forwarding it.
 // @Tags API
-// @Param code path string true "Shortened URL code"
-// @Param json query string false "Return JSON response"
+// @Param code path string true "Shortened URL code, with a + suffix for the preview"
+// @Param preview query bool false "Show the preview of the link"
+// @Param json query string false "Return the preview as JSON"
+// @Produce json,html,plain
 // @Success 200 {object} utils.ApiResponse{data=getShortenedURLResponse}
+// @Success 301 "Redirect to the URL, with the status set on the link (301, 302, 307 or 308) or the configured default"
+// @Failure 401 {object} utils.ApiResponse{error=string} "Password protected"
 // @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 410 {object} utils.ApiResponse{error=string} "Expired, disabled or out of clicks"
 // @Failure 500 {object} utils.ApiResponse{error=string}
-// @Router /api/{code} [get]
-func HandleGetShortenedURL(db repositories.UrlContract) http.HandlerFunc {
+// @Failure 429 {object} utils.ApiResponse{error=string}
+// @Router /{code} [get]
+func HandleGetShortenedURL(db repositories.UrlContract, tracker analytics.Tracker, blocklist validation.Blocklist, opts RedirectOptions) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		code := chi.URLParam(r, "code")
+		code, preview := strings.CutSuffix(chi.URLParam(r, "code"), previewSuffix)
+		if value := r.URL.Query().Get("preview"); value != "" {
+			preview, _ = strconv.ParseBool(value)
+		}
 		json := r.URL.Query().Get("json")
 
-		data, err := db.GetURL(r.Context(), code)
+		link, ok := getActiveLink(w, r, db, code)
+		if !ok {
+			return
+		}
 
-		if err != nil {
-			if errors.Is(err, redis.Nil) {
+		if link.Protected() {
+			// the password is only checked by the unlock form, see HandleUnlockShortenedURL
+			if json == "true" || (preview && negotiate(r.Header.Get("Accept"), "text/html", "application/json", "text/plain") != "text/html") {
 				utils.SendJSON(w, utils.ApiResponse{
-					Error: "url not found",
-				}, http.StatusNotFound)
+					Error: "url is password protected, open it in a browser to enter the password",
+				}, http.StatusUnauthorized)
 				return
 			}
 
-			slog.Error("error get url", "error", err)
-			utils.SendJSON(w, utils.ApiResponse{
-				Error: "something went wrong",
-			}, http.StatusInternalServerError)
+			sendUnlockForm(w, code, "", http.StatusOK)
 			return
 		}
 
 		if json == "true" {
 			utils.SendJSON(w, utils.ApiResponse{
-				Data: getShortenedURLResponse{URL: data},
+				Data: newPreview(link, isBlocked(blocklist, link)),
 			}, http.StatusOK)
 			return
 		}
-		http.Redirect(w, r, data, http.StatusMovedPermanently)
 
+		if preview {
+			sendPreview(w, r, newPreview(link, isBlocked(blocklist, link)))
+			return
+		}
+
+		// the preview shows the link URL, only the redirect is targeted
+		r = resolveTarget(w, r, &link, opts.CountryHeader)
+
+		if isBlocked(blocklist, link) {
+			sendBlockedPage(w, link)
+			return
+		}
+
+		status := link.RedirectStatus
+		if status == 0 {
+			status = opts.Status
+		}
+		if status == 0 {
+			status = http.StatusMovedPermanently
+		}
+		// a cached redirect of a click limited link would skip the counter,
+		// and one of a targeted or split link would skip picking the URL
+		if link.NoCache || opts.NoCache || link.MaxClicks > 0 || len(link.Rules) > 0 || len(link.Variants) > 0 {
+			w.Header().Set("Cache-Control", noCacheControl)
+		}
+
+		if !consumeClick(w, r, db, link) {
+			return
+		}
+
+		tracker.Track(r, link.Key())
+		http.Redirect(w, r, link.URL, status)
+
+	}
+}
+
+// consumeClick takes a click from a click limited link, replying 410 once
+// it is exhausted. Links without a limit always pass.
+func consumeClick(w http.ResponseWriter, r *http.Request, db repositories.UrlContract, link repositories.Link) bool {
+	if link.MaxClicks <= 0 {
+		return true
+	}
+
+	err := db.ConsumeClick(r.Context(), link.Key(), link.MaxClicks)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, repositories.ErrExhausted) {
+		utils.SendJSON(w, utils.ApiResponse{
+			Error: "url exhausted",
+		}, http.StatusGone)
+		return false
+	}
+
+	if errors.Is(err, repositories.ErrNotFound) {
+		utils.SendJSON(w, utils.ApiResponse{
+			Error: "url not found",
+		}, http.StatusNotFound)
+		return false
+	}
+
+	slog.Error("error consuming click", "error", err)
+	utils.SendJSON(w, utils.ApiResponse{
+		Error: "something went wrong",
+	}, http.StatusInternalServerError)
+	return false
+}
+
+// getActiveLink gets the link to redirect to on the domain of the request,
+// replying with the error when it doesn't exist, expired or is disabled.
+func getActiveLink(w http.ResponseWriter, r *http.Request, db repositories.UrlContract, code string) (repositories.Link, bool) {
+	link, err := lookupLink(r, db, code)
+
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "url not found",
+			}, http.StatusNotFound)
+			return repositories.Link{}, false
+		}
+
+		if errors.Is(err, repositories.ErrExpired) {
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "url expired",
+			}, http.StatusGone)
+			return repositories.Link{}, false
+		}
+
+		slog.Error("error get url", "error", err)
+		utils.SendJSON(w, utils.ApiResponse{
+			Error: "something went wrong",
+		}, http.StatusInternalServerError)
+		return repositories.Link{}, false
 	}
+
+	if link.Disabled {
+		utils.SendJSON(w, utils.ApiResponse{
+			Error: "url disabled",
+		}, http.StatusGone)
+		return repositories.Link{}, false
+	}
+
+	return link, true
+}
+
+// isBlocked reports whether the link URL became blocked after the link was
+// created, the blocklist being nil when none is set.
+func isBlocked(blocklist validation.Blocklist, link repositories.Link) bool {
+	if blocklist == nil {
+		return false
+	}
+
+	rule, blocked := blocklist.Blocked(link.URL)
+	if blocked {
+		slog.Warn("blocked url requested", "code", link.Code, "rule", rule)
+	}
+	return blocked
+}
+
+func sendBlockedPage(w http.ResponseWriter, link repositories.Link) {
+	// so the page goes away as soon as the URL is unblocked
+	w.Header().Set("Cache-Control", noCacheControl)
+	sendHTML(w, "blocked.html", blockedPage{Code: link.Code, URL: link.URL}, http.StatusOK)
 }
 
 type postBody struct {
-	URL string `json:"url"`
+	URL   string   `json:"url"`
+	Alias string   `json:"alias,omitempty"`
+	Title string   `json:"title,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	// ExpiresIn is the lifetime of the link in seconds.
+	ExpiresIn int64      `json:"expires_in,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// RedirectStatus is 301, 302, 307 or 308, the configured default when
+	// omitted.
+	RedirectStatus int `json:"redirect_status,omitempty"`
+	// NoCache asks browsers not to cache the redirect.
+	NoCache bool `json:"no_cache,omitempty"`
+	// Password is asked before redirecting, only its hash is stored.
+	Password string `json:"password,omitempty"`
+	// MaxClicks is how many redirects the link allows, 1 for a single use
+	// link, unlimited when omitted.
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	// Rules send the visitors they match to other URLs, see targeting.Rule.
+	Rules []targeting.Rule `json:"rules,omitempty"`
+	// Variants split the visitors across several URLs by weight, see
+	// split.Variant.
+	Variants []split.Variant `json:"variants,omitempty"`
+	// Query holds params added to the target on redirect, along with the
+	// utm_* ones built from UTM.
+	Query map[string]string `json:"query,omitempty"`
+	UTM   *utmParams        `json:"utm,omitempty"`
+	// ForwardQuery adds the query of the short URL request to the target.
+	ForwardQuery bool `json:"forward_query,omitempty"`
+	// Host binds the link to a registered domain, the default one when
+	// omitted.
+	Host string `json:"host,omitempty" example:"go.acme.io"`
+	// Reuse returns the code of an active link to the same URL, if there is
+	// one, instead of creating a new link. It is ignored along with an alias,
+	// an expiration, redirect options, a password, a click limit, rules,
+	// variants or query params, as the existing link wouldn't honor them.
+	Reuse bool `json:"reuse,omitempty"`
+}
+
+// reusableCode returns the code of the link the body can reuse, of the same
+// tenant and on the same host as the new link, empty when there is none.
+func reusableCode(ctx context.Context, db repositories.UrlContract, body postBody, link repositories.Link) (string, error) {
+	if !body.Reuse || body.Alias != "" || body.ExpiresIn != 0 || body.ExpiresAt != nil ||
+		body.RedirectStatus != 0 || body.NoCache || body.Password != "" || body.MaxClicks != 0 || len(body.Rules) > 0 || len(body.Variants) > 0 ||
+		len(body.Query) > 0 || body.UTM != nil || body.ForwardQuery {
+		return "", nil
+	}
+
+	existing, err := db.FindURL(ctx, link.Tenant, link.Host, body.URL)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) || errors.Is(err, repositories.ErrExpired) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return existing.Code, nil
+}
+
+// expiration resolves the expires_in/expires_at pair into an absolute time,
+// nil meaning the link never expires.
+func (b postBody) expiration(now time.Time) (*time.Time, error) {
+	switch {
+	case b.ExpiresIn != 0 && b.ExpiresAt != nil:
+		return nil, errors.New("only one of expires_in or expires_at can be set")
+	case b.ExpiresIn < 0:
+		return nil, errors.New("expires_in must be positive")
+	case b.ExpiresIn > 0:
+		expiresAt := now.Add(time.Duration(b.ExpiresIn) * time.Second)
+		return &expiresAt, nil
+	case b.ExpiresAt != nil:
+		if !b.ExpiresAt.After(now) {
+			return nil, errors.New("expires_at must be in the future")
+		}
+		return b.ExpiresAt, nil
+	}
+	return nil, nil
+}
+
+// link validates the body and turns it into the link to save, the alias
+// being left to the caller.
+func (b postBody) link(now time.Time, policy validation.Policy) (repositories.Link, error) {
+	if err := policy.Validate(b.URL); err != nil {
+		return repositories.Link{}, err
+	}
+
+	expiresAt, err := b.expiration(now)
+	if err != nil {
+		return repositories.Link{}, err
+	}
+
+	if len(b.Title) > maxTitleLength {
+		return repositories.Link{}, fmt.Errorf("title must be at most %d characters long", maxTitleLength)
+	}
+
+	tags, err := normalizeTags(b.Tags)
+	if err != nil {
+		return repositories.Link{}, err
+	}
+
+	if b.Alias != "" {
+		if err := utils.ValidateAlias(b.Alias); err != nil {
+			return repositories.Link{}, err
+		}
+	}
+
+	if b.RedirectStatus != 0 && !repositories.ValidRedirectStatus(b.RedirectStatus) {
+		return repositories.Link{}, errInvalidRedirectStatus
+	}
+
+	if b.MaxClicks < 0 {
+		return repositories.Link{}, errInvalidMaxClicks
+	}
+
+	var rules []targeting.Rule
+	if len(b.Rules) > 0 {
+		if rules, err = validateRules(b.Rules, policy); err != nil {
+			return repositories.Link{}, err
+		}
+	}
+
+	if len(b.Variants) > 0 {
+		if err := validateVariants(b.Variants, policy); err != nil {
+			return repositories.Link{}, err
+		}
+	}
+
+	query, err := buildQuery(b.Query, b.UTM)
+	if err != nil {
+		return repositories.Link{}, err
+	}
+
+	var passwordHash string
+	if b.Password != "" {
+		if passwordHash, err = auth.HashPassword(b.Password); err != nil {
+			return repositories.Link{}, err
+		}
+	}
+
+	return repositories.Link{
+		URL:            b.URL,
+		Title:          b.Title,
+		Tags:           tags,
+		ExpiresAt:      expiresAt,
+		RedirectStatus: b.RedirectStatus,
+		NoCache:        b.NoCache,
+		PasswordHash:   passwordHash,
+		MaxClicks:      b.MaxClicks,
+		Rules:          rules,
+		Variants:       b.Variants,
+		Query:          query,
+		ForwardQuery:   b.ForwardQuery,
+	}, nil
+}
+
+var (
+	errInvalidRedirectStatus = errors.New("redirect_status must be 301, 302, 307 or 308")
+	errInvalidMaxClicks      = errors.New("max_clicks must be positive")
+)
+
+const (
+	maxTitleLength = 200
+	maxTags        = 20
+	maxTagLength   = 50
+)
+
+// errorResponse builds the response of a rejected request, with the reason
+// of a rejected URL.
+func errorResponse(err error) utils.ApiResponse {
+	resp := utils.ApiResponse{Error: err.Error()}
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		resp.Reason = string(validationErr.Reason)
+	}
+	return resp
+}
+
+// normalizeTags trims, lowercases and dedupes the tags, keeping their order.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxTags)
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tags must be at most %d characters long", maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
 }
 
 // HandlePostShortenedURL godoc
 // @Summary Post shortened URL
-// @Description Get the original URL from the shortened code
+// @Description Shorten a URL, optionally under a custom alias, with an expiration, a title, tags and a password asked before redirecting.
+// @Description With reuse set, the code of an active link to the same URL is returned with a 200 when there is one.
+// @Description The URL must be http or https, point to a public host other than this shortener and pass the domain allow/deny lists, a rejected URL responds with a reason.
+// @Description Requires the links:create scope, the API key name is recorded as the link creator.
+// @Description Links created with a tenant API key belong to its tenant, a 403 responding once the tenant link quota is reached.
+// @Description A host binds the link to a registered domain, the same code being free on every domain.
+// @Security ApiKeyAuth
 // @Tags API
+// @Param Authorization header string true "Bearer API key"
 // @Param data body postBody true "Shortened URL Post Body"
 // @Success 201 {object} utils.ApiResponse{data=string}
+// @Success 200 {object} utils.ApiResponse{data=string} "Existing link reused"
 // @Failure 400 {object} utils.ApiResponse{error=string}
+// @Failure 409 {object} utils.ApiResponse{error=string}
 // @Failure 500 {object} utils.ApiResponse{error=string}
 // @Failure 422 {object} utils.ApiResponse{error=string}
-// @Router /api/shorten [post]
-func HandlePostShortenedURL(db repositories.UrlContract) http.HandlerFunc {
+// @Failure 401 {object} utils.ApiResponse{error=string}
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Failure 429 {object} utils.ApiResponse{error=string}
+// @Router /api/v1/shorten [post]
+func HandlePostShortenedURL(db repositories.UrlContract, domains repositories.DomainContract, policy validation.Policy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var body postBody
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -82,18 +533,81 @@ func HandlePostShortenedURL(db repositories.UrlContract) http.HandlerFunc {
 			return
 		}
 
-		if body.URL == "" {
-			utils.SendJSON(w, utils.ApiResponse{Error: "URL is required"}, http.StatusBadRequest)
+		registered, err := domains.ListDomains(r.Context())
+		if err != nil {
+			slog.Error("error get domains", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		link, err := body.link(time.Now(), domainPolicy(policy, registered))
+		if err != nil {
+			utils.SendJSON(w, errorResponse(err), http.StatusBadRequest)
 			return
 		}
+		if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+			link.Creator = principal.Name
+			link.Tenant = principal.Tenant
+		}
+
+		if link.Host, err = linkHost(registered, body.Host); err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		reused, err := reusableCode(r.Context(), db, body, link)
+		if err != nil {
+			slog.Error("error finding url", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+		if reused != "" {
+			utils.SendJSON(w, utils.ApiResponse{Data: reused}, http.StatusOK)
+			return
+		}
+
+		if body.Alias != "" {
+			err := checkLegacyCode(r.Context(), db, registered, link.Host, body.Alias)
+			if err == nil {
+				err = db.SaveURLWithCode(r.Context(), body.Alias, link)
+			}
+			if err != nil {
+				if errors.Is(err, repositories.ErrCodeTaken) {
+					utils.SendJSON(w, utils.ApiResponse{
+						Error: "alias already in use",
+					}, http.StatusConflict)
+					return
+				}
+				if errors.Is(err, repositories.ErrQuotaExceeded) {
+					utils.SendJSON(w, utils.ApiResponse{
+						Error: "link quota exceeded",
+					}, http.StatusForbidden)
+					return
+				}
+
+				slog.Error("error saving url", "error", err)
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "something went wrong",
+				}, http.StatusInternalServerError)
+				return
+			}
 
-		if _, err := url.Parse(body.URL); err != nil {
-			utils.SendJSON(w, utils.ApiResponse{Error: "invalid URL"}, http.StatusBadRequest)
+			utils.SendJSON(w, utils.ApiResponse{Data: body.Alias}, http.StatusCreated)
 			return
 		}
 
-		code, err := db.SaveShortenedURL(r.Context(), body.URL)
+		code, err := db.SaveShortenedURL(r.Context(), link)
 		if err != nil {
+			if errors.Is(err, repositories.ErrQuotaExceeded) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "link quota exceeded",
+				}, http.StatusForbidden)
+				return
+			}
 			slog.Error("error saving url", "error", err)
 			utils.SendJSON(w, utils.ApiResponse{
 				Error: "something went wrong",
@@ -107,23 +621,104 @@ func HandlePostShortenedURL(db repositories.UrlContract) http.HandlerFunc {
 }
 
 type getAllUrlsResponse struct {
-	URLs map[string]string `json:"urls"`
+	Links      []repositories.Link `json:"links"`
+	NextCursor string              `json:"next_cursor"`
+}
+
+// parseListOptions reads the listing filters from the query string.
+func parseListOptions(query url.Values) (repositories.ListOptions, error) {
+	opts := repositories.ListOptions{
+		Cursor:   query.Get("cursor"),
+		Sort:     query.Get("sort"),
+		Contains: query.Get("q"),
+		Domain:   query.Get("domain"),
+		Tag:      query.Get("tag"),
+		Tenant:   query.Get("tenant"),
+		Host:     normalizeDomain(query.Get("host")),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > repositories.MaxListLimit {
+			return opts, fmt.Errorf("limit must be between 1 and %d", repositories.MaxListLimit)
+		}
+		opts.Limit = n
+	}
+
+	switch opts.Sort {
+	case "", repositories.SortCreatedAsc, repositories.SortCreatedDesc:
+	default:
+		return opts, fmt.Errorf("sort must be %s or %s", repositories.SortCreatedAsc, repositories.SortCreatedDesc)
+	}
+
+	var err error
+	if opts.CreatedFrom, err = parseDateParam(query, "created_from"); err != nil {
+		return opts, err
+	}
+	if opts.CreatedTo, err = parseDateParam(query, "created_to"); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+func parseDateParam(query url.Values, param string) (time.Time, error) {
+	value := query.Get(param)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 date", param)
+	}
+	return t, nil
 }
 
 // HandleGetAllUrls godoc
 // @Summary Get all shortened URL
-// @Description Get all shortened URLs and respective codes
+// @Description List shortened URLs along with their metadata, one page at a time.
+// @Description Pass the next_cursor of a page as cursor to get the next one, it is empty on the last page.
+// @Description A page may hold fewer links than the limit and still have a next_cursor.
+// @Description Tenant API keys only list the links of their tenant.
 // @Security BasicAuth
 // @Tags ADMIN
-// @Param Authorization header string true "Basic Auth"
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param limit query int false "Maximum links per page" default(50) minimum(1) maximum(1000)
+// @Param q query string false "Substring of the target URL, case insensitive"
+// @Param domain query string false "Domain of the target URL, subdomains included"
+// @Param tag query string false "Tag of the link"
+// @Param created_from query string false "Created at or after, RFC 3339"
+// @Param created_to query string false "Created before, RFC 3339"
+// @Param sort query string false "Sort by creation date, storage order by default" Enums(created_at, -created_at)
+// @Param tenant query string false "Tenant of the links, ignored for tenant API keys which only list their tenant links"
+// @Param host query string false "Domain the links are bound to"
 // @Success 200 {object} utils.ApiResponse{data=getAllUrlsResponse}
+// @Failure 400 {object} utils.ApiResponse{error=string}
 // @Failure 500 {object} utils.ApiResponse{error=string}
 // @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
 // @Router /admin/all [get]
 func HandleGetAllUrls(db repositories.UrlContract) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		urls, err := db.GetAllURL(r.Context())
+		opts, err := parseListOptions(r.URL.Query())
+		if err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if tenant := principalTenant(r); tenant != "" {
+			opts.Tenant = tenant
+		}
+
+		page, err := db.ListURL(r.Context(), opts)
 		if err != nil {
+			if errors.Is(err, repositories.ErrInvalidCursor) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "invalid cursor",
+				}, http.StatusBadRequest)
+				return
+			}
 			slog.Error("error get urls", "error", err)
 			utils.SendJSON(w, utils.ApiResponse{
 				Error: "something went wrong",
@@ -132,7 +727,7 @@ func HandleGetAllUrls(db repositories.UrlContract) http.HandlerFunc {
 		}
 
 		utils.SendJSON(w, utils.ApiResponse{
-			Data: getAllUrlsResponse{URLs: urls},
+			Data: getAllUrlsResponse{Links: page.Links, NextCursor: page.NextCursor},
 		}, http.StatusOK)
 
 	}
@@ -143,19 +738,21 @@ func HandleGetAllUrls(db repositories.UrlContract) http.HandlerFunc {
 // @Description Delete shortened URL that match the code passed
 // @Security BasicAuth
 // @Tags ADMIN
-// @Param Authorization header string true "Basic Auth"
+// @Param Authorization header string true "Basic Auth or Bearer API key"
 // @Param code path string true "Shortened URL code"
+// @Param host query string false "Domain the link is bound to, for the links that have one"
 // @Success 204 {object} utils.ApiResponse{}
 // @Failure 500 {object} utils.ApiResponse{error=string}
 // @Failure 404 {object} utils.ApiResponse{error=string}
 // @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
 // @Router /admin/{code} [delete]
 func HandleDeleteShortenedURL(db repositories.UrlContract) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		code := chi.URLParam(r, "code")
+		code := adminLinkKey(r)
 
 		if err := db.DeleteURL(r.Context(), code); err != nil {
-			if errors.Is(err, redis.Nil) {
+			if errors.Is(err, repositories.ErrNotFound) {
 				utils.SendJSON(w, utils.ApiResponse{
 					Error: "url not found",
 				}, http.StatusNotFound)
@@ -175,15 +772,37 @@ func HandleDeleteShortenedURL(db repositories.UrlContract) http.HandlerFunc {
 
 type updateBody struct {
 	NewURL string `json:"new_url"`
+	// Title, Tags, Disabled and the redirect options are left untouched when
+	// omitted, a redirect_status of 0 going back to the configured default.
+	Title          *string  `json:"title,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Disabled       *bool    `json:"disabled,omitempty"`
+	RedirectStatus *int     `json:"redirect_status,omitempty"`
+	NoCache        *bool    `json:"no_cache,omitempty"`
+	// Password replaces the password of the link, an empty one making it
+	// public again.
+	Password *string `json:"password,omitempty"`
+	// MaxClicks replaces the click limit, 0 removing it. Clicks already
+	// taken still count against the new limit.
+	MaxClicks *int64 `json:"max_clicks,omitempty"`
+	// Variants replace the variants of the link, an empty list removing
+	// them.
+	Variants *[]split.Variant `json:"variants,omitempty"`
+	// Query and UTM replace the stored query params together, an empty
+	// query removing them.
+	Query        *map[string]string `json:"query,omitempty"`
+	UTM          *utmParams         `json:"utm,omitempty"`
+	ForwardQuery *bool              `json:"forward_query,omitempty"`
 }
 
 // HandleUpdateShortenedURL godoc
 // @Summary Update shortened URL
-// @Description Update shortened URL that match the code passed
+// @Description Update the URL and metadata of the shortened URL that match the code passed
 // @Security BasicAuth
 // @Tags ADMIN
-// @Param Authorization header string true "Basic Auth"
+// @Param Authorization header string true "Basic Auth or Bearer API key"
 // @Param code path string true "Shortened URL code"
+// @Param host query string false "Domain the link is bound to, for the links that have one"
 // @Param data body updateBody true "Shortened URL Update Body"
 // @Success 201 {object} utils.ApiResponse{data=string}
 // @Failure 500 {object} utils.ApiResponse{error=string}
@@ -191,10 +810,11 @@ type updateBody struct {
 // @Failure 422 {object} utils.ApiResponse{error=string}
 // @Failure 400 {object} utils.ApiResponse{error=string}
 // @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
 // @Router /admin/{code} [put]
-func HandleUpdateShortenedURL(db repositories.UrlContract) http.HandlerFunc {
+func HandleUpdateShortenedURL(db repositories.UrlContract, domains repositories.DomainContract, policy validation.Policy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		code := chi.URLParam(r, "code")
+		code := adminLinkKey(r)
 
 		var body updateBody
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -207,14 +827,107 @@ func HandleUpdateShortenedURL(db repositories.UrlContract) http.HandlerFunc {
 			return
 		}
 
-		if _, err := url.Parse(body.NewURL); err != nil {
-			utils.SendJSON(w, utils.ApiResponse{Error: "invalid URL"}, http.StatusBadRequest)
+		policy, err := loadDomainPolicy(r.Context(), domains, policy)
+		if err != nil {
+			slog.Error("error get domains", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		if err := policy.Validate(body.NewURL); err != nil {
+			utils.SendJSON(w, errorResponse(err), http.StatusBadRequest)
+			return
+		}
+
+		if body.Title != nil && len(*body.Title) > maxTitleLength {
+			utils.SendJSON(w, utils.ApiResponse{Error: fmt.Sprintf("title must be at most %d characters long", maxTitleLength)}, http.StatusBadRequest)
+			return
+		}
+
+		tags, err := normalizeTags(body.Tags)
+		if err != nil {
+			utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		if body.RedirectStatus != nil && *body.RedirectStatus != 0 && !repositories.ValidRedirectStatus(*body.RedirectStatus) {
+			utils.SendJSON(w, utils.ApiResponse{Error: errInvalidRedirectStatus.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		if body.MaxClicks != nil && *body.MaxClicks < 0 {
+			utils.SendJSON(w, utils.ApiResponse{Error: errInvalidMaxClicks.Error()}, http.StatusBadRequest)
 			return
 		}
 
-		code, err := db.UpdateURL(r.Context(), code, body.NewURL)
+		if body.Variants != nil && len(*body.Variants) > 0 {
+			if err := validateVariants(*body.Variants, policy); err != nil {
+				utils.SendJSON(w, errorResponse(err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		var query map[string]string
+		if body.Query != nil || body.UTM != nil {
+			var stored map[string]string
+			if body.Query != nil {
+				stored = *body.Query
+			}
+			if query, err = buildQuery(stored, body.UTM); err != nil {
+				utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+
+		var passwordHash string
+		if body.Password != nil && *body.Password != "" {
+			if passwordHash, err = auth.HashPassword(*body.Password); err != nil {
+				utils.SendJSON(w, utils.ApiResponse{Error: err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+
+		_, err = db.UpdateURL(r.Context(), code, func(link *repositories.Link) error {
+			link.URL = body.NewURL
+			if body.Title != nil {
+				link.Title = *body.Title
+			}
+			if body.Tags != nil {
+				link.Tags = tags
+			}
+			if body.Disabled != nil {
+				link.Disabled = *body.Disabled
+			}
+			if body.RedirectStatus != nil {
+				link.RedirectStatus = *body.RedirectStatus
+			}
+			if body.NoCache != nil {
+				link.NoCache = *body.NoCache
+			}
+			if body.Password != nil {
+				link.PasswordHash = passwordHash
+			}
+			if body.MaxClicks != nil {
+				link.MaxClicks = *body.MaxClicks
+			}
+			if body.Variants != nil {
+				link.Variants = nil
+				if len(*body.Variants) > 0 {
+					link.Variants = *body.Variants
+				}
+			}
+			if body.Query != nil || body.UTM != nil {
+				link.Query = query
+			}
+			if body.ForwardQuery != nil {
+				link.ForwardQuery = *body.ForwardQuery
+			}
+			return nil
+		})
 		if err != nil {
-			if errors.Is(err, redis.Nil) {
+			if errors.Is(err, repositories.ErrNotFound) {
 				utils.SendJSON(w, utils.ApiResponse{
 					Error: "url not found",
 				}, http.StatusNotFound)
@@ -230,3 +943,41 @@ func HandleUpdateShortenedURL(db repositories.UrlContract) http.HandlerFunc {
 		utils.SendJSON(w, utils.ApiResponse{Data: code}, http.StatusCreated)
 	}
 }
+
+// HandleGetLink godoc
+// @Summary Get shortened URL details
+// @Description Get the link and all its metadata, expired links included
+// @Security BasicAuth
+// @Tags ADMIN
+// @Param Authorization header string true "Basic Auth or Bearer API key"
+// @Param code path string true "Shortened URL code"
+// @Param host query string false "Domain the link is bound to, for the links that have one"
+// @Success 200 {object} utils.ApiResponse{data=repositories.Link}
+// @Failure 500 {object} utils.ApiResponse{error=string}
+// @Failure 404 {object} utils.ApiResponse{error=string}
+// @Failure 401
+// @Failure 403 {object} utils.ApiResponse{error=string}
+// @Router /admin/{code} [get]
+func HandleGetLink(db repositories.UrlContract) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := adminLinkKey(r)
+
+		link, err := db.GetURL(r.Context(), code)
+		if err != nil && !errors.Is(err, repositories.ErrExpired) {
+			if errors.Is(err, repositories.ErrNotFound) {
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "url not found",
+				}, http.StatusNotFound)
+				return
+			}
+
+			slog.Error("error get url", "error", err)
+			utils.SendJSON(w, utils.ApiResponse{
+				Error: "something went wrong",
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		utils.SendJSON(w, utils.ApiResponse{Data: link}, http.StatusOK)
+	}
+}
@@ -7,10 +7,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+	"url-shortener/internal/auth"
+	"url-shortener/internal/repositories"
 	"url-shortener/internal/utils"
+	"url-shortener/internal/validation"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -19,19 +22,37 @@ type MockUrlRepository struct {
 	mock.Mock
 }
 
-func (m *MockUrlRepository) SaveShortenedURL(ctx context.Context, url string) (string, error) {
-	args := m.Called(ctx, url)
+func (m *MockUrlRepository) SaveShortenedURL(ctx context.Context, link repositories.Link) (string, error) {
+	args := m.Called(ctx, link)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockUrlRepository) GetURL(ctx context.Context, code string) (string, error) {
+func (m *MockUrlRepository) SaveURLWithCode(ctx context.Context, code string, link repositories.Link) error {
+	args := m.Called(ctx, code, link)
+	return args.Error(0)
+}
+
+func (m *MockUrlRepository) SaveURLs(ctx context.Context, links []repositories.Link) ([]repositories.SaveResult, error) {
+	args := m.Called(ctx, links)
+	if results, ok := args.Get(0).([]repositories.SaveResult); ok {
+		return results, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUrlRepository) GetURL(ctx context.Context, code string) (repositories.Link, error) {
 	args := m.Called(ctx, code)
-	return args.String(0), args.Error(1)
+	return args.Get(0).(repositories.Link), args.Error(1)
+}
+
+func (m *MockUrlRepository) FindURL(ctx context.Context, tenant, host, target string) (repositories.Link, error) {
+	args := m.Called(ctx, tenant, host, target)
+	return args.Get(0).(repositories.Link), args.Error(1)
 }
 
-func (m *MockUrlRepository) GetAllURL(ctx context.Context) (map[string]string, error) {
-	args := m.Called(ctx)
-	return args.Get(0).(map[string]string), args.Error(1)
+func (m *MockUrlRepository) ListURL(ctx context.Context, opts repositories.ListOptions) (repositories.LinkPage, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(repositories.LinkPage), args.Error(1)
 }
 
 func (m *MockUrlRepository) DeleteURL(ctx context.Context, code string) error {
@@ -39,9 +60,52 @@ func (m *MockUrlRepository) DeleteURL(ctx context.Context, code string) error {
 	return args.Error(0)
 }
 
-func (m *MockUrlRepository) UpdateURL(ctx context.Context, code string, newURL string) (string, error) {
-	args := m.Called(ctx, code, newURL)
-	return args.String(0), args.Error(1)
+// UpdateURL applies the update to the link set as the first return value,
+// just like the real repositories do with the stored one.
+func (m *MockUrlRepository) UpdateURL(ctx context.Context, code string, update func(link *repositories.Link) error) (repositories.Link, error) {
+	args := m.Called(ctx, code)
+	if err := args.Error(1); err != nil {
+		return repositories.Link{}, err
+	}
+
+	link := args.Get(0).(repositories.Link)
+	if err := update(&link); err != nil {
+		return repositories.Link{}, err
+	}
+	return link, nil
+}
+
+func (m *MockUrlRepository) IncrementClicks(ctx context.Context, clicks map[string]int64) error {
+	args := m.Called(ctx, clicks)
+	return args.Error(0)
+}
+
+func (m *MockUrlRepository) ConsumeClick(ctx context.Context, code string, max int64) error {
+	args := m.Called(ctx, code, max)
+	return args.Error(0)
+}
+
+func (m *MockUrlRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockTracker struct {
+	mock.Mock
+}
+
+// stubBlocklist blocks the URLs it holds.
+type stubBlocklist map[string]bool
+
+func (b stubBlocklist) Blocked(raw string) (string, bool) {
+	if b[raw] {
+		return "url:" + raw, true
+	}
+	return "", false
+}
+
+func (m *MockTracker) Track(r *http.Request, code string) {
+	m.Called(r, code)
 }
 
 func TestPostShortenedURL_ValidRequest(t *testing.T) {
@@ -60,8 +124,8 @@ func TestPostShortenedURL_ValidRequest(t *testing.T) {
 		expectedBody:   utils.ApiResponse{Data: validUrl},
 	}
 	mockStore := new(MockUrlRepository)
-	mockStore.On("SaveShortenedURL", mock.Anything, tt.body.URL).Return(tt.mockSaveReturn, tt.mockSaveError)
-	handler := HandlePostShortenedURL(mockStore)
+	mockStore.On("SaveShortenedURL", mock.Anything, repositories.Link{URL: tt.body.URL}).Return(tt.mockSaveReturn, tt.mockSaveError)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
 
 	var requestBody bytes.Buffer
 	json.NewEncoder(&requestBody).Encode(tt.body)
@@ -89,11 +153,11 @@ func TestPostShortenedURL_MissingParams(t *testing.T) {
 	}{
 		body:         postBody{},
 		expectedCode: http.StatusBadRequest,
-		expectedBody: utils.ApiResponse{Error: "URL is required"},
+		expectedBody: utils.ApiResponse{Error: "URL is required", Reason: "url_required"},
 	}
 
 	mockStore := new(MockUrlRepository)
-	handler := HandlePostShortenedURL(mockStore)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
 
 	var requestBody bytes.Buffer
 	json.NewEncoder(&requestBody).Encode(tt.body)
@@ -110,6 +174,54 @@ func TestPostShortenedURL_MissingParams(t *testing.T) {
 	assert.Equal(t, tt.expectedBody, actualResponse)
 }
 
+func TestPostShortenedURL_RejectedURL(t *testing.T) {
+	policy := validation.Policy{OwnHosts: []string{"sho.rt"}, Deny: []string{"evil.com"}}
+	tests := []struct {
+		url          string
+		expectedBody utils.ApiResponse
+	}{
+		{
+			url:          "javascript:alert(1)",
+			expectedBody: utils.ApiResponse{Error: "URL scheme must be one of http, https", Reason: "scheme_not_allowed"},
+		},
+		{
+			url:          "http://127.0.0.1/admin",
+			expectedBody: utils.ApiResponse{Error: "URL must not point to a private or local address", Reason: "private_address"},
+		},
+		{
+			url:          "https://sho.rt/abc",
+			expectedBody: utils.ApiResponse{Error: "URL must not point to this shortener", Reason: "own_domain"},
+		},
+		{
+			url:          "https://www.evil.com",
+			expectedBody: utils.ApiResponse{Error: "URL domain www.evil.com is not allowed", Reason: "domain_denied"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			handler := HandlePostShortenedURL(mockStore, newTestDomains(t), policy)
+
+			var requestBody bytes.Buffer
+			json.NewEncoder(&requestBody).Encode(postBody{URL: tt.url})
+
+			req := httptest.NewRequest("POST", "/api/shorten", &requestBody)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var actualResponse utils.ApiResponse
+			json.Unmarshal(w.Body.Bytes(), &actualResponse)
+			assert.Equal(t, tt.expectedBody, actualResponse)
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
 func TestPostShortenedURL_InvalidRequest(t *testing.T) {
 	tt := struct {
 		body         string
@@ -122,7 +234,7 @@ func TestPostShortenedURL_InvalidRequest(t *testing.T) {
 	}
 
 	mockStore := new(MockUrlRepository)
-	handler := HandlePostShortenedURL(mockStore)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
 
 	var requestBody bytes.Buffer
 	json.NewEncoder(&requestBody).Encode(tt.body)
@@ -154,7 +266,7 @@ func TestPostShortenedURL_SomethindWentWrong(t *testing.T) {
 
 	mockStore := new(MockUrlRepository)
 	mockStore.On("SaveShortenedURL", mock.Anything, mock.Anything).Return("", assert.AnError)
-	handler := HandlePostShortenedURL(mockStore)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
 
 	var requestBody bytes.Buffer
 	json.NewEncoder(&requestBody).Encode(tt.body)
@@ -171,24 +283,164 @@ func TestPostShortenedURL_SomethindWentWrong(t *testing.T) {
 	assert.Equal(t, tt.expectedBody, actualResponse)
 }
 
+func TestPostShortenedURL_Alias(t *testing.T) {
+	validUrl := "https://example.com"
+	tests := []struct {
+		name          string
+		body          postBody
+		mockSaveError error
+		callsSave     bool
+		expectedCode  int
+		expectedBody  utils.ApiResponse
+	}{
+		{
+			name:         "valid alias",
+			body:         postBody{URL: validUrl, Alias: "launch-2026"},
+			callsSave:    true,
+			expectedCode: http.StatusCreated,
+			expectedBody: utils.ApiResponse{Data: "launch-2026"},
+		},
+		{
+			name:          "alias taken",
+			body:          postBody{URL: validUrl, Alias: "launch-2026"},
+			mockSaveError: repositories.ErrCodeTaken,
+			callsSave:     true,
+			expectedCode:  http.StatusConflict,
+			expectedBody:  utils.ApiResponse{Error: "alias already in use"},
+		},
+		{
+			name:         "invalid alias",
+			body:         postBody{URL: validUrl, Alias: "a/b"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: utils.ApiResponse{Error: utils.ErrAliasInvalid.Error()},
+		},
+		{
+			name:         "reserved alias",
+			body:         postBody{URL: validUrl, Alias: "Swagger"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: utils.ApiResponse{Error: utils.ErrAliasReserved.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			if tt.callsSave {
+				mockStore.On("SaveURLWithCode", mock.Anything, tt.body.Alias, repositories.Link{URL: tt.body.URL}).Return(tt.mockSaveError)
+			}
+			handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+			var requestBody bytes.Buffer
+			json.NewEncoder(&requestBody).Encode(tt.body)
+
+			req := httptest.NewRequest("POST", "/api/shorten", &requestBody)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			var actualResponse utils.ApiResponse
+			json.Unmarshal(w.Body.Bytes(), &actualResponse)
+			assert.Equal(t, tt.expectedBody, actualResponse)
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostShortenedURL_Expiration(t *testing.T) {
+	validUrl := "https://example.com"
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name         string
+		body         postBody
+		callsSave    bool
+		expectedCode int
+		expectedBody utils.ApiResponse
+	}{
+		{
+			name:         "expires in",
+			body:         postBody{URL: validUrl, ExpiresIn: 3600},
+			callsSave:    true,
+			expectedCode: http.StatusCreated,
+			expectedBody: utils.ApiResponse{Data: "abc12345"},
+		},
+		{
+			name:         "expires at",
+			body:         postBody{URL: validUrl, ExpiresAt: &future},
+			callsSave:    true,
+			expectedCode: http.StatusCreated,
+			expectedBody: utils.ApiResponse{Data: "abc12345"},
+		},
+		{
+			name:         "expires at in the past",
+			body:         postBody{URL: validUrl, ExpiresAt: &past},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: utils.ApiResponse{Error: "expires_at must be in the future"},
+		},
+		{
+			name:         "negative expires in",
+			body:         postBody{URL: validUrl, ExpiresIn: -1},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: utils.ApiResponse{Error: "expires_in must be positive"},
+		},
+		{
+			name:         "both expires in and expires at",
+			body:         postBody{URL: validUrl, ExpiresIn: 60, ExpiresAt: &future},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: utils.ApiResponse{Error: "only one of expires_in or expires_at can be set"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			if tt.callsSave {
+				mockStore.On("SaveShortenedURL", mock.Anything, mock.MatchedBy(func(link repositories.Link) bool {
+					return link.URL == tt.body.URL && link.ExpiresAt != nil && link.ExpiresAt.After(time.Now())
+				})).Return("abc12345", nil)
+			}
+			handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+			var requestBody bytes.Buffer
+			json.NewEncoder(&requestBody).Encode(tt.body)
+
+			req := httptest.NewRequest("POST", "/api/shorten", &requestBody)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			var actualResponse utils.ApiResponse
+			json.Unmarshal(w.Body.Bytes(), &actualResponse)
+			assert.Equal(t, tt.expectedBody, actualResponse)
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
 func TestGetShortenedURL_ValidRequest(t *testing.T) {
 	validUrl := "https://example.com"
 	tt := struct {
-		mockSaveReturn string
+		mockSaveReturn repositories.Link
 		mockSaveError  error
 		expectedCode   int
 		expectedBody   utils.ApiResponse
 	}{
-		mockSaveReturn: validUrl,
+		mockSaveReturn: repositories.Link{Code: "123", URL: validUrl},
 		mockSaveError:  nil,
 		expectedCode:   http.StatusOK,
 		expectedBody: utils.ApiResponse{
-			Data: getShortenedURLResponse{URL: validUrl},
+			Data: getShortenedURLResponse{Code: "123", URL: validUrl, Domain: "example.com"},
 		},
 	}
 	mockStore := new(MockUrlRepository)
 	mockStore.On("GetURL", context.Background(), "").Return(tt.mockSaveReturn, tt.mockSaveError)
-	handler := HandleGetShortenedURL(mockStore)
+	handler := HandleGetShortenedURL(mockStore, new(MockTracker), nil, RedirectOptions{})
 
 	req := httptest.NewRequest("GET", "/api/123?json=true", nil)
 	w := httptest.NewRecorder()
@@ -204,6 +456,232 @@ func TestGetShortenedURL_ValidRequest(t *testing.T) {
 	mockStore.AssertExpectations(t)
 }
 
+func TestGetShortenedURL_Redirect(t *testing.T) {
+	validUrl := "https://example.com"
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "123").Return(repositories.Link{Code: "123", URL: validUrl}, nil)
+	mockTracker := new(MockTracker)
+	mockTracker.On("Track", mock.Anything, "123").Return()
+	handler := HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{})
+
+	req := httptest.NewRequest("GET", "/api/123", nil)
+	w := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	router.Get("/api/{code}", handler.ServeHTTP)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, validUrl, w.Header().Get("Location"))
+
+	mockStore.AssertExpectations(t)
+	mockTracker.AssertExpectations(t)
+}
+
+func TestGetShortenedURL_RedirectStatus(t *testing.T) {
+	tests := []struct {
+		name                 string
+		link                 repositories.Link
+		defaults             RedirectOptions
+		expectedCode         int
+		expectedCacheControl string
+	}{
+		{
+			name:         "permanent by default",
+			link:         repositories.Link{Code: "123", URL: "https://example.com"},
+			expectedCode: http.StatusMovedPermanently,
+		},
+		{
+			name:         "configured default",
+			link:         repositories.Link{Code: "123", URL: "https://example.com"},
+			defaults:     RedirectOptions{Status: http.StatusFound},
+			expectedCode: http.StatusFound,
+		},
+		{
+			name:         "set on the link",
+			link:         repositories.Link{Code: "123", URL: "https://example.com", RedirectStatus: http.StatusTemporaryRedirect},
+			defaults:     RedirectOptions{Status: http.StatusFound},
+			expectedCode: http.StatusTemporaryRedirect,
+		},
+		{
+			name:                 "no cache on the link",
+			link:                 repositories.Link{Code: "123", URL: "https://example.com", RedirectStatus: http.StatusPermanentRedirect, NoCache: true},
+			expectedCode:         http.StatusPermanentRedirect,
+			expectedCacheControl: "no-store, max-age=0",
+		},
+		{
+			name:                 "no cache by default",
+			link:                 repositories.Link{Code: "123", URL: "https://example.com"},
+			defaults:             RedirectOptions{NoCache: true},
+			expectedCode:         http.StatusMovedPermanently,
+			expectedCacheControl: "no-store, max-age=0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(tt.link, nil)
+			mockTracker := new(MockTracker)
+			mockTracker.On("Track", mock.Anything, "123").Return()
+			handler := HandleGetShortenedURL(mockStore, mockTracker, nil, tt.defaults)
+
+			req := httptest.NewRequest("GET", "/api/123", nil)
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/api/{code}", handler.ServeHTTP)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+			assert.Equal(t, tt.expectedCacheControl, w.Header().Get("Cache-Control"))
+
+			mockStore.AssertExpectations(t)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetShortenedURL_Blocked(t *testing.T) {
+	blockedUrl := "https://phishing.example/login?next=<script>"
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "123").Return(repositories.Link{Code: "123", URL: blockedUrl}, nil)
+	// the warning page doesn't count as a click
+	mockTracker := new(MockTracker)
+	handler := HandleGetShortenedURL(mockStore, mockTracker, stubBlocklist{blockedUrl: true}, RedirectOptions{})
+
+	router := chi.NewRouter()
+	router.Get("/api/{code}", handler.ServeHTTP)
+
+	req := httptest.NewRequest("GET", "/api/123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "This link may be harmful")
+	assert.Contains(t, w.Body.String(), "https://phishing.example/login?next=&lt;script&gt;")
+
+	req = httptest.NewRequest("GET", "/api/123?json=true", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{
+		"code":"123",
+		"url":"https://phishing.example/login?next=\u003cscript\u003e",
+		"domain":"phishing.example",
+		"created_at":"0001-01-01T00:00:00Z",
+		"clicks":0,
+		"blocked":true
+	}}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+	mockTracker.AssertExpectations(t)
+}
+
+func TestGetShortenedURL_Preview(t *testing.T) {
+	createdAt := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	link := repositories.Link{
+		Code:      "123",
+		URL:       "https://www.example.com/docs?page=2",
+		Title:     "Docs",
+		CreatedAt: createdAt,
+		Clicks:    42,
+	}
+
+	tests := []struct {
+		name                string
+		target              string
+		accept              string
+		expectedContentType string
+		expectedBody        []string
+	}{
+		{
+			name:                "html with the suffix",
+			target:              "/api/123+",
+			accept:              "text/html,application/xhtml+xml,*/*;q=0.8",
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody: []string{
+				`<a href="https://www.example.com/docs?page=2"`,
+				"<dd>www.example.com</dd>",
+				"<dd>March 14, 2026</dd>",
+				"<dd>42</dd>",
+			},
+		},
+		{
+			name:                "json with the query param",
+			target:              "/api/123?preview=1",
+			accept:              "application/json",
+			expectedContentType: "application/json",
+			expectedBody: []string{
+				`"domain":"www.example.com"`,
+				`"created_at":"2026-03-14T09:30:00Z"`,
+				`"clicks":42`,
+			},
+		},
+		{
+			name:                "plain text",
+			target:              "/api/123+",
+			accept:              "text/plain",
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody: []string{
+				"Code: 123\nTitle: Docs\nURL: https://www.example.com/docs?page=2\nDomain: www.example.com\nCreated: 2026-03-14T09:30:00Z\nClicks: 42\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(link, nil)
+			// previews aren't clicks
+			mockTracker := new(MockTracker)
+			handler := HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{})
+
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/api/{code}", handler.ServeHTTP)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+
+			mockStore.AssertExpectations(t)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetShortenedURL_PreviewBlocked(t *testing.T) {
+	blockedUrl := "https://phishing.example/login"
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "123").Return(repositories.Link{Code: "123", URL: blockedUrl}, nil)
+	handler := HandleGetShortenedURL(mockStore, new(MockTracker), stubBlocklist{blockedUrl: true}, RedirectOptions{})
+
+	req := httptest.NewRequest("GET", "/api/123+", nil)
+	w := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	router.Get("/api/{code}", handler.ServeHTTP)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "flagged as potentially malicious")
+	assert.NotContains(t, w.Body.String(), `href="https://phishing.example/login"`)
+
+	mockStore.AssertExpectations(t)
+}
+
 func TestGetShortenedURL_UrlNotFound(t *testing.T) {
 	tt := struct {
 		expectedCode int
@@ -215,8 +693,36 @@ func TestGetShortenedURL_UrlNotFound(t *testing.T) {
 		},
 	}
 	mockStore := new(MockUrlRepository)
-	mockStore.On("GetURL", context.Background(), "").Return("", redis.Nil)
-	handler := HandleGetShortenedURL(mockStore)
+	mockStore.On("GetURL", context.Background(), "").Return(repositories.Link{}, repositories.ErrNotFound)
+	handler := HandleGetShortenedURL(mockStore, new(MockTracker), nil, RedirectOptions{})
+
+	req := httptest.NewRequest("GET", "/api/123", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, tt.expectedCode, w.Code)
+
+	expectedBody, _ := json.Marshal(tt.expectedBody)
+
+	assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestGetShortenedURL_UrlExpired(t *testing.T) {
+	tt := struct {
+		expectedCode int
+		expectedBody utils.ApiResponse
+	}{
+		expectedCode: http.StatusGone,
+		expectedBody: utils.ApiResponse{
+			Error: "url expired",
+		},
+	}
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", context.Background(), "").Return(repositories.Link{}, repositories.ErrExpired)
+	handler := HandleGetShortenedURL(mockStore, new(MockTracker), nil, RedirectOptions{})
 
 	req := httptest.NewRequest("GET", "/api/123", nil)
 	w := httptest.NewRecorder()
@@ -243,8 +749,8 @@ func TestGetShortenedURL_SomethingWentWrong(t *testing.T) {
 		},
 	}
 	mockStore := new(MockUrlRepository)
-	mockStore.On("GetURL", context.Background(), "").Return("", assert.AnError)
-	handler := HandleGetShortenedURL(mockStore)
+	mockStore.On("GetURL", context.Background(), "").Return(repositories.Link{}, assert.AnError)
+	handler := HandleGetShortenedURL(mockStore, new(MockTracker), nil, RedirectOptions{})
 
 	req := httptest.NewRequest("GET", "/api/123", nil)
 	w := httptest.NewRecorder()
@@ -261,21 +767,31 @@ func TestGetShortenedURL_SomethingWentWrong(t *testing.T) {
 }
 
 func TestGetAllURL_ValidRequest(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	link := repositories.Link{
+		Code:      "123",
+		URL:       "https://example.com",
+		CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Tags:      []string{"launch"},
+		ExpiresAt: &expiresAt,
+		Clicks:    10,
+	}
 	tt := struct {
-		mockSaveReturn map[string]string
+		mockSaveReturn repositories.LinkPage
 		mockSaveError  error
 		expectedCode   int
 		expectedBody   utils.ApiResponse
 	}{
-		mockSaveReturn: map[string]string{"123": "https://example.com"},
+		mockSaveReturn: repositories.LinkPage{Links: []repositories.Link{link}},
 		mockSaveError:  nil,
 		expectedCode:   http.StatusOK,
 		expectedBody: utils.ApiResponse{
-			Data: getAllUrlsResponse{URLs: map[string]string{"123": "https://example.com"}},
+			Data: getAllUrlsResponse{Links: []repositories.Link{link}},
 		},
 	}
 	mockStore := new(MockUrlRepository)
-	mockStore.On("GetAllURL", context.Background()).Return(tt.mockSaveReturn, tt.mockSaveError)
+	mockStore.On("ListURL", context.Background(), repositories.ListOptions{}).Return(tt.mockSaveReturn, tt.mockSaveError)
 	handler := HandleGetAllUrls(mockStore)
 
 	req := httptest.NewRequest("GET", "/admin/all", nil)
@@ -303,7 +819,7 @@ func TestGetAllURL_SomethingWentWrong(t *testing.T) {
 		},
 	}
 	mockStore := new(MockUrlRepository)
-	mockStore.On("GetAllURL", context.Background()).Return(map[string]string{}, assert.AnError)
+	mockStore.On("ListURL", context.Background(), repositories.ListOptions{}).Return(repositories.LinkPage{}, assert.AnError)
 	handler := HandleGetAllUrls(mockStore)
 
 	req := httptest.NewRequest("GET", "/admin/all", nil)
@@ -320,6 +836,68 @@ func TestGetAllURL_SomethingWentWrong(t *testing.T) {
 	mockStore.AssertExpectations(t)
 }
 
+func TestGetAllURL_Filters(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("ListURL", context.Background(), repositories.ListOptions{
+		Cursor:      "10",
+		Limit:       5,
+		Sort:        repositories.SortCreatedDesc,
+		Contains:    "guide",
+		Domain:      "example.com",
+		Tag:         "docs",
+		CreatedFrom: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedTo:   time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}).Return(repositories.LinkPage{Links: []repositories.Link{}, NextCursor: "15"}, nil)
+	handler := HandleGetAllUrls(mockStore)
+
+	req := httptest.NewRequest("GET", "/admin/all?cursor=10&limit=5&sort=-created_at&q=guide&domain=example.com&tag=docs&created_from=2026-01-01T00:00:00Z&created_to=2026-02-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"links":[],"next_cursor":"15"}}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestGetAllURL_BadRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		mockError error
+		expected  string
+	}{
+		{name: "limit", query: "limit=0", expected: "limit must be between 1 and 1000"},
+		{name: "limit not a number", query: "limit=ten", expected: "limit must be between 1 and 1000"},
+		{name: "sort", query: "sort=url", expected: "sort must be created_at or -created_at"},
+		{name: "date", query: "created_from=yesterday", expected: "created_from must be an RFC 3339 date"},
+		{name: "cursor", query: "cursor=x", mockError: repositories.ErrInvalidCursor, expected: "invalid cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			if tt.mockError != nil {
+				mockStore.On("ListURL", context.Background(), mock.Anything).Return(repositories.LinkPage{}, tt.mockError)
+			}
+			handler := HandleGetAllUrls(mockStore)
+
+			req := httptest.NewRequest("GET", "/admin/all?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			expectedBody, _ := json.Marshal(utils.ApiResponse{Error: tt.expected})
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
 func TestDeleteURL_ValidRequest(t *testing.T) {
 	tt := struct {
 		mockSaveError error
@@ -354,7 +932,7 @@ func TestDeleteURL_URLNotFound(t *testing.T) {
 		expectedCode  int
 		expectedBody  utils.ApiResponse
 	}{
-		mockSaveError: redis.Nil,
+		mockSaveError: repositories.ErrNotFound,
 		expectedCode:  http.StatusNotFound,
 		expectedBody: utils.ApiResponse{
 			Error: "url not found",
@@ -424,20 +1002,20 @@ func TestUpdateShortenedURL_ValidRequest(t *testing.T) {
 	validUrl := "https://example.com"
 	tt := struct {
 		body           updateBody
-		mockSaveReturn string
+		mockSaveReturn repositories.Link
 		mockSaveError  error
 		expectedCode   int
 		expectedBody   utils.ApiResponse
 	}{
 		body:           updateBody{NewURL: validUrl},
-		mockSaveReturn: "123",
+		mockSaveReturn: repositories.Link{Code: "123", URL: "https://old.com"},
 		mockSaveError:  nil,
 		expectedCode:   http.StatusCreated,
 		expectedBody:   utils.ApiResponse{Data: "123"},
 	}
 	mockStore := new(MockUrlRepository)
-	mockStore.On("UpdateURL", mock.Anything, "123", tt.body.NewURL).Return(tt.mockSaveReturn, tt.mockSaveError)
-	handler := HandleUpdateShortenedURL(mockStore)
+	mockStore.On("UpdateURL", mock.Anything, "123").Return(tt.mockSaveReturn, tt.mockSaveError)
+	handler := HandleUpdateShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
 
 	var requestBody bytes.Buffer
 	json.NewEncoder(&requestBody).Encode(tt.body)
@@ -474,7 +1052,7 @@ func TestUpdateShortenedURL_InvalidRequest(t *testing.T) {
 	}
 
 	mockStore := new(MockUrlRepository)
-	handler := HandleUpdateShortenedURL(mockStore)
+	handler := HandleUpdateShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
 
 	var requestBody bytes.Buffer
 	json.NewEncoder(&requestBody).Encode(tt.body)
@@ -499,6 +1077,26 @@ func TestUpdateShortenedURL_InvalidRequest(t *testing.T) {
 	mockStore.AssertExpectations(t)
 }
 
+func TestUpdateShortenedURL_RejectedURL(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	handler := HandleUpdateShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	req, err := http.NewRequest(http.MethodPut, "/admin/123", bytes.NewBufferString(`{"new_url":"ftp://example.com/file"}`))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	router.Put("/admin/{code}", handler.ServeHTTP)
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":"URL scheme must be one of http, https","reason":"scheme_not_allowed"}`, rr.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
 func TestUpdateShortenedURL_MissingParams(t *testing.T) {
 	tt := struct {
 		name         string
@@ -512,7 +1110,7 @@ func TestUpdateShortenedURL_MissingParams(t *testing.T) {
 	}
 
 	mockStore := new(MockUrlRepository)
-	handler := HandleUpdateShortenedURL(mockStore)
+	handler := HandleUpdateShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
 
 	var requestBody bytes.Buffer
 	json.NewEncoder(&requestBody).Encode(tt.body)
@@ -541,22 +1139,22 @@ func TestUpdateShortenedURL_URLNotFound(t *testing.T) {
 	validUrl := "https://example.com"
 	tt := struct {
 		body           updateBody
-		mockSaveReturn string
+		mockSaveReturn repositories.Link
 		mockSaveError  error
 		expectedCode   int
 		expectedBody   utils.ApiResponse
 	}{
 		body:           updateBody{NewURL: validUrl},
-		mockSaveReturn: "123",
-		mockSaveError:  redis.Nil,
+		mockSaveReturn: repositories.Link{Code: "123", URL: "https://old.com"},
+		mockSaveError:  repositories.ErrNotFound,
 		expectedCode:   http.StatusNotFound,
 		expectedBody: utils.ApiResponse{
 			Error: "url not found",
 		},
 	}
 	mockStore := new(MockUrlRepository)
-	mockStore.On("UpdateURL", mock.Anything, "123", tt.body.NewURL).Return(tt.mockSaveReturn, tt.mockSaveError)
-	handler := HandleUpdateShortenedURL(mockStore)
+	mockStore.On("UpdateURL", mock.Anything, "123").Return(tt.mockSaveReturn, tt.mockSaveError)
+	handler := HandleUpdateShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
 
 	var requestBody bytes.Buffer
 	json.NewEncoder(&requestBody).Encode(tt.body)
@@ -585,13 +1183,13 @@ func TestUpdateShortenedURL_SomethingWentWrong(t *testing.T) {
 	validUrl := "https://example.com"
 	tt := struct {
 		body           updateBody
-		mockSaveReturn string
+		mockSaveReturn repositories.Link
 		mockSaveError  error
 		expectedCode   int
 		expectedBody   utils.ApiResponse
 	}{
 		body:           updateBody{NewURL: validUrl},
-		mockSaveReturn: "123",
+		mockSaveReturn: repositories.Link{Code: "123", URL: "https://old.com"},
 		mockSaveError:  assert.AnError,
 		expectedCode:   http.StatusInternalServerError,
 		expectedBody: utils.ApiResponse{
@@ -599,8 +1197,8 @@ func TestUpdateShortenedURL_SomethingWentWrong(t *testing.T) {
 		},
 	}
 	mockStore := new(MockUrlRepository)
-	mockStore.On("UpdateURL", mock.Anything, "123", tt.body.NewURL).Return(tt.mockSaveReturn, tt.mockSaveError)
-	handler := HandleUpdateShortenedURL(mockStore)
+	mockStore.On("UpdateURL", mock.Anything, "123").Return(tt.mockSaveReturn, tt.mockSaveError)
+	handler := HandleUpdateShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
 
 	var requestBody bytes.Buffer
 	json.NewEncoder(&requestBody).Encode(tt.body)
@@ -624,3 +1222,330 @@ func TestUpdateShortenedURL_SomethingWentWrong(t *testing.T) {
 
 	mockStore.AssertExpectations(t)
 }
+
+func TestPostShortenedURL_Metadata(t *testing.T) {
+	validUrl := "https://example.com"
+	body := postBody{URL: validUrl, Title: "Launch", Tags: []string{" Launch ", "docs", "launch", ""}}
+
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveShortenedURL", mock.Anything, repositories.Link{
+		URL:   validUrl,
+		Title: "Launch",
+		Tags:  []string{"launch", "docs"},
+	}).Return("abc12345", nil)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	var requestBody bytes.Buffer
+	json.NewEncoder(&requestBody).Encode(body)
+
+	req := httptest.NewRequest("POST", "/api/shorten", &requestBody)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestPostShortenedURL_Creator(t *testing.T) {
+	ctx := context.Background()
+	keys := repositories.NewMemoryApiKeyRepository()
+	key, apiKey, err := auth.GenerateKey("marketing", []string{auth.ScopeLinksCreate}, time.Now())
+	assert.NoError(t, err)
+	assert.NoError(t, keys.SaveApiKey(ctx, apiKey))
+
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveShortenedURL", mock.Anything, repositories.Link{
+		URL:     "https://example.com",
+		Creator: "marketing",
+	}).Return("abc12345", nil)
+	handler := auth.NewAuthenticator(keys, "", "").Require(auth.ScopeLinksCreate)(HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{}))
+
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com"}`))
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestPostShortenedURL_Reuse(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		findReturn   repositories.Link
+		findError    error
+		expectFind   bool
+		expectSave   bool
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "reused",
+			body:         `{"url":"https://example.com","reuse":true}`,
+			findReturn:   repositories.Link{Code: "existing"},
+			expectFind:   true,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":"existing"}`,
+		},
+		{
+			name:         "nothing to reuse",
+			body:         `{"url":"https://example.com","reuse":true}`,
+			findError:    repositories.ErrNotFound,
+			expectFind:   true,
+			expectSave:   true,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"data":"abc12345"}`,
+		},
+		{
+			name:         "expired link",
+			body:         `{"url":"https://example.com","reuse":true}`,
+			findReturn:   repositories.Link{Code: "existing"},
+			findError:    repositories.ErrExpired,
+			expectFind:   true,
+			expectSave:   true,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"data":"abc12345"}`,
+		},
+		{
+			name:         "not asked",
+			body:         `{"url":"https://example.com"}`,
+			expectSave:   true,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"data":"abc12345"}`,
+		},
+		{
+			name:         "find failed",
+			body:         `{"url":"https://example.com","reuse":true}`,
+			findError:    assert.AnError,
+			expectFind:   true,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":"something went wrong"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			if tt.expectFind {
+				mockStore.On("FindURL", mock.Anything, "", "", "https://example.com").Return(tt.findReturn, tt.findError)
+			}
+			if tt.expectSave {
+				mockStore.On("SaveShortenedURL", mock.Anything, repositories.Link{URL: "https://example.com"}).Return("abc12345", nil)
+			}
+			handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+			req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetShortenedURL_Disabled(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("GetURL", mock.Anything, "").Return(repositories.Link{URL: "https://example.com", Disabled: true}, nil)
+	handler := HandleGetShortenedURL(mockStore, new(MockTracker), nil, RedirectOptions{})
+
+	req := httptest.NewRequest("GET", "/api/123", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.JSONEq(t, `{"error":"url disabled"}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestGetLink(t *testing.T) {
+	link := repositories.Link{
+		Code:      "123",
+		URL:       "https://example.com",
+		CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		Title:     "Example",
+		Clicks:    4,
+	}
+	tests := []struct {
+		name         string
+		mockLink     repositories.Link
+		mockError    error
+		expectedCode int
+		expectedBody utils.ApiResponse
+	}{
+		{
+			name:         "valid request",
+			mockLink:     link,
+			expectedCode: http.StatusOK,
+			expectedBody: utils.ApiResponse{Data: link},
+		},
+		{
+			name:         "expired link",
+			mockLink:     link,
+			mockError:    repositories.ErrExpired,
+			expectedCode: http.StatusOK,
+			expectedBody: utils.ApiResponse{Data: link},
+		},
+		{
+			name:         "url not found",
+			mockError:    repositories.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: utils.ApiResponse{Error: "url not found"},
+		},
+		{
+			name:         "something went wrong",
+			mockError:    assert.AnError,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: utils.ApiResponse{Error: "something went wrong"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(tt.mockLink, tt.mockError)
+			handler := HandleGetLink(mockStore)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/123", nil)
+			rr := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/admin/{code}", handler.ServeHTTP)
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			expectedBody, _ := json.Marshal(tt.expectedBody)
+			assert.JSONEq(t, string(expectedBody), rr.Body.String())
+
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostShortenedURL_RedirectOptions(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveShortenedURL", mock.Anything, repositories.Link{
+		URL:            "https://example.com",
+		RedirectStatus: http.StatusFound,
+		NoCache:        true,
+	}).Return("abc12345", nil)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","redirect_status":302,"no_cache":true}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"data":"abc12345"}`, w.Body.String())
+
+	req = httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","redirect_status":303}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"redirect_status must be 301, 302, 307 or 308"}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestUpdateShortenedURL_InvalidRedirectStatus(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	handler := HandleUpdateShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	req, err := http.NewRequest(http.MethodPut, "/admin/123", bytes.NewBufferString(`{"new_url":"https://example.com","redirect_status":200}`))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	router.Put("/admin/{code}", handler.ServeHTTP)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":"redirect_status must be 301, 302, 307 or 308"}`, rr.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestPostShortenedURL_MaxClicks(t *testing.T) {
+	mockStore := new(MockUrlRepository)
+	mockStore.On("SaveShortenedURL", mock.Anything, repositories.Link{
+		URL:       "https://example.com",
+		MaxClicks: 1,
+	}).Return("abc12345", nil)
+	handler := HandlePostShortenedURL(mockStore, newTestDomains(t), validation.Policy{})
+
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","max_clicks":1,"reuse":true}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"data":"abc12345"}`, w.Body.String())
+
+	req = httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com","max_clicks":-1}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"max_clicks must be positive"}`, w.Body.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestGetShortenedURL_MaxClicks(t *testing.T) {
+	link := repositories.Link{Code: "123", URL: "https://example.com", MaxClicks: 1}
+
+	tests := []struct {
+		name         string
+		consumeErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "click left", expectedCode: http.StatusMovedPermanently},
+		{name: "exhausted", consumeErr: repositories.ErrExhausted, expectedCode: http.StatusGone, expectedBody: `{"error":"url exhausted"}`},
+		{name: "failure", consumeErr: assert.AnError, expectedCode: http.StatusInternalServerError, expectedBody: `{"error":"something went wrong"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockUrlRepository)
+			mockStore.On("GetURL", mock.Anything, "123").Return(link, nil)
+			mockStore.On("ConsumeClick", mock.Anything, "123", int64(1)).Return(tt.consumeErr)
+			mockTracker := new(MockTracker)
+			if tt.consumeErr == nil {
+				mockTracker.On("Track", mock.Anything, "123").Return()
+			}
+			handler := HandleGetShortenedURL(mockStore, mockTracker, nil, RedirectOptions{})
+
+			req := httptest.NewRequest("GET", "/api/123", nil)
+			w := httptest.NewRecorder()
+
+			router := chi.NewRouter()
+			router.Get("/api/{code}", handler.ServeHTTP)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, noCacheControl, w.Header().Get("Cache-Control"))
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			} else {
+				assert.Equal(t, link.URL, w.Header().Get("Location"))
+			}
+
+			mockStore.AssertExpectations(t)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
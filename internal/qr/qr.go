@@ -0,0 +1,172 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	DefaultSize   = 256
+	MinSize       = 64
+	MaxSize       = 2048
+	DefaultMargin = 4
+	MaxMargin     = 16
+)
+
+var ErrSizeTooSmall = errors.New("size is too small for the code")
+
+// levels maps the usual error correction letters to the recovery levels,
+// L recovering about 7% of the code and H about 30%.
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options tells how a QR code is rendered.
+type Options struct {
+	// Size is the width and height of the image in pixels.
+	Size int
+	// Level is the error correction level: L, M, Q or H.
+	Level string
+	// Margin is the quiet zone around the code, in modules.
+	Margin     int
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// DefaultOptions renders a 256px black on white code, with medium error
+// correction and the standard 4 modules margin.
+func DefaultOptions() Options {
+	return Options{
+		Size:       DefaultSize,
+		Level:      "M",
+		Margin:     DefaultMargin,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// ValidLevel reports whether the level is one of L, M, Q or H.
+func ValidLevel(level string) bool {
+	_, ok := levels[level]
+	return ok
+}
+
+// ParseColor reads a hex color, as RGB or RRGGBB with an optional "#".
+func ParseColor(value string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", value)
+	}
+
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", value)
+	}
+
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
+
+// modules encodes the content into its dark/light modules, margin included.
+func modules(content string, opts Options) ([][]bool, error) {
+	level, ok := levels[opts.Level]
+	if !ok {
+		return nil, fmt.Errorf("invalid error correction level %q", opts.Level)
+	}
+
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	// the library border is fixed, the margin is added here instead
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+
+	total := len(bitmap) + 2*opts.Margin
+	grid := make([][]bool, total)
+	for y := range grid {
+		grid[y] = make([]bool, total)
+		if row := y - opts.Margin; row >= 0 && row < len(bitmap) {
+			copy(grid[y][opts.Margin:], bitmap[row])
+		}
+	}
+	return grid, nil
+}
+
+// PNG renders the content as a PNG image. Modules are a whole number of
+// pixels wide so the code stays sharp, the pixels left are spread around it.
+func PNG(content string, opts Options) ([]byte, error) {
+	grid, err := modules(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	scale := opts.Size / len(grid)
+	if scale == 0 {
+		return nil, ErrSizeTooSmall
+	}
+	offset := (opts.Size - scale*len(grid)) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, opts.Size, opts.Size), color.Palette{opts.Background, opts.Foreground})
+	for y, row := range grid {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				start := img.PixOffset(offset+x*scale, offset+y*scale+dy)
+				for dx := 0; dx < scale; dx++ {
+					img.Pix[start+dx] = 1
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the content as an SVG image, drawing the dark modules as a
+// single path.
+func SVG(content string, opts Options) ([]byte, error) {
+	grid, err := modules(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, len(grid), len(grid))
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, hexColor(opts.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hexColor(opts.Foreground))
+	for y, row := range grid {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,107 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPNG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Foreground = color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}
+
+	data, err := PNG("http://localhost:9000/api/abc12345", opts)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSize, img.Bounds().Dx())
+	assert.Equal(t, DefaultSize, img.Bounds().Dy())
+
+	// the top left finder pattern starts right after the margin
+	grid, err := modules("http://localhost:9000/api/abc12345", opts)
+	require.NoError(t, err)
+	scale := DefaultSize / len(grid)
+	offset := (DefaultSize - scale*len(grid)) / 2
+	corner := offset + DefaultMargin*scale
+
+	r, g, b, _ := img.At(corner, corner).RGBA()
+	assert.Equal(t, []uint32{0x1111, 0x2222, 0x3333}, []uint32{r, g, b})
+	r, g, b, _ = img.At(corner-1, corner-1).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0xffff, 0xffff}, []uint32{r, g, b})
+}
+
+func TestPNG_SizeTooSmall(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Size = 10
+
+	_, err := PNG("http://localhost:9000/api/abc12345", opts)
+	assert.ErrorIs(t, err, ErrSizeTooSmall)
+}
+
+func TestSVG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Margin = 0
+	opts.Level = "H"
+	opts.Background = color.RGBA{R: 0xff, G: 0xee, B: 0xdd, A: 0xff}
+
+	data, err := SVG("http://localhost:9000/api/abc12345", opts)
+	require.NoError(t, err)
+
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256"`))
+	assert.Contains(t, svg, `fill="#ffeedd"`)
+	// without margin the finder pattern starts at the origin
+	assert.Contains(t, svg, `d="M0 0h1v1h-1z`)
+}
+
+func TestModules_Margin(t *testing.T) {
+	opts := DefaultOptions()
+	noMargin := opts
+	noMargin.Margin = 0
+
+	withMargin, err := modules("content", opts)
+	require.NoError(t, err)
+	without, err := modules("content", noMargin)
+	require.NoError(t, err)
+
+	assert.Equal(t, len(without)+2*DefaultMargin, len(withMargin))
+	assert.False(t, withMargin[DefaultMargin-1][DefaultMargin-1])
+	assert.True(t, withMargin[DefaultMargin][DefaultMargin])
+
+	opts.Level = "X"
+	_, err = modules("content", opts)
+	assert.Error(t, err)
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected color.RGBA
+		err      bool
+	}{
+		{value: "#ff8000", expected: color.RGBA{R: 0xff, G: 0x80, A: 0xff}},
+		{value: "0A0B0C", expected: color.RGBA{R: 0x0a, G: 0x0b, B: 0x0c, A: 0xff}},
+		{value: "fff", expected: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{value: "red", err: true},
+		{value: "#12345", err: true},
+		{value: "gggggg", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			c, err := ParseColor(tt.value)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, c)
+		})
+	}
+}
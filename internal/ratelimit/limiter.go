@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests per Window, with bursts of up to Requests. A zero
+// Limit disables limiting.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l Limit) Disabled() bool {
+	return l.Requests <= 0 || l.Window <= 0
+}
+
+// emission is the interval between two requests at a steady rate.
+func (l Limit) emission() time.Duration {
+	return l.Window / time.Duration(l.Requests)
+}
+
+func (l Limit) String() string {
+	if l.Disabled() {
+		return "off"
+	}
+	return strconv.Itoa(l.Requests) + "/" + l.Window.String()
+}
+
+// ParseLimit parses limits written as "requests/window", like "60/1m", or
+// "off" for no limit.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "off" || value == "0" {
+		return Limit{}, nil
+	}
+
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected requests/window", value)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit requests %q", requests)
+	}
+
+	d, err := time.ParseDuration(window)
+	if err != nil || d < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit window %q", window)
+	}
+
+	return Limit{Requests: n, Window: d}, nil
+}
+
+// Result is the outcome of a rate limited request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is how long until the full limit is available again.
+	ResetAfter time.Duration
+	// RetryAfter is how long until the next request is allowed, zero when
+	// this one was.
+	RetryAfter time.Duration
+}
+
+// Limiter enforces a limit per key, using the generic cell rate algorithm,
+// a token bucket that only needs to store one timestamp per key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// gcra applies one request to the theoretical arrival time of the key and
+// returns the result along with the new arrival time to store, now being
+// returned when the request isn't allowed and nothing should change.
+func gcra(now time.Time, tat time.Time, limit Limit) (Result, time.Time) {
+	emission := limit.emission()
+	tolerance := emission * time.Duration(limit.Requests)
+
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTat := tat.Add(emission)
+	allowAt := newTat.Add(-tolerance)
+	if now.Before(allowAt) {
+		return Result{
+			Limit:      limit.Requests,
+			ResetAfter: tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}, tat
+	}
+
+	return Result{
+		Allowed:    true,
+		Limit:      limit.Requests,
+		Remaining:  int((tolerance - newTat.Sub(now)) / emission),
+		ResetAfter: newTat.Sub(now),
+	}, newTat
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many calls the memory limiter waits between removing
+// the keys whose bucket is full again.
+const sweepEvery = 1000
+
+// MemoryLimiter keeps the buckets in process memory, so its limits only
+// hold within one replica. It is meant for the backends without redis.
+type MemoryLimiter struct {
+	mu    sync.Mutex
+	tats  map[string]time.Time
+	calls int
+	now   func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{tats: map[string]time.Time{}, now: time.Now}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	result, tat := gcra(now, l.tats[key], limit)
+	if result.Allowed {
+		l.tats[key] = tat
+	}
+
+	l.calls++
+	if l.calls%sweepEvery == 0 {
+		for key, tat := range l.tats {
+			if !tat.After(now) {
+				delete(l.tats, key)
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/auth"
+	"url-shortener/internal/utils"
+)
+
+// Middleware limits the requests of a route, named by route, counting them
+// per API key when the request was authenticated with one and per client IP
+// otherwise. It must come after the middlewares setting either of them.
+// Limiter failures let requests through, an outage of the limiter storage
+// shouldn't take the routes down.
+func Middleware(limiter Limiter, route string, limit Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit.Disabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := limiter.Allow(r.Context(), route+":"+clientKey(r), limit)
+			if err != nil {
+				slog.Error("error checking rate limit", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", seconds(result.ResetAfter))
+
+			if !result.Allowed {
+				h.Set("Retry-After", seconds(result.RetryAfter))
+				utils.SendJSON(w, utils.ApiResponse{
+					Error: "too many requests",
+				}, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok && principal.KeyID != "" {
+		return "key:" + principal.KeyID
+	}
+	return "ip:" + ClientIP(r)
+}
+
+// ClientIP returns the IP of the client, as found by RealIP.
+func ClientIP(r *http.Request) string {
+	// RemoteAddr holds just the IP once RealIP found one
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return ip
+}
+
+// seconds rounds up, so clients never retry too early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,194 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Limit
+		err      bool
+	}{
+		{value: "60/1m", expected: Limit{Requests: 60, Window: time.Minute}},
+		{value: " 10/1s ", expected: Limit{Requests: 10, Window: time.Second}},
+		{value: "off"},
+		{value: ""},
+		{value: "60", err: true},
+		{value: "a/1m", err: true},
+		{value: "60/minute", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			limit, err := ParseLimit(tt.value)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, limit)
+		})
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	limit := Limit{Requests: 3, Window: 3 * time.Second}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var tat time.Time
+	for remaining := 2; remaining >= 0; remaining-- {
+		var result Result
+		result, tat = gcra(now, tat, limit)
+		require.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, next := gcra(now, tat, limit)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, tat, next)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.ResetAfter)
+
+	// one emission interval later one request is allowed again
+	result, _ = gcra(now.Add(time.Second), tat, limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func newTestLimiters(t *testing.T) map[string]Limiter {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	return map[string]Limiter{
+		"memory": NewMemoryLimiter(),
+		"redis":  NewRedisLimiter(rdb),
+	}
+}
+
+func TestLimiters(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Window: time.Hour}
+
+	for name, limiter := range newTestLimiters(t) {
+		t.Run(name, func(t *testing.T) {
+			for range 2 {
+				result, err := limiter.Allow(ctx, "a", limit)
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+			}
+
+			result, err := limiter.Allow(ctx, "a", limit)
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, 0, result.Remaining)
+			assert.InDelta(t, 30*time.Minute, result.RetryAfter, float64(time.Second))
+
+			// keys don't share their buckets
+			result, err = limiter.Allow(ctx, "b", limit)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 1, result.Remaining)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	limit := Limit{Requests: 1, Window: time.Minute}
+	handler := Middleware(NewMemoryLimiter(), "redirect", limit)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMovedPermanently)
+		}),
+	)
+
+	req := httptest.NewRequest("GET", "/api/abc", nil)
+	req.RemoteAddr = "203.0.113.1:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"too many requests"}`, w.Body.String())
+
+	// another client has its own limit
+	req.RemoteAddr = "203.0.113.2:1234"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies(" 10.0.0.0/8, 192.0.2.1 ,::1")
+	require.NoError(t, err)
+	require.Len(t, proxies, 3)
+	assert.True(t, isTrusted(proxies, "10.1.2.3"))
+	assert.True(t, isTrusted(proxies, "192.0.2.1"))
+	assert.False(t, isTrusted(proxies, "192.0.2.2"))
+	assert.True(t, isTrusted(proxies, "::1"))
+
+	proxies, err = ParseTrustedProxies("")
+	require.NoError(t, err)
+	assert.Empty(t, proxies)
+
+	_, err = ParseTrustedProxies("10.0.0.0/8,proxy")
+	assert.Error(t, err)
+}
+
+func TestRealIP_SpoofedHeaders(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8")
+	require.NoError(t, err)
+
+	limit := Limit{Requests: 1, Window: time.Minute}
+	handler := RealIP(trusted)(Middleware(NewMemoryLimiter(), "redirect", limit)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMovedPermanently)
+		}),
+	))
+
+	serve := func(remoteAddr string, headers map[string]string) int {
+		req := httptest.NewRequest("GET", "/abc", nil)
+		req.RemoteAddr = remoteAddr
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// a client reaching the app directly can't pick a new IP per request
+	assert.Equal(t, http.StatusMovedPermanently, serve("203.0.113.1:1234", nil))
+	for i, header := range []string{"True-Client-IP", "X-Real-IP", "X-Forwarded-For"} {
+		ip := "198.51.100." + strconv.Itoa(i+1)
+		assert.Equal(t, http.StatusTooManyRequests, serve("203.0.113.1:1234", map[string]string{header: ip}), header)
+	}
+
+	// behind the trusted proxy only its X-Real-IP counts
+	proxied := func(realIP string, spoofed string) int {
+		return serve("10.0.0.5:4321", map[string]string{"X-Real-IP": realIP, "True-Client-IP": spoofed})
+	}
+	assert.Equal(t, http.StatusMovedPermanently, proxied("203.0.113.7", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, proxied("203.0.113.7", "198.51.100.2"))
+	assert.Equal(t, http.StatusMovedPermanently, proxied("203.0.113.8", "198.51.100.2"))
+}
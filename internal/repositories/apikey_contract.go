@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrKeyNotFound is returned when no API key matches the id or hash.
+	ErrKeyNotFound = errors.New("api key not found")
+	// ErrKeyExists is returned when saving an API key whose id or hash is already stored.
+	ErrKeyExists = errors.New("api key already exists")
+)
+
+// ApiKey is an issued API key. The key itself is never stored, only its
+// hash, which is kept out of the JSON representation.
+type ApiKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Prefix is the start of the key, enough for people to recognize it.
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Tenant limits the key to the links of the tenant, empty for the keys
+	// allowed across tenants.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// Revoked reports whether the key was revoked.
+func (k ApiKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// ApiKeyContract is the storage contract for API keys, implemented by every
+// storage backend.
+type ApiKeyContract interface {
+	SaveApiKey(ctx context.Context, key ApiKey) error
+	// GetApiKeyByHash returns the key with the hash, revoked ones included.
+	GetApiKeyByHash(ctx context.Context, hash string) (ApiKey, error)
+	// ListApiKeys returns every key, revoked ones included, oldest first.
+	ListApiKeys(ctx context.Context) ([]ApiKey, error)
+	// RevokeApiKey marks the key as revoked and returns it, revoking an
+	// already revoked key keeps its first revocation time.
+	RevokeApiKey(ctx context.Context, id string) (ApiKey, error)
+}
+
+// apiKeyRecord is the stored form of an ApiKey, hash included.
+type apiKeyRecord struct {
+	ApiKey
+	Hash string `json:"hash"`
+}
+
+func encodeApiKey(key ApiKey) (string, error) {
+	data, err := json.Marshal(apiKeyRecord{ApiKey: key, Hash: key.Hash})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode api key %q: %w", key.ID, err)
+	}
+	return string(data), nil
+}
+
+func decodeApiKey(value string) (ApiKey, error) {
+	var record apiKeyRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return ApiKey{}, fmt.Errorf("failed to decode api key: %w", err)
+	}
+	record.ApiKey.Hash = record.Hash
+	return record.ApiKey, nil
+}
+
+func revokeApiKey(key *ApiKey, now time.Time) {
+	if key.RevokedAt == nil {
+		key.RevokedAt = &now
+	}
+}
+
+func sortApiKeys(keys []ApiKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+}
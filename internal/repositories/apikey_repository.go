@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	apiKeysKey      = "encurtador:apikeys"
+	apiKeyHashesKey = "encurtador:apikeys:hashes"
+)
+
+// saveApiKeyScript stores the key and its hash index entry only if neither
+// the id nor the hash are taken.
+var saveApiKeyScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 or redis.call('HEXISTS', KEYS[2], ARGV[3]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('HSET', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`)
+
+type ApiKeyRepository struct {
+	rdb *redis.Client
+}
+
+func NewApiKeyRepository(rdb *redis.Client) ApiKeyContract {
+	return &ApiKeyRepository{rdb: rdb}
+}
+
+func (s *ApiKeyRepository) SaveApiKey(ctx context.Context, key ApiKey) error {
+	value, err := encodeApiKey(key)
+	if err != nil {
+		return err
+	}
+
+	ok, err := saveApiKeyScript.Run(ctx, s.rdb, []string{apiKeysKey, apiKeyHashesKey}, key.ID, value, key.Hash).Bool()
+	if err != nil {
+		return fmt.Errorf("error setting on redis: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("failed to save api key %q: %w", key.ID, ErrKeyExists)
+	}
+
+	return nil
+}
+
+func (s *ApiKeyRepository) GetApiKeyByHash(ctx context.Context, hash string) (ApiKey, error) {
+	id, err := s.rdb.HGet(ctx, apiKeyHashesKey, hash).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ApiKey{}, ErrKeyNotFound
+		}
+		return ApiKey{}, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	value, err := s.rdb.HGet(ctx, apiKeysKey, id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ApiKey{}, ErrKeyNotFound
+		}
+		return ApiKey{}, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return decodeApiKey(value)
+}
+
+func (s *ApiKeyRepository) ListApiKeys(ctx context.Context) ([]ApiKey, error) {
+	values, err := s.rdb.HVals(ctx, apiKeysKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	keys := make([]ApiKey, 0, len(values))
+	for _, value := range values {
+		key, err := decodeApiKey(value)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sortApiKeys(keys)
+
+	return keys, nil
+}
+
+func (s *ApiKeyRepository) RevokeApiKey(ctx context.Context, id string) (ApiKey, error) {
+	var revoked ApiKey
+	txf := func(tx *redis.Tx) error {
+		value, err := tx.HGet(ctx, apiKeysKey, id).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return ErrKeyNotFound
+			}
+			return err
+		}
+
+		key, err := decodeApiKey(value)
+		if err != nil {
+			return err
+		}
+		revokeApiKey(&key, time.Now())
+
+		value, err = encodeApiKey(key)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, apiKeysKey, id, value)
+			return nil
+		})
+		revoked = key
+		return err
+	}
+
+	for range updateRetries {
+		err := s.rdb.Watch(ctx, txf, apiKeysKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return ApiKey{}, fmt.Errorf("failed to revoke api key: %w", err)
+		}
+		return revoked, nil
+	}
+
+	return ApiKey{}, fmt.Errorf("failed to revoke api key: %w", redis.TxFailedErr)
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltApiKeysBucket      = []byte("apikeys")
+	boltApiKeyHashesBucket = []byte("apikey_hashes")
+)
+
+type BoltApiKeyRepository struct {
+	db *bolt.DB
+}
+
+func NewBoltApiKeyRepository(db *bolt.DB) (ApiKeyContract, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltApiKeysBucket, boltApiKeyHashesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bolt buckets: %w", err)
+	}
+
+	return &BoltApiKeyRepository{db: db}, nil
+}
+
+func (s *BoltApiKeyRepository) SaveApiKey(ctx context.Context, key ApiKey) error {
+	value, err := encodeApiKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(boltApiKeysBucket)
+		hashes := tx.Bucket(boltApiKeyHashesBucket)
+		if keys.Get([]byte(key.ID)) != nil || hashes.Get([]byte(key.Hash)) != nil {
+			return fmt.Errorf("failed to save api key %q: %w", key.ID, ErrKeyExists)
+		}
+
+		if err := keys.Put([]byte(key.ID), []byte(value)); err != nil {
+			return err
+		}
+		return hashes.Put([]byte(key.Hash), []byte(key.ID))
+	})
+	if err != nil {
+		return fmt.Errorf("error setting on bolt: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltApiKeyRepository) GetApiKeyByHash(ctx context.Context, hash string) (ApiKey, error) {
+	var key ApiKey
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(boltApiKeyHashesBucket).Get([]byte(hash))
+		if id == nil {
+			return ErrKeyNotFound
+		}
+
+		value := tx.Bucket(boltApiKeysBucket).Get(id)
+		if value == nil {
+			return ErrKeyNotFound
+		}
+
+		var err error
+		key, err = decodeApiKey(string(value))
+		return err
+	})
+	if err != nil {
+		return ApiKey{}, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (s *BoltApiKeyRepository) ListApiKeys(ctx context.Context) ([]ApiKey, error) {
+	keys := []ApiKey{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltApiKeysBucket).ForEach(func(_, value []byte) error {
+			key, err := decodeApiKey(string(value))
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	sortApiKeys(keys)
+
+	return keys, nil
+}
+
+func (s *BoltApiKeyRepository) RevokeApiKey(ctx context.Context, id string) (ApiKey, error) {
+	var key ApiKey
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(boltApiKeysBucket)
+		value := keys.Get([]byte(id))
+		if value == nil {
+			return ErrKeyNotFound
+		}
+
+		var err error
+		key, err = decodeApiKey(string(value))
+		if err != nil {
+			return err
+		}
+		revokeApiKey(&key, time.Now())
+
+		encoded, err := encodeApiKey(key)
+		if err != nil {
+			return err
+		}
+		return keys.Put([]byte(id), []byte(encoded))
+	})
+	if err != nil {
+		return ApiKey{}, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltDomainsBucket = []byte("domains")
+	// boltDomainSettingsBucket holds the name of the default domain under
+	// boltDefaultDomainKey, and the one of the legacy domain under
+	// boltLegacyDomainKey, see Domain.Legacy.
+	boltDomainSettingsBucket = []byte("domain_settings")
+	boltDefaultDomainKey     = []byte("default")
+	boltLegacyDomainKey      = []byte("legacy")
+)
+
+type BoltDomainRepository struct {
+	db *bolt.DB
+}
+
+func NewBoltDomainRepository(db *bolt.DB) (DomainContract, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltDomainsBucket, boltDomainSettingsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bolt buckets: %w", err)
+	}
+
+	return &BoltDomainRepository{db: db}, nil
+}
+
+func (s *BoltDomainRepository) SaveDomain(ctx context.Context, domain Domain) error {
+	value, err := encodeDomain(domain)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		domains := tx.Bucket(boltDomainsBucket)
+		if domains.Get([]byte(domain.Name)) != nil {
+			return fmt.Errorf("failed to save domain %q: %w", domain.Name, ErrDomainExists)
+		}
+		if err := domains.Put([]byte(domain.Name), []byte(value)); err != nil {
+			return err
+		}
+
+		settings := tx.Bucket(boltDomainSettingsBucket)
+		if settings.Get(boltLegacyDomainKey) == nil {
+			if err := settings.Put(boltLegacyDomainKey, []byte(domain.Name)); err != nil {
+				return err
+			}
+		}
+		if settings.Get(boltDefaultDomainKey) != nil {
+			return nil
+		}
+		return settings.Put(boltDefaultDomainKey, []byte(domain.Name))
+	})
+	if err != nil {
+		return fmt.Errorf("error setting on bolt: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltDomainRepository) ListDomains(ctx context.Context) ([]Domain, error) {
+	domains := []Domain{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		settings := tx.Bucket(boltDomainSettingsBucket)
+		defaultName := string(settings.Get(boltDefaultDomainKey))
+		legacyName := string(settings.Get(boltLegacyDomainKey))
+		return tx.Bucket(boltDomainsBucket).ForEach(func(_, value []byte) error {
+			domain, err := decodeDomain(string(value), defaultName, legacyName)
+			if err != nil {
+				return err
+			}
+			domains = append(domains, domain)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+	sortDomains(domains)
+
+	return domains, nil
+}
+
+func (s *BoltDomainRepository) DeleteDomain(ctx context.Context, name string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		domains := tx.Bucket(boltDomainsBucket)
+		if domains.Get([]byte(name)) == nil {
+			return ErrDomainNotFound
+		}
+		if err := domains.Delete([]byte(name)); err != nil {
+			return err
+		}
+
+		settings := tx.Bucket(boltDomainSettingsBucket)
+		if string(settings.Get(boltDefaultDomainKey)) != name {
+			return nil
+		}
+		return settings.Delete(boltDefaultDomainKey)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete domain: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltDomainRepository) SetDefaultDomain(ctx context.Context, name string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltDomainsBucket).Get([]byte(name)) == nil {
+			return ErrDomainNotFound
+		}
+		return tx.Bucket(boltDomainSettingsBucket).Put(boltDefaultDomainKey, []byte(name))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set default domain: %w", err)
+	}
+
+	return nil
+}
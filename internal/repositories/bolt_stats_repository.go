@@ -0,0 +1,221 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltStatsBucket = []byte("stats")
+
+	boltClicksKey       = []byte("clicks")
+	boltVisitorsBucket  = []byte("visitors")
+	boltDailyBucket     = []byte("daily")
+	boltHourlyBucket    = []byte("hourly")
+	boltReferrersBucket = []byte("referrers")
+	boltCountriesBucket = []byte("countries")
+	boltVariantsBucket  = []byte("variants")
+)
+
+// BoltStatsRepository keeps the click stats in the bbolt file, one nested
+// bucket per code. Unique visitors are counted exactly.
+type BoltStatsRepository struct {
+	db *bolt.DB
+}
+
+func NewBoltStatsRepository(db *bolt.DB) (StatsContract, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltStatsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bolt buckets: %w", err)
+	}
+
+	return &BoltStatsRepository{db: db}, nil
+}
+
+func (s *BoltStatsRepository) RecordClicks(ctx context.Context, clicks []Click) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(boltStatsBucket)
+		for _, click := range clicks {
+			stats, err := root.CreateBucketIfNotExists([]byte(click.Code))
+			if err != nil {
+				return err
+			}
+
+			ts := click.Timestamp.UTC()
+			if err := boltIncr(stats, boltClicksKey); err != nil {
+				return err
+			}
+			if err := boltIncrIn(stats, boltDailyBucket, ts.Format(dayLayout)); err != nil {
+				return err
+			}
+			if err := boltIncrIn(stats, boltHourlyBucket, ts.Format(hourLayout)); err != nil {
+				return err
+			}
+			if err := boltPruneHours(stats.Bucket(boltHourlyBucket), time.Now()); err != nil {
+				return err
+			}
+			if click.VisitorID != "" {
+				visitors, err := stats.CreateBucketIfNotExists(boltVisitorsBucket)
+				if err != nil {
+					return err
+				}
+				if err := visitors.Put([]byte(click.VisitorID), []byte{}); err != nil {
+					return err
+				}
+			}
+			if click.Referrer != "" {
+				if err := boltIncrReferrer(stats, click.Referrer); err != nil {
+					return err
+				}
+			}
+			if click.Country != "" {
+				if err := boltIncrIn(stats, boltCountriesBucket, click.Country); err != nil {
+					return err
+				}
+			}
+			if click.Variant != "" {
+				if err := boltIncrIn(stats, boltVariantsBucket, click.Variant); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record clicks: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltStatsRepository) GetStats(ctx context.Context, code string) (URLStats, error) {
+	stats := URLStats{
+		Daily:     map[string]int64{},
+		Hourly:    map[string]int64{},
+		Referrers: map[string]int64{},
+		Countries: map[string]int64{},
+		Variants:  map[string]int64{},
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltStatsBucket).Bucket([]byte(code))
+		if bucket == nil {
+			return nil
+		}
+
+		if clicks := bucket.Get(boltClicksKey); clicks != nil {
+			stats.TotalClicks = int64(btoi(clicks))
+		}
+		if visitors := bucket.Bucket(boltVisitorsBucket); visitors != nil {
+			stats.UniqueVisitors = int64(visitors.Stats().KeyN)
+		}
+
+		buckets := []struct {
+			name []byte
+			dest map[string]int64
+		}{
+			{boltDailyBucket, stats.Daily},
+			{boltHourlyBucket, stats.Hourly},
+			{boltReferrersBucket, stats.Referrers},
+			{boltCountriesBucket, stats.Countries},
+			{boltVariantsBucket, stats.Variants},
+		}
+		for _, b := range buckets {
+			counts := bucket.Bucket(b.name)
+			if counts == nil {
+				continue
+			}
+			err := counts.ForEach(func(key, count []byte) error {
+				b.dest[string(key)] = int64(btoi(count))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		pruneHours(stats.Hourly, time.Now())
+		return nil
+	})
+	if err != nil {
+		return URLStats{}, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+func boltIncr(bucket *bolt.Bucket, key []byte) error {
+	var count uint64
+	if value := bucket.Get(key); value != nil {
+		count = btoi(value)
+	}
+	return bucket.Put(key, itob(count+1))
+}
+
+func boltIncrIn(parent *bolt.Bucket, name []byte, key string) error {
+	bucket, err := parent.CreateBucketIfNotExists(name)
+	if err != nil {
+		return err
+	}
+	return boltIncr(bucket, []byte(key))
+}
+
+// boltIncrReferrer counts the click of the referrer, or of otherReferrer
+// once the code has maxReferrers of them.
+func boltIncrReferrer(stats *bolt.Bucket, referrer string) error {
+	referrers, err := stats.CreateBucketIfNotExists(boltReferrersBucket)
+	if err != nil {
+		return err
+	}
+	// the sequence counts the referrers, bucket stats missing the ones of
+	// the ongoing transaction
+	if referrers.Get([]byte(referrer)) == nil {
+		if referrers.Sequence() >= maxReferrers {
+			referrer = otherReferrer
+		}
+		if referrers.Get([]byte(referrer)) == nil {
+			if _, err := referrers.NextSequence(); err != nil {
+				return err
+			}
+		}
+	}
+	return boltIncr(referrers, []byte(referrer))
+}
+
+// boltPruneHours deletes the hourly buckets past hourlyRetention at now.
+func boltPruneHours(hourly *bolt.Bucket, now time.Time) error {
+	cutoff := hourlyCutoff(now)
+
+	// keys are deleted after iterating, bolt cursors don't support
+	// mutating the bucket while walking it
+	var expired [][]byte
+	c := hourly.Cursor()
+	for hour, _ := c.First(); hour != nil && string(hour) < cutoff; hour, _ = c.Next() {
+		expired = append(expired, hour)
+	}
+	for _, hour := range expired {
+		if err := hourly.Delete(hour); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltDeleteStats removes the stats of the code, so a link saved later
+// under it starts from none.
+func boltDeleteStats(tx *bolt.Tx, code []byte) error {
+	root := tx.Bucket(boltStatsBucket)
+	if root == nil {
+		return nil
+	}
+	if err := root.DeleteBucket(code); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return err
+	}
+	return nil
+}
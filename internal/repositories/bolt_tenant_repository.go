@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *BoltUrlRepository) SaveTenant(ctx context.Context, tenant Tenant) error {
+	value, err := encodeTenant(tenant)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		tenants := tx.Bucket(boltTenantsBucket)
+		if tenants.Get([]byte(tenant.ID)) != nil {
+			return fmt.Errorf("failed to save tenant %q: %w", tenant.ID, ErrTenantExists)
+		}
+		return tenants.Put([]byte(tenant.ID), []byte(value))
+	})
+	if err != nil {
+		return fmt.Errorf("error setting on bolt: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltUrlRepository) GetTenant(ctx context.Context, id string) (Tenant, error) {
+	var tenant Tenant
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		tenant, err = boltGetTenant(tx, id)
+		return err
+	})
+	if err != nil {
+		return Tenant{}, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	return tenant, nil
+}
+
+func (s *BoltUrlRepository) ListTenants(ctx context.Context) ([]Tenant, error) {
+	tenants := []Tenant{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltTenantsBucket).ForEach(func(id, _ []byte) error {
+			tenant, err := boltGetTenant(tx, string(id))
+			if err != nil {
+				return err
+			}
+			tenants = append(tenants, tenant)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	// bolt keys are already sorted, ids included
+	return tenants, nil
+}
+
+func (s *BoltUrlRepository) UpdateTenant(ctx context.Context, id string, update func(tenant *Tenant) error) (Tenant, error) {
+	var tenant Tenant
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		current, err := boltGetTenant(tx, id)
+		if err != nil {
+			return err
+		}
+
+		tenant, err = applyTenantUpdate(current, update, time.Now())
+		if err != nil {
+			return err
+		}
+
+		value, err := encodeTenant(tenant)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(boltTenantsBucket).Put([]byte(id), []byte(value))
+	})
+	if err != nil {
+		return Tenant{}, fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	return tenant, nil
+}
+
+// boltGetTenant reads the tenant with its count of links, returning
+// ErrTenantNotFound if missing.
+func boltGetTenant(tx *bolt.Tx, id string) (Tenant, error) {
+	value := tx.Bucket(boltTenantsBucket).Get([]byte(id))
+	if value == nil {
+		return Tenant{}, ErrTenantNotFound
+	}
+
+	tenant, err := decodeTenant(string(value))
+	if err != nil {
+		return Tenant{}, err
+	}
+
+	tenant.Links = boltTenantLinks(tx, id)
+	return tenant, nil
+}
@@ -0,0 +1,644 @@
+package repositories
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/codegen"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltUrlsBucket   = []byte("urls")
+	boltExpiryBucket = []byte("expiry")
+	boltClicksBucket = []byte("clicks")
+	// boltTargetsBucket maps the normalized target URLs, scoped by tenant
+	// and host, to a code, see FindURL.
+	boltTargetsBucket = []byte("targets")
+	// boltUsesBucket counts the clicks taken by links with a click limit,
+	// see ConsumeClick.
+	boltUsesBucket = []byte("uses")
+	// boltTenantsBucket holds the tenants, and boltTenantLinksBucket one
+	// nested bucket per tenant with the codes of its links, its sequence
+	// counting them.
+	boltTenantsBucket     = []byte("tenants")
+	boltTenantLinksBucket = []byte("tenant_links")
+)
+
+// BoltUrlRepository stores the links in an embedded bbolt file, meant for
+// single node deploys where running redis isn't worth it.
+type BoltUrlRepository struct {
+	db    *bolt.DB
+	codes codegen.Generator
+}
+
+func NewBoltUrlRepository(db *bolt.DB, codes codegen.Generator) (UrlContract, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltUrlsBucket, boltExpiryBucket, boltClicksBucket, boltTargetsBucket, boltUsesBucket, boltTenantsBucket, boltTenantLinksBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bolt buckets: %w", err)
+	}
+
+	return &BoltUrlRepository{db: db, codes: codes}, nil
+}
+
+// boltCounter backs the counter code strategy with the sequence of the
+// urls bucket.
+func boltCounter(db *bolt.DB) codegen.NextFunc {
+	return func(ctx context.Context) (uint64, error) {
+		var n uint64
+		err := db.Update(func(tx *bolt.Tx) error {
+			var err error
+			n, err = tx.Bucket(boltUrlsBucket).NextSequence()
+			return err
+		})
+		return n, err
+	}
+}
+
+// SaveShortenedURL generates the codes out of the write transaction, as
+// the counter strategy takes one of its own.
+func (s *BoltUrlRepository) SaveShortenedURL(ctx context.Context, link Link) (string, error) {
+	return saveGenerated(ctx, s.codes, link, func(code string) error {
+		return s.saveWithCode(ctx, code, link, hostlessKey(link.Host, code))
+	})
+}
+
+func (s *BoltUrlRepository) SaveURLWithCode(ctx context.Context, code string, link Link) error {
+	return s.saveWithCode(ctx, code, link, "")
+}
+
+// saveWithCode saves the link unless the code, or the hostless key when
+// set, is taken.
+func (s *BoltUrlRepository) saveWithCode(ctx context.Context, code string, link Link, hostless string) error {
+	key := LinkKey(link.Host, code)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if boltCodeTaken(tx, key, hostless) {
+			return fmt.Errorf("failed to save code %q: %w", code, ErrCodeTaken)
+		}
+		if err := boltCheckQuota(tx, link.Tenant); err != nil {
+			return fmt.Errorf("failed to save code %q: %w", code, err)
+		}
+		return boltSave(tx, key, prepareNewLink(link, time.Now()))
+	})
+	if err != nil {
+		return fmt.Errorf("error setting on bolt: %w", err)
+	}
+
+	return nil
+}
+
+// SaveURLs saves the whole batch in one transaction per attempt, only
+// failing it as a whole when bolt itself fails. Generated codes that
+// collide are retried in the next attempt.
+func (s *BoltUrlRepository) SaveURLs(ctx context.Context, links []Link) ([]SaveResult, error) {
+	now := time.Now()
+	results := make([]SaveResult, len(links))
+	codes := make([]string, len(links))
+
+	pending := make([]int, len(links))
+	for i := range links {
+		pending[i] = i
+	}
+
+	for attempt := 0; attempt < codeAttempts && len(pending) > 0; attempt++ {
+		generated := make([]int, 0, len(pending))
+		for _, i := range pending {
+			codes[i] = links[i].Code
+			if codes[i] == "" {
+				var err error
+				if codes[i], err = s.codes.Generate(ctx, links[i].URL, attempt); err != nil {
+					results[i] = SaveResult{Err: err}
+					continue
+				}
+			}
+			generated = append(generated, i)
+		}
+
+		var retry []int
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			retry = nil
+			for _, i := range generated {
+				key := LinkKey(links[i].Host, codes[i])
+				var hostless string
+				if links[i].Code == "" {
+					hostless = hostlessKey(links[i].Host, codes[i])
+				}
+				if boltCodeTaken(tx, key, hostless) {
+					if links[i].Code != "" {
+						results[i] = SaveResult{Err: fmt.Errorf("failed to save code %q: %w", codes[i], ErrCodeTaken)}
+					} else {
+						retry = append(retry, i)
+					}
+					continue
+				}
+				if err := boltCheckQuota(tx, links[i].Tenant); err != nil {
+					if !errors.Is(err, ErrQuotaExceeded) {
+						return err
+					}
+					results[i] = SaveResult{Err: fmt.Errorf("failed to save code %q: %w", codes[i], err)}
+					continue
+				}
+				if err := boltSave(tx, key, prepareNewLink(links[i], now)); err != nil {
+					return err
+				}
+				results[i] = SaveResult{Code: codes[i]}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error setting on bolt: %w", err)
+		}
+		pending = retry
+	}
+
+	for _, i := range pending {
+		results[i] = SaveResult{Err: fmt.Errorf("failed to generate a free code: %w", ErrCodeTaken)}
+	}
+
+	return results, nil
+}
+
+// boltCodeTaken reports whether the key, or the hostless key when set, is
+// taken.
+func boltCodeTaken(tx *bolt.Tx, key, hostless string) bool {
+	urls := tx.Bucket(boltUrlsBucket)
+	return urls.Get([]byte(key)) != nil || hostless != "" && urls.Get([]byte(hostless)) != nil
+}
+
+// boltSave writes the link along with its expiry and target index entries,
+// leaving the clicks counter untouched.
+func boltSave(tx *bolt.Tx, code string, link Link) error {
+	value, err := encodeLink(link)
+	if err != nil {
+		return err
+	}
+
+	urls := tx.Bucket(boltUrlsBucket)
+	if previous := urls.Get([]byte(code)); previous != nil {
+		previousLink, err := decodeLink(code, string(previous))
+		if err != nil {
+			return err
+		}
+		if previousLink.targetKey() != link.targetKey() {
+			if err := boltUnindexTarget(tx, code, previousLink); err != nil {
+				return err
+			}
+		}
+	} else if err := boltIndexTenant(tx, code, link); err != nil {
+		return err
+	}
+
+	if err := urls.Put([]byte(code), []byte(value)); err != nil {
+		return err
+	}
+
+	if err := boltIndexTarget(tx, code, link); err != nil {
+		return err
+	}
+
+	expiry := tx.Bucket(boltExpiryBucket)
+	if link.ExpiresAt == nil {
+		return expiry.Delete([]byte(code))
+	}
+	return expiry.Put([]byte(code), itob(uint64(link.ExpiresAt.Unix())))
+}
+
+// boltIndexTarget points the target to the code, unless it already points
+// to another existing code, keeping the first link saved.
+func boltIndexTarget(tx *bolt.Tx, code string, link Link) error {
+	key := link.targetKey()
+	if key == "" {
+		return nil
+	}
+
+	targets := tx.Bucket(boltTargetsBucket)
+	current := targets.Get([]byte(key))
+	if current != nil && string(current) != code && tx.Bucket(boltUrlsBucket).Get(current) != nil {
+		return nil
+	}
+	return targets.Put([]byte(key), []byte(code))
+}
+
+func boltUnindexTarget(tx *bolt.Tx, code string, link Link) error {
+	key := link.targetKey()
+	if key == "" {
+		return nil
+	}
+
+	targets := tx.Bucket(boltTargetsBucket)
+	if string(targets.Get([]byte(key))) != code {
+		return nil
+	}
+	return targets.Delete([]byte(key))
+}
+
+// boltIndexTenant adds the code of a new link to its tenant bucket.
+func boltIndexTenant(tx *bolt.Tx, code string, link Link) error {
+	if link.Tenant == "" {
+		return nil
+	}
+
+	links, err := tx.Bucket(boltTenantLinksBucket).CreateBucketIfNotExists([]byte(link.Tenant))
+	if err != nil {
+		return err
+	}
+	if err := links.Put([]byte(code), []byte{}); err != nil {
+		return err
+	}
+	return links.SetSequence(links.Sequence() + 1)
+}
+
+func boltUnindexTenant(tx *bolt.Tx, code string, link Link) error {
+	if link.Tenant == "" {
+		return nil
+	}
+
+	links := tx.Bucket(boltTenantLinksBucket).Bucket([]byte(link.Tenant))
+	if links == nil || links.Get([]byte(code)) == nil {
+		return nil
+	}
+	if err := links.Delete([]byte(code)); err != nil {
+		return err
+	}
+	return links.SetSequence(links.Sequence() - 1)
+}
+
+// boltTenantLinks counts the links of the tenant.
+func boltTenantLinks(tx *bolt.Tx, tenant string) int64 {
+	if links := tx.Bucket(boltTenantLinksBucket).Bucket([]byte(tenant)); links != nil {
+		return int64(links.Sequence())
+	}
+	return 0
+}
+
+// boltCheckQuota returns ErrQuotaExceeded when the tenant already holds
+// its max links.
+func boltCheckQuota(tx *bolt.Tx, tenant string) error {
+	if tenant == "" {
+		return nil
+	}
+
+	value := tx.Bucket(boltTenantsBucket).Get([]byte(tenant))
+	if value == nil {
+		return nil
+	}
+	record, err := decodeTenant(string(value))
+	if err != nil {
+		return err
+	}
+
+	if record.MaxLinks > 0 && boltTenantLinks(tx, tenant) >= record.MaxLinks {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// boltGet reads the link with its clicks, returning ErrNotFound if missing.
+func boltGet(tx *bolt.Tx, code string) (Link, error) {
+	value := tx.Bucket(boltUrlsBucket).Get([]byte(code))
+	if value == nil {
+		return Link{}, ErrNotFound
+	}
+
+	link, err := decodeLink(code, string(value))
+	if err != nil {
+		return Link{}, err
+	}
+
+	if clicks := tx.Bucket(boltClicksBucket).Get([]byte(code)); clicks != nil {
+		link.Clicks = int64(btoi(clicks))
+	}
+
+	return link, nil
+}
+
+func boltDelete(tx *bolt.Tx, code []byte) error {
+	if value := tx.Bucket(boltUrlsBucket).Get(code); value != nil {
+		link, err := decodeLink(string(code), string(value))
+		if err != nil {
+			return err
+		}
+		if err := boltUnindexTarget(tx, string(code), link); err != nil {
+			return err
+		}
+		if err := boltUnindexTenant(tx, string(code), link); err != nil {
+			return err
+		}
+	}
+
+	for _, bucket := range [][]byte{boltUrlsBucket, boltExpiryBucket, boltClicksBucket, boltUsesBucket} {
+		if err := tx.Bucket(bucket).Delete(code); err != nil {
+			return err
+		}
+	}
+	return boltDeleteStats(tx, code)
+}
+
+func (s *BoltUrlRepository) FindURL(ctx context.Context, tenant, host, target string) (Link, error) {
+	var code string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltTargetsBucket).Get([]byte(targetKey(tenant, host, target)))
+		if value == nil {
+			return ErrNotFound
+		}
+		code = string(value)
+		return nil
+	})
+	if err != nil {
+		return Link{}, fmt.Errorf("failed to find url: %w", err)
+	}
+
+	return foundLink(s.GetURL(ctx, code))
+}
+
+func (s *BoltUrlRepository) GetURL(ctx context.Context, code string) (Link, error) {
+	var link Link
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		link, err = boltGet(tx, code)
+		if err != nil {
+			return err
+		}
+
+		if link.Expired(time.Now()) {
+			return ErrExpired
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrExpired) {
+			return link, fmt.Errorf("failed to get url: %w", err)
+		}
+		return Link{}, fmt.Errorf("failed to get url: %w", err)
+	}
+
+	return link, nil
+}
+
+// ListURL walks the urls bucket, or the tenant one, in key order, using
+// the last code of the page as cursor. Sorting by creation date needs
+// every link in memory, as there is no index by creation date.
+func (s *BoltUrlRepository) ListURL(ctx context.Context, opts ListOptions) (LinkPage, error) {
+	opts = opts.withDefaults()
+
+	var page LinkPage
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// the tenant bucket holds the codes of just the tenant links
+		index := tx.Bucket(boltUrlsBucket)
+		if opts.Tenant != "" {
+			index = tx.Bucket(boltTenantLinksBucket).Bucket([]byte(opts.Tenant))
+			if index == nil {
+				page.Links = []Link{}
+				return nil
+			}
+		}
+
+		if opts.Sort != "" {
+			links := []Link{}
+			err := index.ForEach(func(code, _ []byte) error {
+				link, err := boltGet(tx, string(code))
+				if err != nil {
+					return err
+				}
+				links = append(links, link)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			page, err = pageLinks(links, opts)
+			return err
+		}
+
+		page.Links = []Link{}
+		cursor := index.Cursor()
+		code, _ := cursor.First()
+		if opts.Cursor != "" {
+			code, _ = cursor.Seek([]byte(opts.Cursor))
+			if code != nil && string(code) == opts.Cursor {
+				code, _ = cursor.Next()
+			}
+		}
+
+		// the last scanned code, not the last matching one, is the next
+		// cursor so a page without matches still moves forward
+		var last string
+		for scanned := 0; code != nil; code, _ = cursor.Next() {
+			if len(page.Links) >= opts.Limit || scanned >= opts.scanBudget() {
+				page.NextCursor = last
+				return nil
+			}
+			scanned++
+			last = string(code)
+
+			link, err := boltGet(tx, last)
+			if err != nil {
+				return err
+			}
+			if opts.Matches(link) {
+				page.Links = append(page.Links, link)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return LinkPage{}, fmt.Errorf("failed to list urls: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *BoltUrlRepository) DeleteURL(ctx context.Context, code string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltUrlsBucket).Get([]byte(code)) == nil {
+			return ErrNotFound
+		}
+		return boltDelete(tx, []byte(code))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete url: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltUrlRepository) UpdateURL(ctx context.Context, code string, update func(link *Link) error) (Link, error) {
+	var link Link
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		link, err = boltGet(tx, code)
+		if err != nil {
+			return err
+		}
+
+		previous := link
+		if err := update(&link); err != nil {
+			return err
+		}
+		link.Code, link.Host = previous.Code, previous.Host
+		link.Clicks = previous.Clicks
+		link.Tenant = previous.Tenant
+		link.UpdatedAt = time.Now()
+
+		return boltSave(tx, code, link)
+	})
+	if err != nil {
+		return Link{}, fmt.Errorf("failed to update url: %w", err)
+	}
+
+	return link, nil
+}
+
+func (s *BoltUrlRepository) IncrementClicks(ctx context.Context, clicks map[string]int64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		urls := tx.Bucket(boltUrlsBucket)
+		counters := tx.Bucket(boltClicksBucket)
+		for code, count := range clicks {
+			if urls.Get([]byte(code)) == nil {
+				continue
+			}
+
+			var current uint64
+			if value := counters.Get([]byte(code)); value != nil {
+				current = btoi(value)
+			}
+			if err := counters.Put([]byte(code), itob(current+uint64(count))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to increment clicks: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltUrlRepository) ConsumeClick(ctx context.Context, code string, max int64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltUrlsBucket).Get([]byte(code)) == nil {
+			return ErrNotFound
+		}
+
+		uses := tx.Bucket(boltUsesBucket)
+		var used uint64
+		if value := uses.Get([]byte(code)); value != nil {
+			used = btoi(value)
+		}
+		if int64(used) >= max {
+			return ErrExhausted
+		}
+		return uses.Put([]byte(code), itob(used+1))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to consume click: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltUrlRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var expired [][]byte
+		err := tx.Bucket(boltExpiryBucket).ForEach(func(code, expiresAt []byte) error {
+			if int64(btoi(expiresAt)) <= before.Unix() {
+				expired = append(expired, code)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// keys are deleted after iterating, bolt cursors don't support
+		// mutating the bucket while walking it
+		for _, code := range expired {
+			if err := boltDelete(tx, code); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired urls: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// MigrateLegacyURLs rewrites the bare URL strings as links, carrying over
+// the expiration kept in the expiry bucket.
+func (s *BoltUrlRepository) MigrateLegacyURLs(ctx context.Context) (int64, error) {
+	var migrated int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		urls := tx.Bucket(boltUrlsBucket)
+		expiry := tx.Bucket(boltExpiryBucket)
+
+		legacy := map[string]Link{}
+		var current []Link
+		err := urls.ForEach(func(code, value []byte) error {
+			if !isLegacyValue(string(value)) {
+				// links saved before the target index existed
+				link, err := decodeLink(string(code), string(value))
+				if err != nil {
+					return err
+				}
+				current = append(current, link)
+				return nil
+			}
+
+			link := Link{URL: string(value)}
+			if expiresAt := expiry.Get(code); expiresAt != nil {
+				t := time.Unix(int64(btoi(expiresAt)), 0).UTC()
+				link.ExpiresAt = &t
+			}
+			legacy[string(code)] = link
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for code, link := range legacy {
+			if err := boltSave(tx, code, link); err != nil {
+				return err
+			}
+			migrated++
+		}
+		for _, link := range current {
+			if err := boltIndexTarget(tx, link.Key(), link); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate legacy urls: %w", err)
+	}
+
+	return migrated, nil
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func btoi(b []byte) uint64 {
+	return binary.BigEndian.Uint64(b)
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CachedDomainRepository keeps the domain list in memory, as every redirect
+// looks it up. Writes made through it clear the cache at once, the ones of
+// other instances show up once the ttl is over.
+type CachedDomainRepository struct {
+	next DomainContract
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.RWMutex
+	domains []Domain
+	loaded  time.Time
+	// generation counts the invalidations, so a list loaded while a write
+	// was in flight isn't cached over it.
+	generation uint64
+}
+
+func NewCachedDomainRepository(next DomainContract, ttl time.Duration) DomainContract {
+	return &CachedDomainRepository{next: next, ttl: ttl, now: time.Now}
+}
+
+func (s *CachedDomainRepository) SaveDomain(ctx context.Context, domain Domain) error {
+	defer s.invalidate()
+	return s.next.SaveDomain(ctx, domain)
+}
+
+func (s *CachedDomainRepository) ListDomains(ctx context.Context) ([]Domain, error) {
+	s.mu.RLock()
+	domains, loaded, generation := s.domains, s.loaded, s.generation
+	s.mu.RUnlock()
+	if !loaded.IsZero() && s.now().Sub(loaded) < s.ttl {
+		return slices.Clone(domains), nil
+	}
+
+	domains, err := s.next.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.generation == generation {
+		s.domains, s.loaded = domains, s.now()
+	}
+	s.mu.Unlock()
+
+	return slices.Clone(domains), nil
+}
+
+func (s *CachedDomainRepository) DeleteDomain(ctx context.Context, name string) error {
+	defer s.invalidate()
+	return s.next.DeleteDomain(ctx, name)
+}
+
+func (s *CachedDomainRepository) SetDefaultDomain(ctx context.Context, name string) error {
+	defer s.invalidate()
+	return s.next.SetDefaultDomain(ctx, name)
+}
+
+func (s *CachedDomainRepository) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.domains, s.loaded = nil, time.Time{}
+	s.generation++
+}
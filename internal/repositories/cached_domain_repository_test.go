@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingDomainRepository counts the lists reaching the backend.
+type countingDomainRepository struct {
+	DomainContract
+	lists int
+}
+
+func (s *countingDomainRepository) ListDomains(ctx context.Context) ([]Domain, error) {
+	s.lists++
+	return s.DomainContract.ListDomains(ctx)
+}
+
+func TestCachedDomainRepository(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingDomainRepository{DomainContract: NewMemoryDomainRepository()}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cached := NewCachedDomainRepository(backend, time.Minute).(*CachedDomainRepository)
+	cached.now = func() time.Time { return now }
+
+	list := func() []Domain {
+		t.Helper()
+		domains, err := cached.ListDomains(ctx)
+		require.NoError(t, err)
+		return domains
+	}
+
+	assert.Empty(t, list())
+	assert.Empty(t, list())
+	assert.Equal(t, 1, backend.lists, "an empty list is cached too")
+
+	// writes through the cache show up at once
+	require.NoError(t, cached.SaveDomain(ctx, Domain{Name: "go.acme.io"}))
+	require.NoError(t, cached.SaveDomain(ctx, Domain{Name: "acme.link"}))
+	assert.Len(t, list(), 2)
+	require.NoError(t, cached.SetDefaultDomain(ctx, "acme.link"))
+	assert.True(t, list()[0].Default)
+	require.NoError(t, cached.DeleteDomain(ctx, "acme.link"))
+	assert.Len(t, list(), 1)
+	lists := backend.lists
+	list()
+	assert.Equal(t, lists, backend.lists)
+
+	// the ones of other instances once the ttl is over
+	require.NoError(t, backend.SaveDomain(ctx, Domain{Name: "acme.dev"}))
+	assert.Len(t, list(), 1)
+	now = now.Add(time.Minute)
+	assert.Len(t, list(), 2)
+}
@@ -0,0 +1,1297 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"url-shortener/internal/codegen"
+	"url-shortener/internal/split"
+	"url-shortener/internal/targeting"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestStorages returns one fresh storage per backend, every backend must
+// pass the same conformance suites.
+func newTestStorages(t *testing.T) map[string]*Storage {
+	t.Helper()
+	return newTestStoragesWithCodes(t, codegen.Options{})
+}
+
+func newTestStoragesWithCodes(t *testing.T, codes codegen.Options) map[string]*Storage {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	storages := map[string]*Storage{}
+	for _, opts := range []StorageOptions{
+		{Backend: BackendRedis, Redis: &redis.Options{Addr: mr.Addr()}, Codes: codes},
+		{Backend: BackendMemory, Codes: codes},
+		{Backend: BackendBolt, BoltPath: filepath.Join(t.TempDir(), "test.db"), Codes: codes},
+	} {
+		storage, err := NewStorage(opts)
+		require.NoError(t, err)
+		t.Cleanup(func() { storage.Close() })
+		storages[opts.Backend] = storage
+	}
+
+	return storages
+}
+
+func TestUrlContractConformance(t *testing.T) {
+	suite := map[string]func(t *testing.T, db UrlContract){
+		"save and get":     testSaveAndGet,
+		"save with code":   testSaveWithCode,
+		"save batch":       testSaveURLs,
+		"not found":        testNotFound,
+		"find":             testFindURL,
+		"update":           testUpdate,
+		"update aborted":   testUpdateAborted,
+		"delete":           testDelete,
+		"expiration":       testExpiration,
+		"delete expired":   testDeleteExpired,
+		"list":             testList,
+		"list pages":       testListPages,
+		"list filters":     testListFilters,
+		"list sorted":      testListSorted,
+		"list writes":      testListPagesWhileWriting,
+		"increment clicks": testIncrementClicks,
+		"consume click":    testConsumeClick,
+		"hosts":            testHosts,
+	}
+
+	for name, test := range suite {
+		t.Run(name, func(t *testing.T) {
+			for backend, storage := range newTestStorages(t) {
+				t.Run(backend, func(t *testing.T) {
+					test(t, storage.Urls)
+				})
+			}
+		})
+	}
+}
+
+func testSaveAndGet(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Second)
+
+	code, err := db.SaveShortenedURL(ctx, Link{
+		URL:     "https://example.com",
+		Title:   "Example",
+		Tags:    []string{"docs", "launch"},
+		Creator: "marketing",
+
+		RedirectStatus: http.StatusFound,
+		NoCache:        true,
+		PasswordHash:   "$2a$10$hash",
+		MaxClicks:      10,
+		Rules: []targeting.Rule{
+			{URL: "https://apps.apple.com/app", Platforms: []string{"ios"}},
+		},
+		Variants: []split.Variant{
+			{Name: "a", URL: "https://example.com/a", Weight: 70},
+			{Name: "b", URL: "https://example.com/b", Weight: 30},
+		},
+		Query:        map[string]string{"utm_source": "news"},
+		ForwardQuery: true,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, code)
+
+	link, err := db.GetURL(ctx, code)
+	require.NoError(t, err)
+	assert.Equal(t, code, link.Code)
+	assert.Equal(t, "https://example.com", link.URL)
+	assert.Equal(t, "Example", link.Title)
+	assert.Equal(t, []string{"docs", "launch"}, link.Tags)
+	assert.Equal(t, "marketing", link.Creator)
+	assert.True(t, link.CreatedAt.After(before))
+	assert.True(t, link.CreatedAt.Equal(link.UpdatedAt))
+	assert.Nil(t, link.ExpiresAt)
+	assert.False(t, link.Disabled)
+	assert.Equal(t, int64(0), link.Clicks)
+	assert.Equal(t, http.StatusFound, link.RedirectStatus)
+	assert.True(t, link.NoCache)
+	assert.Equal(t, "$2a$10$hash", link.PasswordHash)
+	assert.Equal(t, int64(10), link.MaxClicks)
+	assert.Equal(t, []targeting.Rule{{URL: "https://apps.apple.com/app", Platforms: []string{"ios"}}}, link.Rules)
+	assert.Equal(t, []split.Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 70},
+		{Name: "b", URL: "https://example.com/b", Weight: 30},
+	}, link.Variants)
+	assert.Equal(t, map[string]string{"utm_source": "news"}, link.Query)
+	assert.True(t, link.ForwardQuery)
+}
+
+func testSaveWithCode(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "launch", Link{URL: "https://example.com"}))
+
+	err := db.SaveURLWithCode(ctx, "launch", Link{URL: "https://other.com"})
+	assert.ErrorIs(t, err, ErrCodeTaken)
+
+	link, err := db.GetURL(ctx, "launch")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", link.URL)
+}
+
+func testSaveURLs(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "taken", Link{URL: "https://taken.com"}))
+
+	results, err := db.SaveURLs(ctx, []Link{
+		{URL: "https://generated.com", Title: "Generated"},
+		{Code: "custom", URL: "https://custom.com", ExpiresAt: &expiresAt},
+		{Code: "taken", URL: "https://other.com"},
+		{Code: "custom", URL: "https://duplicate.com"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.NoError(t, results[0].Err)
+	assert.NotEmpty(t, results[0].Code)
+	require.NoError(t, results[1].Err)
+	assert.Equal(t, "custom", results[1].Code)
+	assert.ErrorIs(t, results[2].Err, ErrCodeTaken)
+	assert.ErrorIs(t, results[3].Err, ErrCodeTaken)
+
+	link, err := db.GetURL(ctx, results[0].Code)
+	require.NoError(t, err)
+	assert.Equal(t, "https://generated.com", link.URL)
+	assert.Equal(t, "Generated", link.Title)
+	assert.False(t, link.CreatedAt.IsZero())
+
+	link, err = db.GetURL(ctx, "custom")
+	require.NoError(t, err)
+	assert.Equal(t, "https://custom.com", link.URL)
+	require.NotNil(t, link.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*link.ExpiresAt))
+
+	link, err = db.GetURL(ctx, "taken")
+	require.NoError(t, err)
+	assert.Equal(t, "https://taken.com", link.URL)
+
+	page, err := db.ListURL(ctx, ListOptions{Sort: SortCreatedAsc})
+	require.NoError(t, err)
+	assert.Len(t, page.Links, 3)
+}
+
+func testHosts(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "launch", Link{URL: "https://example.com"}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "launch", Link{URL: "https://acme.com", Host: "go.acme.io"}))
+	err := db.SaveURLWithCode(ctx, "launch", Link{URL: "https://other.com", Host: "go.acme.io"})
+	assert.ErrorIs(t, err, ErrCodeTaken)
+
+	code, err := db.SaveShortenedURL(ctx, Link{URL: "https://acme.com/docs", Host: "go.acme.io"})
+	require.NoError(t, err)
+	assert.NotContains(t, code, "/")
+
+	results, err := db.SaveURLs(ctx, []Link{
+		{Code: "launch", URL: "https://acme.link", Host: "acme.link"},
+		{Code: "launch", URL: "https://duplicate.com", Host: "go.acme.io"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "launch", results[0].Code)
+	assert.ErrorIs(t, results[1].Err, ErrCodeTaken)
+
+	link, err := db.GetURL(ctx, "launch")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", link.URL)
+	assert.Empty(t, link.Host)
+
+	link, err = db.GetURL(ctx, LinkKey("go.acme.io", "launch"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://acme.com", link.URL)
+	assert.Equal(t, "launch", link.Code)
+	assert.Equal(t, "go.acme.io", link.Host)
+
+	link, err = db.GetURL(ctx, LinkKey("go.acme.io", code))
+	require.NoError(t, err)
+	assert.Equal(t, "https://acme.com/docs", link.URL)
+
+	updated, err := db.UpdateURL(ctx, LinkKey("go.acme.io", "launch"), func(link *Link) error {
+		link.Title = "Acme"
+		link.Host = "acme.link"
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.io", updated.Host)
+	assert.Equal(t, "launch", updated.Code)
+
+	page, err := db.ListURL(ctx, ListOptions{Host: "go.acme.io"})
+	require.NoError(t, err)
+	assert.Len(t, page.Links, 2)
+	for _, link := range page.Links {
+		assert.Equal(t, "go.acme.io", link.Host)
+	}
+
+	require.NoError(t, db.IncrementClicks(ctx, map[string]int64{LinkKey("acme.link", "launch"): 2}))
+	require.NoError(t, db.DeleteURL(ctx, LinkKey("go.acme.io", "launch")))
+
+	link, err = db.GetURL(ctx, "launch")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", link.URL)
+	assert.Zero(t, link.Clicks)
+
+	link, err = db.GetURL(ctx, LinkKey("acme.link", "launch"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), link.Clicks)
+
+	_, err = db.GetURL(ctx, LinkKey("go.acme.io", "launch"))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testFindURL(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Second)
+
+	_, err := db.FindURL(ctx, "", "", "https://example.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "first", Link{URL: "HTTPS://Example.com:443/path?b=2&a=1"}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "second", Link{URL: "https://example.com/path?a=1&b=2"}))
+	generated, err := db.SaveShortenedURL(ctx, Link{URL: "https://generated.com"})
+	require.NoError(t, err)
+	results, err := db.SaveURLs(ctx, []Link{{URL: "https://batch.com"}})
+	require.NoError(t, err)
+	require.NoError(t, db.SaveURLWithCode(ctx, "disabled", Link{URL: "https://disabled.com", Disabled: true}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "expired", Link{URL: "https://expired.com", ExpiresAt: &past}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "temporary", Link{URL: "https://temporary.com", RedirectStatus: http.StatusFound}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "uncached", Link{URL: "https://uncached.com", NoCache: true}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "protected", Link{URL: "https://protected.com", PasswordHash: "hash"}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "limited", Link{URL: "https://limited.com", MaxClicks: 1}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "tagged", Link{URL: "https://tagged.com", Query: map[string]string{"utm_source": "news"}}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "targeted", Link{URL: "https://targeted.com", Rules: []targeting.Rule{{URL: "https://other.com", Countries: []string{"BR"}}}}))
+
+	link, err := db.FindURL(ctx, "", "", "https://example.com/path?a=1&b=2")
+	require.NoError(t, err)
+	assert.Equal(t, "first", link.Code)
+
+	link, err = db.FindURL(ctx, "", "", "https://generated.com/")
+	require.NoError(t, err)
+	assert.Equal(t, generated, link.Code)
+
+	link, err = db.FindURL(ctx, "", "", "https://batch.com")
+	require.NoError(t, err)
+	assert.Equal(t, results[0].Code, link.Code)
+
+	_, err = db.FindURL(ctx, "", "", "https://disabled.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.FindURL(ctx, "", "", "https://protected.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.FindURL(ctx, "", "", "https://limited.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.FindURL(ctx, "", "", "https://targeted.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.FindURL(ctx, "", "", "https://tagged.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.FindURL(ctx, "", "", "https://expired.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.FindURL(ctx, "", "", "https://temporary.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.FindURL(ctx, "", "", "https://uncached.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// the index follows target changes
+	_, err = db.UpdateURL(ctx, generated, func(link *Link) error {
+		link.URL = "https://moved.com"
+		return nil
+	})
+	require.NoError(t, err)
+	_, err = db.FindURL(ctx, "", "", "https://generated.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+	link, err = db.FindURL(ctx, "", "", "https://moved.com")
+	require.NoError(t, err)
+	assert.Equal(t, generated, link.Code)
+
+	// and disabling
+	_, err = db.UpdateURL(ctx, generated, func(link *Link) error {
+		link.Disabled = true
+		return nil
+	})
+	require.NoError(t, err)
+	_, err = db.FindURL(ctx, "", "", "https://moved.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// and deletes
+	require.NoError(t, db.DeleteURL(ctx, "first"))
+	_, err = db.FindURL(ctx, "", "", "https://example.com/path?a=1&b=2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// a link saved after the indexed one is gone takes its place
+	require.NoError(t, db.SaveURLWithCode(ctx, "third", Link{URL: "https://example.com/path?a=1&b=2"}))
+	link, err = db.FindURL(ctx, "", "", "https://example.com/path?b=2&a=1")
+	require.NoError(t, err)
+	assert.Equal(t, "third", link.Code)
+
+	_, err = db.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	_, err = db.FindURL(ctx, "", "", "https://expired.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// the index is scoped by tenant and host
+	require.NoError(t, db.SaveURLWithCode(ctx, "acme", Link{URL: "https://scoped.com", Tenant: "acme"}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "hosted", Link{URL: "https://scoped.com", Host: "acme.link"}))
+	_, err = db.FindURL(ctx, "", "", "https://scoped.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+	link, err = db.FindURL(ctx, "acme", "", "https://scoped.com")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", link.Code)
+	link, err = db.FindURL(ctx, "", "acme.link", "https://scoped.com")
+	require.NoError(t, err)
+	assert.Equal(t, "hosted", link.Code)
+	_, err = db.FindURL(ctx, "acme", "acme.link", "https://scoped.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, db.DeleteURL(ctx, "acme.link/hosted"))
+	_, err = db.FindURL(ctx, "", "acme.link", "https://scoped.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+	link, err = db.FindURL(ctx, "acme", "", "https://scoped.com")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", link.Code)
+}
+
+func testNotFound(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+
+	_, err := db.GetURL(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.UpdateURL(ctx, "missing", func(link *Link) error { return nil })
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.ErrorIs(t, db.DeleteURL(ctx, "missing"), ErrNotFound)
+}
+
+func testUpdate(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "update", Link{URL: "https://example.com", Title: "Old"}))
+	saved, err := db.GetURL(ctx, "update")
+	require.NoError(t, err)
+
+	updated, err := db.UpdateURL(ctx, "update", func(link *Link) error {
+		link.URL = "https://updated.com"
+		link.Tags = []string{"new"}
+		link.Disabled = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "update", updated.Code)
+	assert.Equal(t, "https://updated.com", updated.URL)
+
+	link, err := db.GetURL(ctx, "update")
+	require.NoError(t, err)
+	assert.Equal(t, "https://updated.com", link.URL)
+	assert.Equal(t, "Old", link.Title)
+	assert.Equal(t, []string{"new"}, link.Tags)
+	assert.True(t, link.Disabled)
+	assert.True(t, saved.CreatedAt.Equal(link.CreatedAt))
+	assert.False(t, link.UpdatedAt.Before(saved.UpdatedAt))
+}
+
+func testUpdateAborted(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "aborted", Link{URL: "https://example.com"}))
+
+	_, err := db.UpdateURL(ctx, "aborted", func(link *Link) error {
+		link.URL = "https://updated.com"
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+
+	link, err := db.GetURL(ctx, "aborted")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", link.URL)
+}
+
+func testDelete(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "delete", Link{URL: "https://example.com", ExpiresAt: &expiresAt}))
+	require.NoError(t, db.IncrementClicks(ctx, map[string]int64{"delete": 3}))
+	require.NoError(t, db.DeleteURL(ctx, "delete"))
+
+	_, err := db.GetURL(ctx, "delete")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// a deleted code can be claimed again, without the old clicks
+	require.NoError(t, db.SaveURLWithCode(ctx, "delete", Link{URL: "https://example.com"}))
+	link, err := db.GetURL(ctx, "delete")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), link.Clicks)
+}
+
+func testExpiration(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	past := time.Now().Add(-time.Second).Truncate(time.Second)
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "future", Link{URL: "https://example.com", ExpiresAt: &future}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "past", Link{URL: "https://example.com", ExpiresAt: &past}))
+
+	link, err := db.GetURL(ctx, "future")
+	require.NoError(t, err)
+	require.NotNil(t, link.ExpiresAt)
+	assert.True(t, future.Equal(*link.ExpiresAt))
+
+	link, err = db.GetURL(ctx, "past")
+	assert.ErrorIs(t, err, ErrExpired)
+	assert.Equal(t, "past", link.Code)
+}
+
+func testDeleteExpired(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Second)
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "forever", Link{URL: "https://example.com"}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "future", Link{URL: "https://example.com", ExpiresAt: &future}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "past", Link{URL: "https://example.com", ExpiresAt: &past}))
+
+	deleted, err := db.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, err = db.GetURL(ctx, "past")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	page, err := db.ListURL(ctx, ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, page.Links, 2)
+
+	// the expired link must be gone from the creation index as well
+	page, err = db.ListURL(ctx, ListOptions{Sort: SortCreatedAsc})
+	require.NoError(t, err)
+	assert.Len(t, page.Links, 2)
+}
+
+func testList(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "second", Link{URL: "https://second.com"}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "first", Link{URL: "https://first.com"}))
+	require.NoError(t, db.IncrementClicks(ctx, map[string]int64{"second": 2}))
+
+	page, err := db.ListURL(ctx, ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, page.NextCursor)
+
+	links := page.Links
+	sortLinks(links)
+	require.Len(t, links, 2)
+	assert.Equal(t, "first", links[0].Code)
+	assert.Equal(t, "https://first.com", links[0].URL)
+	assert.Equal(t, "second", links[1].Code)
+	assert.Equal(t, int64(2), links[1].Clicks)
+
+	_, err = db.ListURL(ctx, ListOptions{Cursor: "not a cursor", Sort: SortCreatedAsc})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func testListPages(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+
+	const total = 57
+	for i := range total {
+		code := fmt.Sprintf("code%02d", i)
+		require.NoError(t, db.SaveURLWithCode(ctx, code, Link{URL: "https://example.com/" + code}))
+	}
+
+	for _, sort := range []string{"", SortCreatedAsc, SortCreatedDesc} {
+		seen := map[string]bool{}
+		opts := ListOptions{Limit: 10, Sort: sort}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, total, "listing doesn't end")
+
+			page, err := db.ListURL(ctx, opts)
+			require.NoError(t, err)
+			for _, link := range page.Links {
+				assert.False(t, seen[link.Code], "%q listed twice", link.Code)
+				seen[link.Code] = true
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+		}
+		assert.Len(t, seen, total, "sort %q", sort)
+	}
+}
+
+// testListPagesWhileWriting deletes a listed link or saves one ahead of the
+// listing between pages, neither may shift the next pages.
+func testListPagesWhileWriting(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for prefix, sort := range map[string]string{"asc": SortCreatedAsc, "desc": SortCreatedDesc} {
+		const total = 30
+		for i := range total {
+			code := fmt.Sprintf("%s%02d", prefix, i)
+			// three links a millisecond, to page through ties
+			created := day.Add(time.Duration(i/3) * time.Millisecond)
+			require.NoError(t, db.SaveURLWithCode(ctx, code, Link{URL: "https://example.com/" + code, CreatedAt: created}))
+		}
+
+		seen := map[string]bool{}
+		opts := ListOptions{Limit: 4, Sort: sort}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, total, "listing doesn't end")
+
+			page, err := db.ListURL(ctx, opts)
+			require.NoError(t, err)
+			for _, link := range page.Links {
+				if !strings.HasPrefix(link.Code, prefix) {
+					continue
+				}
+				assert.False(t, seen[link.Code], "%q listed twice", link.Code)
+				seen[link.Code] = true
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+
+			if pages%2 == 0 {
+				require.NoError(t, db.DeleteURL(ctx, page.Links[0].Code))
+				continue
+			}
+
+			ahead := day.Add(-time.Hour)
+			if sort == SortCreatedDesc {
+				ahead = day.Add(time.Hour)
+			}
+			code := fmt.Sprintf("new%s%02d", prefix, pages)
+			require.NoError(t, db.SaveURLWithCode(ctx, code, Link{URL: "https://example.com/" + code, CreatedAt: ahead}))
+		}
+		assert.Len(t, seen, total, "sort %q", sort)
+	}
+}
+
+func testListFilters(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "docs", Link{
+		URL:       "https://docs.example.com/Guide",
+		Tags:      []string{"docs"},
+		CreatedAt: day,
+	}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "blog", Link{
+		URL:       "https://example.com/blog",
+		Tags:      []string{"launch"},
+		CreatedAt: day.Add(24 * time.Hour),
+	}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "other", Link{
+		URL:       "https://notexample.com/guide",
+		CreatedAt: day.Add(48 * time.Hour),
+	}))
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{name: "contains", opts: ListOptions{Contains: "GUIDE"}, want: []string{"docs", "other"}},
+		{name: "domain", opts: ListOptions{Domain: "example.com"}, want: []string{"blog", "docs"}},
+		{name: "subdomain", opts: ListOptions{Domain: "docs.example.com"}, want: []string{"docs"}},
+		{name: "tag", opts: ListOptions{Tag: "launch"}, want: []string{"blog"}},
+		{
+			name: "created range",
+			opts: ListOptions{CreatedFrom: day.Add(24 * time.Hour), CreatedTo: day.Add(48 * time.Hour)},
+			want: []string{"blog"},
+		},
+		{
+			name: "created range sorted",
+			opts: ListOptions{CreatedFrom: day.Add(time.Hour), Sort: SortCreatedDesc},
+			want: []string{"blog", "other"},
+		},
+		{name: "no match", opts: ListOptions{Tag: "missing"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := db.ListURL(ctx, tt.opts)
+			require.NoError(t, err)
+
+			codes := []string{}
+			for _, link := range page.Links {
+				codes = append(codes, link.Code)
+			}
+			slices.Sort(codes)
+			assert.Equal(t, tt.want, codes)
+		})
+	}
+}
+
+func testListSorted(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "b", Link{URL: "https://b.com", CreatedAt: day}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "c", Link{URL: "https://c.com", CreatedAt: day.Add(-time.Hour)}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "a", Link{URL: "https://a.com", CreatedAt: day.Add(time.Hour)}))
+
+	codes := func(sort string) []string {
+		page, err := db.ListURL(ctx, ListOptions{Sort: sort, Limit: 2})
+		require.NoError(t, err)
+		next, err := db.ListURL(ctx, ListOptions{Sort: sort, Limit: 2, Cursor: page.NextCursor})
+		require.NoError(t, err)
+		assert.Empty(t, next.NextCursor)
+
+		codes := []string{}
+		for _, link := range append(page.Links, next.Links...) {
+			codes = append(codes, link.Code)
+		}
+		return codes
+	}
+
+	assert.Equal(t, []string{"c", "b", "a"}, codes(SortCreatedAsc))
+	assert.Equal(t, []string{"a", "b", "c"}, codes(SortCreatedDesc))
+}
+
+func testIncrementClicks(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "clicks", Link{URL: "https://example.com"}))
+	require.NoError(t, db.IncrementClicks(ctx, map[string]int64{"clicks": 2, "missing": 1}))
+	require.NoError(t, db.IncrementClicks(ctx, map[string]int64{"clicks": 1}))
+
+	link, err := db.GetURL(ctx, "clicks")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), link.Clicks)
+
+	// clicks of unknown codes are dropped, not kept for a future link
+	require.NoError(t, db.SaveURLWithCode(ctx, "missing", Link{URL: "https://example.com"}))
+	link, err = db.GetURL(ctx, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), link.Clicks)
+
+	// updates don't touch the clicks
+	link, err = db.UpdateURL(ctx, "clicks", func(link *Link) error {
+		link.Clicks = 100
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), link.Clicks)
+}
+
+func testConsumeClick(t *testing.T, db UrlContract) {
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "limited", Link{URL: "https://example.com", MaxClicks: 5}))
+
+	// concurrent redirects never take more than the limit
+	var taken atomic.Int64
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.ConsumeClick(ctx, "limited", 5)
+			if err == nil {
+				taken.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, ErrExhausted)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(5), taken.Load())
+
+	assert.ErrorIs(t, db.ConsumeClick(ctx, "missing", 5), ErrNotFound)
+
+	// a new link under the same code starts over
+	require.NoError(t, db.DeleteURL(ctx, "limited"))
+	require.NoError(t, db.SaveURLWithCode(ctx, "limited", Link{URL: "https://example.com", MaxClicks: 1}))
+	require.NoError(t, db.ConsumeClick(ctx, "limited", 1))
+	assert.ErrorIs(t, db.ConsumeClick(ctx, "limited", 1), ErrExhausted)
+}
+
+func TestCodeStrategiesConformance(t *testing.T) {
+	strategies := map[string]codegen.Options{
+		"random":             {Strategy: codegen.StrategyRandom},
+		"counter":            {Strategy: codegen.StrategyCounter},
+		"obfuscated counter": {Strategy: codegen.StrategyCounter, Key: "secret"},
+		"hash":               {Strategy: codegen.StrategyHash, Length: 6},
+		"numeric":            {Alphabet: "numeric", Length: 4},
+	}
+
+	for name, opts := range strategies {
+		t.Run(name, func(t *testing.T) {
+			for backend, storage := range newTestStoragesWithCodes(t, opts) {
+				t.Run(backend, func(t *testing.T) {
+					ctx := context.Background()
+					db := storage.Urls
+
+					codes := map[string]bool{}
+					for range 20 {
+						code, err := db.SaveShortenedURL(ctx, Link{URL: "https://example.com"})
+						require.NoError(t, err)
+						assert.False(t, codes[code], "code %q generated twice", code)
+						codes[code] = true
+					}
+
+					results, err := db.SaveURLs(ctx, []Link{{URL: "https://example.com"}, {URL: "https://example.com"}})
+					require.NoError(t, err)
+					for _, result := range results {
+						require.NoError(t, result.Err)
+						assert.False(t, codes[result.Code], "code %q generated twice", result.Code)
+						codes[result.Code] = true
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestCounterCodeSkipsTakenCodes(t *testing.T) {
+	for backend, storage := range newTestStoragesWithCodes(t, codegen.Options{Strategy: codegen.StrategyCounter}) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			db := storage.Urls
+
+			// the first two counter values, taken by aliases beforehand
+			require.NoError(t, db.SaveURLWithCode(ctx, "aaaaaaab", Link{URL: "https://alias.com"}))
+			require.NoError(t, db.SaveURLWithCode(ctx, "aaaaaaac", Link{URL: "https://alias.com"}))
+
+			code, err := db.SaveShortenedURL(ctx, Link{URL: "https://example.com"})
+			require.NoError(t, err)
+			assert.Equal(t, "aaaaaaad", code)
+
+			link, err := db.GetURL(ctx, "aaaaaaab")
+			require.NoError(t, err)
+			assert.Equal(t, "https://alias.com", link.URL)
+		})
+	}
+}
+
+// fixedCode always proposes the same code, colliding from the second link on.
+type fixedCode string
+
+func (c fixedCode) Generate(ctx context.Context, target string, attempt int) (string, error) {
+	return string(c), nil
+}
+
+// attemptCodes proposes the code of the attempt, like the hash strategy.
+type attemptCodes []string
+
+func (c attemptCodes) Generate(ctx context.Context, target string, attempt int) (string, error) {
+	return c[attempt], nil
+}
+
+// newTestRepos returns one fresh link repository per backend, generating
+// the codes with codes.
+func newTestRepos(t *testing.T, codes codegen.Generator) map[string]UrlContract {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	boltRepo, err := NewBoltUrlRepository(db, codes)
+	require.NoError(t, err)
+
+	return map[string]UrlContract{
+		BackendRedis:  NewUrlRepository(redis.NewClient(&redis.Options{Addr: mr.Addr()}), codes),
+		BackendMemory: NewMemoryUrlRepository(codes),
+		BackendBolt:   boltRepo,
+	}
+}
+
+func TestGeneratedCodesNeverOverwrite(t *testing.T) {
+	for backend, repo := range newTestRepos(t, fixedCode("abc")) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+
+			code, err := repo.SaveShortenedURL(ctx, Link{URL: "https://first.com"})
+			require.NoError(t, err)
+			assert.Equal(t, "abc", code)
+
+			_, err = repo.SaveShortenedURL(ctx, Link{URL: "https://second.com"})
+			assert.ErrorIs(t, err, ErrCodeTaken)
+
+			results, err := repo.SaveURLs(ctx, []Link{{URL: "https://third.com"}})
+			require.NoError(t, err)
+			assert.ErrorIs(t, results[0].Err, ErrCodeTaken)
+
+			link, err := repo.GetURL(ctx, "abc")
+			require.NoError(t, err)
+			assert.Equal(t, "https://first.com", link.URL)
+		})
+	}
+}
+
+func TestGeneratedCodesSkipHostlessCodes(t *testing.T) {
+	for backend, repo := range newTestRepos(t, attemptCodes{"abc", "abd", "abe"}) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+
+			// saved before any domain was registered, served first on the legacy domain
+			require.NoError(t, repo.SaveURLWithCode(ctx, "abc", Link{URL: "https://legacy.com"}))
+
+			code, err := repo.SaveShortenedURL(ctx, Link{URL: "https://first.com", Host: "legacy.link"})
+			require.NoError(t, err)
+			assert.Equal(t, "abd", code)
+
+			results, err := repo.SaveURLs(ctx, []Link{{URL: "https://second.com", Host: "legacy.link"}})
+			require.NoError(t, err)
+			require.NoError(t, results[0].Err)
+			assert.Equal(t, "abe", results[0].Code)
+
+			link, err := repo.GetURL(ctx, "abc")
+			require.NoError(t, err)
+			assert.Equal(t, "https://legacy.com", link.URL)
+			_, err = repo.GetURL(ctx, LinkKey("legacy.link", "abc"))
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+func TestStatsContractConformance(t *testing.T) {
+	for backend, storage := range newTestStorages(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			ts := time.Now().UTC().Truncate(24 * time.Hour).Add(-48*time.Hour + 10*time.Hour + 30*time.Minute)
+			day, hour := ts.Format(dayLayout), ts.Format(hourLayout)
+			nextDay := ts.Add(24 * time.Hour)
+
+			empty, err := storage.Stats.GetStats(ctx, "abc")
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), empty.TotalClicks)
+
+			err = storage.Stats.RecordClicks(ctx, []Click{
+				{Code: "abc", Timestamp: ts, VisitorID: "a", Country: "BR", Referrer: "news.example"},
+				{Code: "abc", Timestamp: ts.Add(time.Hour), VisitorID: "a", Variant: "control"},
+				{Code: "abc", Timestamp: ts.Add(24 * time.Hour), VisitorID: "b", Country: "BR", Variant: "control"},
+				{Code: "other", Timestamp: ts, VisitorID: "c"},
+			})
+			require.NoError(t, err)
+
+			stats, err := storage.Stats.GetStats(ctx, "abc")
+			require.NoError(t, err)
+			assert.Equal(t, URLStats{
+				TotalClicks:    3,
+				UniqueVisitors: 2,
+				Daily:          map[string]int64{day: 2, nextDay.Format(dayLayout): 1},
+				Hourly:         map[string]int64{hour: 1, ts.Add(time.Hour).Format(hourLayout): 1, nextDay.Format(hourLayout): 1},
+				Referrers:      map[string]int64{"news.example": 1},
+				Countries:      map[string]int64{"BR": 2},
+				Variants:       map[string]int64{"control": 2},
+			}, stats)
+		})
+	}
+}
+
+func TestStatsBounded(t *testing.T) {
+	for backend, storage := range newTestStorages(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now().UTC()
+
+			clicks := []Click{
+				// past the hourly retention, only the daily bucket keeps it
+				{Code: "abc", Timestamp: now.Add(-hourlyRetention - 2*time.Hour)},
+				{Code: "abc", Timestamp: now},
+			}
+			for i := range maxReferrers + 5 {
+				clicks = append(clicks, Click{Code: "abc", Timestamp: now, Referrer: fmt.Sprintf("site%d.example", i)})
+			}
+			require.NoError(t, storage.Stats.RecordClicks(ctx, clicks))
+
+			stats, err := storage.Stats.GetStats(ctx, "abc")
+			require.NoError(t, err)
+			assert.Equal(t, int64(maxReferrers+7), stats.TotalClicks)
+			assert.Equal(t, map[string]int64{now.Format(hourLayout): maxReferrers + 6}, stats.Hourly)
+			assert.Len(t, stats.Daily, 2)
+			assert.Len(t, stats.Referrers, maxReferrers+1)
+			assert.Equal(t, int64(5), stats.Referrers[otherReferrer])
+		})
+	}
+}
+
+func TestStatsDeletedWithLink(t *testing.T) {
+	for backend, storage := range newTestStorages(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			expired := time.Now().Add(-time.Minute)
+
+			require.NoError(t, storage.Urls.SaveURLWithCode(ctx, "gone", Link{URL: "https://gone.com"}))
+			require.NoError(t, storage.Urls.SaveURLWithCode(ctx, "old", Link{URL: "https://old.com", ExpiresAt: &expired}))
+			require.NoError(t, storage.Stats.RecordClicks(ctx, []Click{
+				{Code: "gone", Timestamp: time.Now(), VisitorID: "a", Referrer: "news.example"},
+				{Code: "old", Timestamp: time.Now(), VisitorID: "a", Country: "BR"},
+			}))
+
+			require.NoError(t, storage.Urls.DeleteURL(ctx, "gone"))
+			_, err := storage.Urls.DeleteExpired(ctx, time.Now())
+			require.NoError(t, err)
+
+			// a link saved again under the code starts from no clicks
+			for _, code := range []string{"gone", "old"} {
+				stats, err := storage.Stats.GetStats(ctx, code)
+				require.NoError(t, err)
+				assert.Equal(t, URLStats{
+					Daily:     map[string]int64{},
+					Hourly:    map[string]int64{},
+					Referrers: map[string]int64{},
+					Countries: map[string]int64{},
+					Variants:  map[string]int64{},
+				}, stats, code)
+			}
+		})
+	}
+}
+
+func TestTenantContractConformance(t *testing.T) {
+	suite := map[string]func(t *testing.T, storage *Storage){
+		"save and get": testTenantSaveAndGet,
+		"quota":        testTenantQuota,
+		"links":        testTenantLinks,
+		"isolation":    testTenantIsolation,
+	}
+
+	for name, test := range suite {
+		t.Run(name, func(t *testing.T) {
+			for backend, storage := range newTestStorages(t) {
+				t.Run(backend, func(t *testing.T) {
+					test(t, storage)
+				})
+			}
+		})
+	}
+}
+
+func testTenantSaveAndGet(t *testing.T, storage *Storage) {
+	ctx := context.Background()
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := storage.Tenants.GetTenant(ctx, "acme")
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+	_, err = storage.Tenants.UpdateTenant(ctx, "acme", func(tenant *Tenant) error { return nil })
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+
+	require.NoError(t, storage.Tenants.SaveTenant(ctx, Tenant{ID: "zeta", Name: "Zeta", CreatedAt: created}))
+	require.NoError(t, storage.Tenants.SaveTenant(ctx, Tenant{ID: "acme", Name: "Acme", MaxLinks: 10, CreatedAt: created}))
+	err = storage.Tenants.SaveTenant(ctx, Tenant{ID: "acme", Name: "Other"})
+	assert.ErrorIs(t, err, ErrTenantExists)
+
+	require.NoError(t, storage.Urls.SaveURLWithCode(ctx, "acme1", Link{URL: "https://acme.com", Tenant: "acme"}))
+
+	tenant, err := storage.Tenants.GetTenant(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", tenant.Name)
+	assert.Equal(t, int64(10), tenant.MaxLinks)
+	assert.Equal(t, int64(1), tenant.Links)
+	assert.True(t, created.Equal(tenant.CreatedAt))
+
+	tenant, err = storage.Tenants.UpdateTenant(ctx, "acme", func(tenant *Tenant) error {
+		tenant.ID = "changed"
+		tenant.Name = "Acme Inc"
+		tenant.MaxLinks = 0
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenant.ID)
+	assert.Equal(t, "Acme Inc", tenant.Name)
+	assert.Equal(t, int64(1), tenant.Links)
+	assert.True(t, created.Equal(tenant.CreatedAt))
+	assert.True(t, tenant.UpdatedAt.After(created))
+
+	_, err = storage.Tenants.UpdateTenant(ctx, "acme", func(tenant *Tenant) error { return assert.AnError })
+	assert.ErrorIs(t, err, assert.AnError)
+
+	tenants, err := storage.Tenants.ListTenants(ctx)
+	require.NoError(t, err)
+	require.Len(t, tenants, 2)
+	assert.Equal(t, "acme", tenants[0].ID)
+	assert.Equal(t, "Acme Inc", tenants[0].Name)
+	assert.Equal(t, int64(1), tenants[0].Links)
+	assert.Equal(t, "zeta", tenants[1].ID)
+	assert.Equal(t, int64(0), tenants[1].Links)
+}
+
+func testTenantQuota(t *testing.T, storage *Storage) {
+	ctx := context.Background()
+	db := storage.Urls
+
+	require.NoError(t, storage.Tenants.SaveTenant(ctx, Tenant{ID: "acme", MaxLinks: 2}))
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "first", Link{URL: "https://acme.com", Tenant: "acme"}))
+	second, err := db.SaveShortenedURL(ctx, Link{URL: "https://acme.com", Tenant: "acme"})
+	require.NoError(t, err)
+
+	err = db.SaveURLWithCode(ctx, "third", Link{URL: "https://acme.com", Tenant: "acme"})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = db.SaveShortenedURL(ctx, Link{URL: "https://acme.com", Tenant: "acme"})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	results, err := db.SaveURLs(ctx, []Link{
+		{URL: "https://acme.com", Tenant: "acme"},
+		{Code: "batch", URL: "https://acme.com", Tenant: "acme"},
+		{URL: "https://other.com"},
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, ErrQuotaExceeded)
+	assert.ErrorIs(t, results[1].Err, ErrQuotaExceeded)
+	assert.NoError(t, results[2].Err)
+
+	// updates keep the tenant, so they never free a slot
+	_, err = db.UpdateURL(ctx, "first", func(link *Link) error {
+		link.Tenant = ""
+		return nil
+	})
+	require.NoError(t, err)
+	link, err := db.GetURL(ctx, "first")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", link.Tenant)
+
+	require.NoError(t, db.DeleteURL(ctx, second))
+	require.NoError(t, db.SaveURLWithCode(ctx, "third", Link{URL: "https://acme.com", Tenant: "acme"}))
+
+	past := time.Now().Add(-time.Minute)
+	_, err = db.UpdateURL(ctx, "third", func(link *Link) error {
+		link.ExpiresAt = &past
+		return nil
+	})
+	require.NoError(t, err)
+	_, err = db.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+
+	tenant, err := storage.Tenants.GetTenant(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), tenant.Links)
+}
+
+func testTenantLinks(t *testing.T, storage *Storage) {
+	ctx := context.Background()
+	db := storage.Urls
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, db.SaveURLWithCode(ctx, "a1", Link{URL: "https://a.com/1", Tenant: "a", CreatedAt: day}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "b1", Link{URL: "https://b.com/1", Tenant: "b", CreatedAt: day.Add(time.Hour)}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "a2", Link{URL: "https://a.com/2", Tenant: "a", CreatedAt: day.Add(2 * time.Hour)}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "none", Link{URL: "https://none.com", CreatedAt: day.Add(3 * time.Hour)}))
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{name: "tenant", opts: ListOptions{Tenant: "a"}, want: []string{"a1", "a2"}},
+		{name: "tenant sorted", opts: ListOptions{Tenant: "a", Sort: SortCreatedDesc}, want: []string{"a2", "a1"}},
+		{name: "tenant filtered", opts: ListOptions{Tenant: "a", Contains: "/2"}, want: []string{"a2"}},
+		{name: "other tenant", opts: ListOptions{Tenant: "b"}, want: []string{"b1"}},
+		{name: "unknown tenant", opts: ListOptions{Tenant: "c"}, want: []string{}},
+		{name: "every tenant", opts: ListOptions{Sort: SortCreatedAsc}, want: []string{"a1", "b1", "a2", "none"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := db.ListURL(ctx, tt.opts)
+			require.NoError(t, err)
+
+			codes := []string{}
+			for _, link := range page.Links {
+				codes = append(codes, link.Code)
+			}
+			assert.Equal(t, tt.want, codes)
+		})
+	}
+
+	page, err := db.ListURL(ctx, ListOptions{Tenant: "a", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	require.NotEmpty(t, page.NextCursor)
+	next, err := db.ListURL(ctx, ListOptions{Tenant: "a", Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, next.Links, 1)
+	assert.NotEqual(t, page.Links[0].Code, next.Links[0].Code)
+
+	require.NoError(t, db.DeleteURL(ctx, "a1"))
+	page, err = db.ListURL(ctx, ListOptions{Tenant: "a"})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, "a2", page.Links[0].Code)
+}
+
+func testTenantIsolation(t *testing.T, storage *Storage) {
+	ctx := context.Background()
+	db := storage.Urls
+
+	require.NoError(t, storage.Tenants.SaveTenant(ctx, Tenant{ID: "a", MaxLinks: 1}))
+	require.NoError(t, storage.Tenants.SaveTenant(ctx, Tenant{ID: "b", MaxLinks: 1}))
+	require.NoError(t, db.SaveURLWithCode(ctx, "shared", Link{URL: "https://a.com", Tenant: "a"}))
+
+	// codes are global, the code of a link of a is taken for b without
+	// touching the link nor taking a slot of b
+	err := db.SaveURLWithCode(ctx, "shared", Link{URL: "https://b.com", Tenant: "b"})
+	assert.ErrorIs(t, err, ErrCodeTaken)
+	results, err := db.SaveURLs(ctx, []Link{{Code: "shared", URL: "https://b.com", Tenant: "b"}})
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, ErrCodeTaken)
+
+	link, err := db.GetURL(ctx, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.com", link.URL)
+	assert.Equal(t, "a", link.Tenant)
+
+	// the quota of a full tenant doesn't hold the other back
+	require.NoError(t, db.SaveURLWithCode(ctx, "b1", Link{URL: "https://a.com", Tenant: "b"}))
+
+	link, err = db.FindURL(ctx, "b", "", "https://a.com")
+	require.NoError(t, err)
+	assert.Equal(t, "b1", link.Code)
+
+	page, err := db.ListURL(ctx, ListOptions{Tenant: "b"})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, "b1", page.Links[0].Code)
+
+	require.NoError(t, db.DeleteURL(ctx, "b1"))
+	for id, links := range map[string]int64{"a": 1, "b": 0} {
+		tenant, err := storage.Tenants.GetTenant(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, links, tenant.Links, id)
+	}
+	link, err = db.FindURL(ctx, "a", "", "https://a.com")
+	require.NoError(t, err)
+	assert.Equal(t, "shared", link.Code)
+}
+
+func TestApiKeyContractConformance(t *testing.T) {
+	for backend, storage := range newTestStorages(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			_, err := storage.Keys.GetApiKeyByHash(ctx, "missing")
+			assert.ErrorIs(t, err, ErrKeyNotFound)
+
+			second := ApiKey{ID: "k2", Name: "ci", Hash: "hash2", Scopes: []string{"links:read"}, CreatedAt: created.Add(time.Hour)}
+			first := ApiKey{ID: "k1", Name: "marketing", Prefix: "ak_1234", Hash: "hash1", Scopes: []string{"links:create"}, CreatedAt: created}
+			require.NoError(t, storage.Keys.SaveApiKey(ctx, second))
+			require.NoError(t, storage.Keys.SaveApiKey(ctx, first))
+
+			err = storage.Keys.SaveApiKey(ctx, ApiKey{ID: "k1", Hash: "other"})
+			assert.ErrorIs(t, err, ErrKeyExists)
+			err = storage.Keys.SaveApiKey(ctx, ApiKey{ID: "k3", Hash: "hash1"})
+			assert.ErrorIs(t, err, ErrKeyExists)
+
+			key, err := storage.Keys.GetApiKeyByHash(ctx, "hash1")
+			require.NoError(t, err)
+			assert.Equal(t, first.ID, key.ID)
+			assert.Equal(t, first.Name, key.Name)
+			assert.Equal(t, first.Prefix, key.Prefix)
+			assert.Equal(t, first.Hash, key.Hash)
+			assert.Equal(t, first.Scopes, key.Scopes)
+			assert.True(t, first.CreatedAt.Equal(key.CreatedAt))
+			assert.False(t, key.Revoked())
+
+			keys, err := storage.Keys.ListApiKeys(ctx)
+			require.NoError(t, err)
+			require.Len(t, keys, 2)
+			assert.Equal(t, "k1", keys[0].ID)
+			assert.Equal(t, "k2", keys[1].ID)
+
+			revoked, err := storage.Keys.RevokeApiKey(ctx, "k1")
+			require.NoError(t, err)
+			require.True(t, revoked.Revoked())
+
+			again, err := storage.Keys.RevokeApiKey(ctx, "k1")
+			require.NoError(t, err)
+			assert.True(t, revoked.RevokedAt.Equal(*again.RevokedAt))
+
+			key, err = storage.Keys.GetApiKeyByHash(ctx, "hash1")
+			require.NoError(t, err)
+			assert.True(t, key.Revoked())
+
+			_, err = storage.Keys.RevokeApiKey(ctx, "missing")
+			assert.ErrorIs(t, err, ErrKeyNotFound)
+		})
+	}
+}
+
+func TestDomainContractConformance(t *testing.T) {
+	for backend, storage := range newTestStorages(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			domains, err := storage.Domains.ListDomains(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, domains)
+
+			require.NoError(t, storage.Domains.SaveDomain(ctx, Domain{Name: "go.acme.io", CreatedAt: created}))
+			require.NoError(t, storage.Domains.SaveDomain(ctx, Domain{Name: "acme.link", Default: true, CreatedAt: created}))
+			err = storage.Domains.SaveDomain(ctx, Domain{Name: "go.acme.io"})
+			assert.ErrorIs(t, err, ErrDomainExists)
+
+			domains, err = storage.Domains.ListDomains(ctx)
+			require.NoError(t, err)
+			require.Len(t, domains, 2)
+			assert.Equal(t, "acme.link", domains[0].Name)
+			assert.False(t, domains[0].Default, "only the first domain becomes the default one")
+			assert.Equal(t, "go.acme.io", domains[1].Name)
+			assert.True(t, domains[1].Default)
+			assert.True(t, domains[1].Legacy)
+			assert.False(t, domains[0].Legacy)
+			assert.True(t, created.Equal(domains[1].CreatedAt))
+
+			require.NoError(t, storage.Domains.SetDefaultDomain(ctx, "acme.link"))
+			err = storage.Domains.SetDefaultDomain(ctx, "missing.io")
+			assert.ErrorIs(t, err, ErrDomainNotFound)
+
+			domains, err = storage.Domains.ListDomains(ctx)
+			require.NoError(t, err)
+			assert.True(t, domains[0].Default)
+			assert.False(t, domains[1].Default)
+			assert.True(t, domains[1].Legacy, "the legacy domain doesn't follow the default one")
+
+			require.NoError(t, storage.Domains.DeleteDomain(ctx, "acme.link"))
+			err = storage.Domains.DeleteDomain(ctx, "acme.link")
+			assert.ErrorIs(t, err, ErrDomainNotFound)
+
+			domains, err = storage.Domains.ListDomains(ctx)
+			require.NoError(t, err)
+			require.Len(t, domains, 1)
+			assert.False(t, domains[0].Default, "deleting the default domain leaves none")
+
+			// a registered domain only becomes the default when there is none
+			require.NoError(t, storage.Domains.SaveDomain(ctx, Domain{Name: "acme.link"}))
+			domains, err = storage.Domains.ListDomains(ctx)
+			require.NoError(t, err)
+			assert.True(t, domains[0].Default)
+			assert.False(t, domains[0].Legacy)
+			assert.True(t, domains[1].Legacy)
+		})
+	}
+}
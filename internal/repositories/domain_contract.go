@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrDomainNotFound is returned when no domain has the name.
+	ErrDomainNotFound = errors.New("domain not found")
+	// ErrDomainExists is returned when registering a domain twice.
+	ErrDomainExists = errors.New("domain already exists")
+)
+
+// Domain is a host registered to serve short links. Links bound to it are
+// stored with it as Host, the links created without a host being bound to
+// the default domain. The links saved without a host, before any domain was
+// registered, are served by the legacy domain.
+type Domain struct {
+	Name string `json:"name"`
+	// Default is set on the domain the links created without a host are
+	// bound to. It is kept apart from the record, so changing it rewrites a
+	// single key.
+	Default   bool      `json:"default"`
+	CreatedAt time.Time `json:"created_at"`
+	// Legacy is set on the domain serving the links without a host, the
+	// first one registered. Unlike Default it never moves, so those links
+	// keep their URL.
+	Legacy bool `json:"legacy"`
+}
+
+// DomainContract is the storage contract for the registered domains,
+// implemented by every storage backend.
+type DomainContract interface {
+	// SaveDomain registers the domain, returning ErrDomainExists if it
+	// already is. The first domain registered becomes the default and the
+	// legacy one.
+	SaveDomain(ctx context.Context, domain Domain) error
+	// ListDomains returns every domain, by name.
+	ListDomains(ctx context.Context) ([]Domain, error)
+	// DeleteDomain unregisters the domain, its links are kept. Deleting the
+	// default domain leaves no domain as default.
+	DeleteDomain(ctx context.Context, name string) error
+	// SetDefaultDomain makes the domain the default one in place of the
+	// previous default, returning ErrDomainNotFound if it isn't registered.
+	SetDefaultDomain(ctx context.Context, name string) error
+}
+
+func encodeDomain(domain Domain) (string, error) {
+	domain.Default = false
+	domain.Legacy = false
+	data, err := json.Marshal(domain)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode domain %q: %w", domain.Name, err)
+	}
+	return string(data), nil
+}
+
+// decodeDomain flags the domain from the names of the default and legacy
+// domains.
+func decodeDomain(value string, defaultName string, legacyName string) (Domain, error) {
+	var domain Domain
+	if err := json.Unmarshal([]byte(value), &domain); err != nil {
+		return Domain{}, fmt.Errorf("failed to decode domain: %w", err)
+	}
+	domain.Default = domain.Name == defaultName
+	domain.Legacy = domain.Name == legacyName
+	return domain, nil
+}
+
+func sortDomains(domains []Domain) {
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Name < domains[j].Name })
+}
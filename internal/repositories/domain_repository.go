@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	domainsKey       = "encurtador:domains"
+	defaultDomainKey = "encurtador:domains:default"
+	// legacyDomainKey holds the legacy domain, see Domain.Legacy.
+	legacyDomainKey = "encurtador:domains:legacy"
+)
+
+// saveDomainScript registers the domain only if it isn't yet, making it the
+// default one when there is none, and the legacy one when it is the first.
+var saveDomainScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('SET', KEYS[2], ARGV[1], 'NX')
+redis.call('SET', KEYS[3], ARGV[1], 'NX')
+return 1
+`)
+
+// deleteDomainScript unregisters the domain, along with the default when
+// it was the default one.
+var deleteDomainScript = redis.NewScript(`
+if redis.call('HDEL', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+if redis.call('GET', KEYS[2]) == ARGV[1] then
+	redis.call('DEL', KEYS[2])
+end
+return 1
+`)
+
+// setDefaultDomainScript makes the domain the default one only if it is
+// registered.
+var setDefaultDomainScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('SET', KEYS[2], ARGV[1])
+return 1
+`)
+
+type DomainRepository struct {
+	rdb *redis.Client
+}
+
+func NewDomainRepository(rdb *redis.Client) DomainContract {
+	return &DomainRepository{rdb: rdb}
+}
+
+func (s *DomainRepository) SaveDomain(ctx context.Context, domain Domain) error {
+	value, err := encodeDomain(domain)
+	if err != nil {
+		return err
+	}
+
+	ok, err := saveDomainScript.Run(ctx, s.rdb, []string{domainsKey, defaultDomainKey, legacyDomainKey}, domain.Name, value).Bool()
+	if err != nil {
+		return fmt.Errorf("error setting on redis: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("failed to save domain %q: %w", domain.Name, ErrDomainExists)
+	}
+
+	return nil
+}
+
+func (s *DomainRepository) ListDomains(ctx context.Context) ([]Domain, error) {
+	pipe := s.rdb.Pipeline()
+	valuesCmd := pipe.HVals(ctx, domainsKey)
+	defaultCmd := pipe.Get(ctx, defaultDomainKey)
+	legacyCmd := pipe.Get(ctx, legacyDomainKey)
+	// errors are checked per command below, redis.Nil included
+	_, _ = pipe.Exec(ctx)
+
+	values, err := valuesCmd.Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+	defaultName, err := defaultCmd.Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get default domain: %w", err)
+	}
+	legacyName, err := legacyCmd.Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get legacy domain: %w", err)
+	}
+
+	domains := make([]Domain, 0, len(values))
+	for _, value := range values {
+		domain, err := decodeDomain(value, defaultName, legacyName)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, domain)
+	}
+	sortDomains(domains)
+
+	return domains, nil
+}
+
+func (s *DomainRepository) DeleteDomain(ctx context.Context, name string) error {
+	ok, err := deleteDomainScript.Run(ctx, s.rdb, []string{domainsKey, defaultDomainKey}, name).Bool()
+	if err != nil {
+		return fmt.Errorf("failed to delete domain: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("failed to delete domain %q: %w", name, ErrDomainNotFound)
+	}
+
+	return nil
+}
+
+func (s *DomainRepository) SetDefaultDomain(ctx context.Context, name string) error {
+	ok, err := setDefaultDomainScript.Run(ctx, s.rdb, []string{domainsKey, defaultDomainKey}, name).Bool()
+	if err != nil {
+		return fmt.Errorf("failed to set default domain: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("failed to set default domain %q: %w", name, ErrDomainNotFound)
+	}
+
+	return nil
+}
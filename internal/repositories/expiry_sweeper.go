@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RunExpirySweeper periodically removes expired URLs until ctx is canceled.
+// Expired URLs are already rejected on read, the sweeper only reclaims space.
+func RunExpirySweeper(ctx context.Context, db UrlContract, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := db.DeleteExpired(ctx, now)
+			if err != nil {
+				slog.Error("error sweeping expired urls", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("expired urls removed", "count", deleted)
+			}
+		}
+	}
+}
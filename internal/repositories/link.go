@@ -0,0 +1,217 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"url-shortener/internal/split"
+	"url-shortener/internal/targeting"
+	"url-shortener/internal/utils"
+)
+
+// Link is a shortened URL along with its metadata.
+type Link struct {
+	Code      string     `json:"code,omitempty"`
+	URL       string     `json:"url"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Creator   string     `json:"creator,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Clicks is kept in a counter apart from the record, so redirects don't
+	// need to rewrite it.
+	Clicks   int64 `json:"clicks"`
+	Disabled bool  `json:"disabled"`
+	// RedirectStatus is the status of the redirect, one of RedirectStatuses,
+	// the configured default being used when zero.
+	RedirectStatus int `json:"redirect_status,omitempty"`
+	// NoCache asks browsers not to cache the redirect, so an update of the
+	// URL takes effect right away.
+	NoCache bool `json:"no_cache,omitempty"`
+	// PasswordHash is the bcrypt hash of the password asked before the
+	// redirect, empty for public links. It is never sent to clients, see
+	// linkRecord.
+	PasswordHash string `json:"-"`
+	// MaxClicks is how many redirects the link allows before it is
+	// exhausted, zero meaning unlimited.
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	// Rules send the visitors they match to other URLs, URL being the
+	// fallback. The first matching rule wins.
+	Rules []targeting.Rule `json:"rules,omitempty"`
+	// Variants split the visitors no rule matched across several URLs by
+	// weight, URL being left for the previews.
+	Variants []split.Variant `json:"variants,omitempty"`
+	// Query holds params added to the target on redirect, replacing the
+	// target params with the same name.
+	Query map[string]string `json:"query,omitempty"`
+	// ForwardQuery adds the query of the short URL request to the target,
+	// without replacing the params set on the target or in Query.
+	ForwardQuery bool `json:"forward_query,omitempty"`
+	// Tenant is the id of the tenant owning the link, empty for the links
+	// created without one. It never changes once the link is saved.
+	Tenant string `json:"tenant,omitempty"`
+	// Host is the registered domain serving the link, empty for the links
+	// of the default domain. The same code can exist once per host, and the
+	// host never changes once the link is saved.
+	Host string `json:"host,omitempty"`
+}
+
+// linkRecord is the stored form of a link, which holds its password hash.
+type linkRecord struct {
+	Link
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// RedirectStatuses are the statuses a link can redirect with.
+var RedirectStatuses = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+// ValidRedirectStatus reports whether the status is one of RedirectStatuses.
+func ValidRedirectStatus(status int) bool {
+	return slices.Contains(RedirectStatuses, status)
+}
+
+// LinkKey is the storage key of the link with the code on the host, which
+// the repositories take as code. It is the bare code for the links without
+// a host, so they keep the keys they had before links had hosts.
+func LinkKey(host string, code string) string {
+	if host == "" {
+		return code
+	}
+	return host + "/" + code
+}
+
+// hostlessKey is the key of the link without a host that a code generated
+// on the host must not take either, as the legacy domain serves those
+// links first. It is empty for links without a host.
+func hostlessKey(host string, code string) string {
+	if host == "" {
+		return ""
+	}
+	return code
+}
+
+// splitLinkKey returns the host and the code of a link key, codes and hosts
+// never holding a slash.
+func splitLinkKey(key string) (string, string) {
+	host, code, ok := strings.Cut(key, "/")
+	if !ok {
+		return "", key
+	}
+	return host, code
+}
+
+// Key returns the storage key of the link, see LinkKey.
+func (l Link) Key() string {
+	return LinkKey(l.Host, l.Code)
+}
+
+// Protected reports whether the link asks for a password.
+func (l Link) Protected() bool {
+	return l.PasswordHash != ""
+}
+
+// Expired reports whether the link has an expiration that already passed.
+func (l Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
+}
+
+// expiryTime returns the expiration as a time, zero when the link never expires.
+func (l Link) expiryTime() time.Time {
+	if l.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return *l.ExpiresAt
+}
+
+// targetKey is the key of the link in the normalized target index, empty
+// for disabled links and the ones that don't always redirect to their URL
+// as is, with the default status and caching and for good, which aren't
+// indexed so they are never reused.
+func (l Link) targetKey() string {
+	if l.Disabled || l.Protected() || l.MaxClicks > 0 || len(l.Rules) > 0 || len(l.Variants) > 0 ||
+		len(l.Query) > 0 || l.ForwardQuery || l.RedirectStatus != 0 || l.NoCache || l.ExpiresAt != nil {
+		return ""
+	}
+	return targetKey(l.Tenant, l.Host, l.URL)
+}
+
+// foundLink drops the links the target index kept from before they stopped
+// being indexed, as the previous versions indexed links with redirect
+// options or an expiration.
+func foundLink(link Link, err error) (Link, error) {
+	if err == nil && link.targetKey() == "" {
+		return Link{}, fmt.Errorf("failed to find url: %w", ErrNotFound)
+	}
+	return link, err
+}
+
+// targetKey scopes the normalized target to the tenant and the host, so a
+// link is only ever reused by its own tenant on its own domain. Neither
+// holds spaces and the target comes last, so keys can't collide.
+func targetKey(tenant, host, target string) string {
+	return tenant + " " + host + " " + utils.NormalizeURL(target)
+}
+
+// encodeLink serializes the link for storage. The code and the host make
+// the storage key and the clicks live in their own counter, so they are
+// left out.
+func encodeLink(link Link) (string, error) {
+	link.Code = ""
+	link.Host = ""
+	link.Clicks = 0
+
+	data, err := json.Marshal(linkRecord{Link: link, PasswordHash: link.PasswordHash})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode link: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// decodeLink parses a stored link from its key, see LinkKey. Values written
+// before links had metadata are bare URL strings, those are returned as a
+// link with only the URL set.
+func decodeLink(key string, value string) (Link, error) {
+	if isLegacyValue(value) {
+		return Link{Code: key, URL: value}, nil
+	}
+
+	var record linkRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return Link{}, fmt.Errorf("failed to decode link %q: %w", key, err)
+	}
+	link := record.Link
+	link.Host, link.Code = splitLinkKey(key)
+	link.PasswordHash = record.PasswordHash
+
+	return link, nil
+}
+
+func isLegacyValue(value string) bool {
+	return !strings.HasPrefix(value, "{")
+}
+
+// prepareNewLink stamps the creation time of a link about to be saved.
+func prepareNewLink(link Link, now time.Time) Link {
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = now
+	}
+	link.UpdatedAt = link.CreatedAt
+	return link
+}
+
+func sortLinks(links []Link) {
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Key() < links[j].Key()
+	})
+}
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SortCreatedAsc and SortCreatedDesc order the listing by creation date,
+	// by default the storage order is used, which is the cheapest to page through.
+	SortCreatedAsc  = "created_at"
+	SortCreatedDesc = "-created_at"
+
+	DefaultListLimit = 50
+	MaxListLimit     = 1000
+
+	// scanBudgetFactor bounds how many entries are read per page, relative to
+	// the limit, so a filter that matches almost nothing can't read the whole
+	// storage in one request. The page then comes back short with a cursor.
+	scanBudgetFactor = 20
+)
+
+// ListOptions filters and pages the links listing. Cursor is opaque, it
+// must be the NextCursor of the previous page or empty for the first one.
+type ListOptions struct {
+	Cursor string
+	Limit  int
+	Sort   string
+	// Contains matches a case insensitive substring of the target URL.
+	Contains string
+	// Domain matches the host of the target URL, subdomains included.
+	Domain string
+	// CreatedFrom is inclusive and CreatedTo exclusive, zero values are unbounded.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Tag         string
+	// Tenant only lists the links of the tenant, every link when empty.
+	Tenant string
+	// Host only lists the links on the host, every link when empty.
+	Host string
+}
+
+// LinkPage is a page of the links listing, NextCursor is empty on the last page.
+type LinkPage struct {
+	Links      []Link `json:"links"`
+	NextCursor string `json:"next_cursor"`
+}
+
+func (o ListOptions) withDefaults() ListOptions {
+	if o.Limit <= 0 {
+		o.Limit = DefaultListLimit
+	}
+	if o.Limit > MaxListLimit {
+		o.Limit = MaxListLimit
+	}
+	o.Contains = strings.ToLower(o.Contains)
+	o.Domain = strings.ToLower(strings.TrimPrefix(o.Domain, "."))
+	o.Tag = strings.ToLower(o.Tag)
+	o.Host = strings.ToLower(o.Host)
+	return o
+}
+
+func (o ListOptions) scanBudget() int {
+	return o.Limit * scanBudgetFactor
+}
+
+// Matches reports whether the link passes every filter set in the options.
+func (o ListOptions) Matches(link Link) bool {
+	if o.Tenant != "" && link.Tenant != o.Tenant {
+		return false
+	}
+	if o.Host != "" && link.Host != o.Host {
+		return false
+	}
+
+	if o.Contains != "" && !strings.Contains(strings.ToLower(link.URL), o.Contains) {
+		return false
+	}
+
+	if o.Domain != "" {
+		parsed, err := url.Parse(link.URL)
+		if err != nil {
+			return false
+		}
+		host := strings.ToLower(parsed.Hostname())
+		if host != o.Domain && !strings.HasSuffix(host, "."+o.Domain) {
+			return false
+		}
+	}
+
+	if !o.CreatedFrom.IsZero() && link.CreatedAt.Before(o.CreatedFrom) {
+		return false
+	}
+	if !o.CreatedTo.IsZero() && !link.CreatedAt.Before(o.CreatedTo) {
+		return false
+	}
+
+	if o.Tag != "" && !slices.Contains(link.Tags, o.Tag) {
+		return false
+	}
+
+	return true
+}
+
+// pageLinks filters, sorts and pages links already loaded in memory. The
+// cursor is the last link of the page, its key or its createdCursor, so
+// links saved or deleted meanwhile never shift the next pages.
+func pageLinks(links []Link, opts ListOptions) (LinkPage, error) {
+	matched := make([]Link, 0, len(links))
+	for _, link := range links {
+		if opts.Matches(link) {
+			matched = append(matched, link)
+		}
+	}
+
+	desc := opts.Sort == SortCreatedDesc
+	after := func(link Link) bool { return link.Key() > opts.Cursor }
+	cursorOf := func(link Link) string { return link.Key() }
+	switch opts.Sort {
+	case SortCreatedAsc, SortCreatedDesc:
+		sort.Slice(matched, func(i, j int) bool {
+			if desc {
+				return createdBefore(matched[j], matched[i])
+			}
+			return createdBefore(matched[i], matched[j])
+		})
+		cursorOf = func(link Link) string { return newCreatedCursor(link).String() }
+		if opts.Cursor != "" {
+			cursor, err := parseCreatedCursor(opts.Cursor)
+			if err != nil {
+				return LinkPage{}, err
+			}
+			after = func(link Link) bool { return cursor.before(link, desc) }
+		}
+	default:
+		sortLinks(matched)
+	}
+
+	start := 0
+	if opts.Cursor != "" {
+		start = sort.Search(len(matched), func(i int) bool { return after(matched[i]) })
+	}
+
+	end := min(start+opts.Limit, len(matched))
+	page := LinkPage{Links: matched[start:end]}
+	if end < len(matched) {
+		page.NextCursor = cursorOf(matched[end-1])
+	}
+
+	return page, nil
+}
+
+// createdBefore orders the links by creation time, in milliseconds like
+// the redis index, then by key.
+func createdBefore(a Link, b Link) bool {
+	return newCreatedCursor(a).before(b, false)
+}
+
+// createdCursor is the cursor of the listings sorted by creation date, the
+// creation time in milliseconds and the key of the last link of the page.
+type createdCursor struct {
+	created int64
+	key     string
+}
+
+func newCreatedCursor(link Link) createdCursor {
+	return createdCursor{created: link.CreatedAt.UnixMilli(), key: link.Key()}
+}
+
+func parseCreatedCursor(cursor string) (createdCursor, error) {
+	created, key, ok := strings.Cut(cursor, ":")
+	if !ok || key == "" {
+		return createdCursor{}, fmt.Errorf("invalid cursor %q: %w", cursor, ErrInvalidCursor)
+	}
+
+	ms, err := strconv.ParseInt(created, 10, 64)
+	if err != nil {
+		return createdCursor{}, fmt.Errorf("invalid cursor %q: %w", cursor, ErrInvalidCursor)
+	}
+
+	return createdCursor{created: ms, key: key}, nil
+}
+
+func (c createdCursor) String() string {
+	return strconv.FormatInt(c.created, 10) + ":" + c.key
+}
+
+// before reports whether the cursor comes before the link in the listing
+// sorted by creation date, descending when desc.
+func (c createdCursor) before(link Link, desc bool) bool {
+	other := newCreatedCursor(link)
+	return c.afterEntry(other.created, other.key, desc)
+}
+
+// afterEntry reports whether an index entry, its score and key, comes after
+// the cursor in the listing sorted by creation date, descending when desc.
+func (c createdCursor) afterEntry(created int64, key string, desc bool) bool {
+	if desc {
+		return created < c.created || created == c.created && key < c.key
+	}
+	return created > c.created || created == c.created && key > c.key
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type MemoryApiKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]ApiKey
+	// hashes maps a key hash to its id.
+	hashes map[string]string
+}
+
+func NewMemoryApiKeyRepository() ApiKeyContract {
+	return &MemoryApiKeyRepository{keys: map[string]ApiKey{}, hashes: map[string]string{}}
+}
+
+func cloneApiKey(key ApiKey) ApiKey {
+	key.Scopes = slices.Clone(key.Scopes)
+	if key.RevokedAt != nil {
+		revokedAt := *key.RevokedAt
+		key.RevokedAt = &revokedAt
+	}
+	return key
+}
+
+func (s *MemoryApiKeyRepository) SaveApiKey(ctx context.Context, key ApiKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, idTaken := s.keys[key.ID]
+	_, hashTaken := s.hashes[key.Hash]
+	if idTaken || hashTaken {
+		return fmt.Errorf("failed to save api key %q: %w", key.ID, ErrKeyExists)
+	}
+
+	s.keys[key.ID] = cloneApiKey(key)
+	s.hashes[key.Hash] = key.ID
+	return nil
+}
+
+func (s *MemoryApiKeyRepository) GetApiKeyByHash(ctx context.Context, hash string) (ApiKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[s.hashes[hash]]
+	if !ok {
+		return ApiKey{}, ErrKeyNotFound
+	}
+	return cloneApiKey(key), nil
+}
+
+func (s *MemoryApiKeyRepository) ListApiKeys(ctx context.Context) ([]ApiKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]ApiKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, cloneApiKey(key))
+	}
+	sortApiKeys(keys)
+
+	return keys, nil
+}
+
+func (s *MemoryApiKeyRepository) RevokeApiKey(ctx context.Context, id string) (ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ApiKey{}, fmt.Errorf("failed to revoke api key: %w", ErrKeyNotFound)
+	}
+
+	revokeApiKey(&key, time.Now())
+	s.keys[id] = key
+	return cloneApiKey(key), nil
+}
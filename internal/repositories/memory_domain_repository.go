@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type MemoryDomainRepository struct {
+	mu          sync.RWMutex
+	domains     map[string]Domain
+	defaultName string
+	legacyName  string
+}
+
+func NewMemoryDomainRepository() DomainContract {
+	return &MemoryDomainRepository{domains: map[string]Domain{}}
+}
+
+func (s *MemoryDomainRepository) SaveDomain(ctx context.Context, domain Domain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.domains[domain.Name]; ok {
+		return fmt.Errorf("failed to save domain %q: %w", domain.Name, ErrDomainExists)
+	}
+
+	domain.Default = false
+	s.domains[domain.Name] = domain
+	if s.defaultName == "" {
+		s.defaultName = domain.Name
+	}
+	if s.legacyName == "" {
+		s.legacyName = domain.Name
+	}
+	return nil
+}
+
+func (s *MemoryDomainRepository) ListDomains(ctx context.Context) ([]Domain, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	domains := make([]Domain, 0, len(s.domains))
+	for _, domain := range s.domains {
+		domain.Default = domain.Name == s.defaultName
+		domain.Legacy = domain.Name == s.legacyName
+		domains = append(domains, domain)
+	}
+	sortDomains(domains)
+
+	return domains, nil
+}
+
+func (s *MemoryDomainRepository) DeleteDomain(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.domains[name]; !ok {
+		return fmt.Errorf("failed to delete domain %q: %w", name, ErrDomainNotFound)
+	}
+
+	delete(s.domains, name)
+	if s.defaultName == name {
+		s.defaultName = ""
+	}
+	return nil
+}
+
+func (s *MemoryDomainRepository) SetDefaultDomain(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.domains[name]; !ok {
+		return fmt.Errorf("failed to set default domain %q: %w", name, ErrDomainNotFound)
+	}
+
+	s.defaultName = name
+	return nil
+}
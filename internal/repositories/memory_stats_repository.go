@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryURLStats struct {
+	clicks    int64
+	visitors  map[string]struct{}
+	daily     map[string]int64
+	hourly    map[string]int64
+	referrers map[string]int64
+	countries map[string]int64
+	variants  map[string]int64
+}
+
+// MemoryStatsRepository keeps the click stats in process memory. Unlike the
+// redis one, unique visitors are counted exactly and raw events aren't kept.
+type MemoryStatsRepository struct {
+	mu    sync.RWMutex
+	stats map[string]*memoryURLStats
+}
+
+func NewMemoryStatsRepository() StatsContract {
+	return &MemoryStatsRepository{stats: map[string]*memoryURLStats{}}
+}
+
+func (s *MemoryStatsRepository) RecordClicks(ctx context.Context, clicks []Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, click := range clicks {
+		stats, ok := s.stats[click.Code]
+		if !ok {
+			stats = &memoryURLStats{
+				visitors:  map[string]struct{}{},
+				daily:     map[string]int64{},
+				hourly:    map[string]int64{},
+				referrers: map[string]int64{},
+				countries: map[string]int64{},
+				variants:  map[string]int64{},
+			}
+			s.stats[click.Code] = stats
+		}
+
+		ts := click.Timestamp.UTC()
+		stats.clicks++
+		stats.daily[ts.Format(dayLayout)]++
+		stats.hourly[ts.Format(hourLayout)]++
+		pruneHours(stats.hourly, time.Now())
+		if click.VisitorID != "" {
+			stats.visitors[click.VisitorID] = struct{}{}
+		}
+		if click.Referrer != "" {
+			referrer := click.Referrer
+			if _, ok := stats.referrers[referrer]; !ok && len(stats.referrers) >= maxReferrers {
+				referrer = otherReferrer
+			}
+			stats.referrers[referrer]++
+		}
+		if click.Country != "" {
+			stats.countries[click.Country]++
+		}
+		if click.Variant != "" {
+			stats.variants[click.Variant]++
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStatsRepository) GetStats(ctx context.Context, code string) (URLStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats, ok := s.stats[code]
+	if !ok {
+		return URLStats{
+			Daily:     map[string]int64{},
+			Hourly:    map[string]int64{},
+			Referrers: map[string]int64{},
+			Countries: map[string]int64{},
+			Variants:  map[string]int64{},
+		}, nil
+	}
+
+	hourly := copyCounts(stats.hourly)
+	pruneHours(hourly, time.Now())
+
+	return URLStats{
+		TotalClicks:    stats.clicks,
+		UniqueVisitors: int64(len(stats.visitors)),
+		Daily:          copyCounts(stats.daily),
+		Hourly:         hourly,
+		Referrers:      copyCounts(stats.referrers),
+		Countries:      copyCounts(stats.countries),
+		Variants:       copyCounts(stats.variants),
+	}, nil
+}
+
+// deleteStats removes the stats of the codes, so a link saved later under
+// one of them starts from none.
+func (s *MemoryStatsRepository) deleteStats(codes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range codes {
+		delete(s.stats, code)
+	}
+}
+
+func copyCounts(counts map[string]int64) map[string]int64 {
+	copied := make(map[string]int64, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *MemoryUrlRepository) SaveTenant(ctx context.Context, tenant Tenant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tenants[tenant.ID]; ok {
+		return fmt.Errorf("failed to save tenant %q: %w", tenant.ID, ErrTenantExists)
+	}
+
+	tenant.Links = 0
+	s.tenants[tenant.ID] = tenant
+	return nil
+}
+
+func (s *MemoryUrlRepository) GetTenant(ctx context.Context, id string) (Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenant, ok := s.tenants[id]
+	if !ok {
+		return Tenant{}, fmt.Errorf("failed to get tenant: %w", ErrTenantNotFound)
+	}
+
+	tenant.Links = int64(len(s.tenantLinks[id]))
+	return tenant, nil
+}
+
+func (s *MemoryUrlRepository) ListTenants(ctx context.Context) ([]Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenants := make([]Tenant, 0, len(s.tenants))
+	for id, tenant := range s.tenants {
+		tenant.Links = int64(len(s.tenantLinks[id]))
+		tenants = append(tenants, tenant)
+	}
+
+	sortTenants(tenants)
+	return tenants, nil
+}
+
+func (s *MemoryUrlRepository) UpdateTenant(ctx context.Context, id string, update func(tenant *Tenant) error) (Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenant, ok := s.tenants[id]
+	if !ok {
+		return Tenant{}, fmt.Errorf("failed to get tenant: %w", ErrTenantNotFound)
+	}
+
+	tenant, err := applyTenantUpdate(tenant, update, time.Now())
+	if err != nil {
+		return Tenant{}, fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	tenant.Links = 0
+	s.tenants[id] = tenant
+	tenant.Links = int64(len(s.tenantLinks[id]))
+	return tenant, nil
+}
@@ -0,0 +1,334 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/codegen"
+	"url-shortener/internal/targeting"
+)
+
+// MemoryUrlRepository keeps the links in process memory, meant for local
+// development and tests. Everything is lost when the process stops.
+type MemoryUrlRepository struct {
+	mu    sync.RWMutex
+	links map[string]Link
+	// targets maps the normalized target URLs, scoped by tenant and host,
+	// to a code, see FindURL.
+	targets map[string]string
+	// uses counts the clicks taken by links with a click limit, see ConsumeClick.
+	uses  map[string]int64
+	codes codegen.Generator
+	// tenants holds the tenants and tenantLinks the codes of their links.
+	tenants     map[string]Tenant
+	tenantLinks map[string]map[string]struct{}
+	// stats, when set, loses the stats of the links removed.
+	stats *MemoryStatsRepository
+}
+
+func NewMemoryUrlRepository(codes codegen.Generator) UrlContract {
+	return &MemoryUrlRepository{
+		links:       map[string]Link{},
+		targets:     map[string]string{},
+		uses:        map[string]int64{},
+		codes:       codes,
+		tenants:     map[string]Tenant{},
+		tenantLinks: map[string]map[string]struct{}{},
+	}
+}
+
+// memoryCounter backs the counter code strategy for a single process.
+func memoryCounter() codegen.NextFunc {
+	var counter atomic.Uint64
+	return func(ctx context.Context) (uint64, error) {
+		return counter.Add(1), nil
+	}
+}
+
+func (s *MemoryUrlRepository) SaveShortenedURL(ctx context.Context, link Link) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveGenerated(ctx, link)
+}
+
+func (s *MemoryUrlRepository) SaveURLWithCode(ctx context.Context, code string, link Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[LinkKey(link.Host, code)]; ok {
+		return fmt.Errorf("failed to save code %q: %w", code, ErrCodeTaken)
+	}
+	if err := s.checkQuota(link.Tenant); err != nil {
+		return fmt.Errorf("failed to save code %q: %w", code, err)
+	}
+
+	s.save(code, link)
+	return nil
+}
+
+func (s *MemoryUrlRepository) SaveURLs(ctx context.Context, links []Link) ([]SaveResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]SaveResult, len(links))
+	for i, link := range links {
+		if link.Code != "" {
+			if _, ok := s.links[link.Key()]; ok {
+				results[i].Err = fmt.Errorf("failed to save code %q: %w", link.Code, ErrCodeTaken)
+				continue
+			}
+			if err := s.checkQuota(link.Tenant); err != nil {
+				results[i].Err = fmt.Errorf("failed to save code %q: %w", link.Code, err)
+				continue
+			}
+			s.save(link.Code, link)
+			results[i].Code = link.Code
+			continue
+		}
+
+		code, err := s.saveGenerated(ctx, link)
+		results[i] = SaveResult{Code: code, Err: err}
+	}
+
+	return results, nil
+}
+
+// saveGenerated saves the link under the first free code generated, it
+// must be called with the write lock held.
+func (s *MemoryUrlRepository) saveGenerated(ctx context.Context, link Link) (string, error) {
+	if err := s.checkQuota(link.Tenant); err != nil {
+		return "", fmt.Errorf("failed to save url: %w", err)
+	}
+
+	return saveGenerated(ctx, s.codes, link, func(code string) error {
+		if _, ok := s.links[LinkKey(link.Host, code)]; ok {
+			return ErrCodeTaken
+		}
+		if key := hostlessKey(link.Host, code); key != "" {
+			if _, ok := s.links[key]; ok {
+				return ErrCodeTaken
+			}
+		}
+		s.save(code, link)
+		return nil
+	})
+}
+
+// save stores the link under the code on its host, it must be called with
+// the write lock held.
+func (s *MemoryUrlRepository) save(code string, link Link) {
+	link = prepareNewLink(link, time.Now())
+	link.Code = code
+	link.Clicks = 0
+	key := link.Key()
+	s.links[key] = cloneLink(link)
+	delete(s.uses, key)
+	s.indexTarget(key, link)
+	if link.Tenant != "" {
+		if s.tenantLinks[link.Tenant] == nil {
+			s.tenantLinks[link.Tenant] = map[string]struct{}{}
+		}
+		s.tenantLinks[link.Tenant][key] = struct{}{}
+	}
+}
+
+// checkQuota must be called with the write lock held.
+func (s *MemoryUrlRepository) checkQuota(tenant string) error {
+	if tenant == "" {
+		return nil
+	}
+	if max := s.tenants[tenant].MaxLinks; max > 0 && int64(len(s.tenantLinks[tenant])) >= max {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// indexTarget and unindexTarget must be called with the write lock held.
+func (s *MemoryUrlRepository) indexTarget(code string, link Link) {
+	key := link.targetKey()
+	if key == "" {
+		return
+	}
+	if current, ok := s.targets[key]; ok && current != code {
+		if _, exists := s.links[current]; exists {
+			return
+		}
+	}
+	s.targets[key] = code
+}
+
+func (s *MemoryUrlRepository) unindexTarget(code string, link Link) {
+	if key := link.targetKey(); key != "" && s.targets[key] == code {
+		delete(s.targets, key)
+	}
+}
+
+// remove must be called with the write lock held.
+func (s *MemoryUrlRepository) remove(code string) {
+	s.unindexTarget(code, s.links[code])
+	delete(s.tenantLinks[s.links[code].Tenant], code)
+	delete(s.links, code)
+	delete(s.uses, code)
+	if s.stats != nil {
+		s.stats.deleteStats(code)
+	}
+}
+
+func (s *MemoryUrlRepository) GetURL(ctx context.Context, code string) (Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link, ok := s.links[code]
+	if !ok {
+		return Link{}, fmt.Errorf("failed to get url: %w", ErrNotFound)
+	}
+
+	if link.Expired(time.Now()) {
+		return cloneLink(link), fmt.Errorf("failed to get url: %w", ErrExpired)
+	}
+
+	return cloneLink(link), nil
+}
+
+func (s *MemoryUrlRepository) FindURL(ctx context.Context, tenant, host, target string) (Link, error) {
+	s.mu.RLock()
+	code, ok := s.targets[targetKey(tenant, host, target)]
+	s.mu.RUnlock()
+	if !ok {
+		return Link{}, fmt.Errorf("failed to find url: %w", ErrNotFound)
+	}
+
+	return s.GetURL(ctx, code)
+}
+
+func (s *MemoryUrlRepository) ListURL(ctx context.Context, opts ListOptions) (LinkPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := make([]Link, 0, len(s.links))
+	if opts.Tenant != "" {
+		for code := range s.tenantLinks[opts.Tenant] {
+			links = append(links, cloneLink(s.links[code]))
+		}
+	} else {
+		for _, link := range s.links {
+			links = append(links, cloneLink(link))
+		}
+	}
+
+	page, err := pageLinks(links, opts.withDefaults())
+	if err != nil {
+		return LinkPage{}, fmt.Errorf("failed to list urls: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *MemoryUrlRepository) DeleteURL(ctx context.Context, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[code]; !ok {
+		return fmt.Errorf("failed to get url: %w", ErrNotFound)
+	}
+
+	s.remove(code)
+	return nil
+}
+
+func (s *MemoryUrlRepository) UpdateURL(ctx context.Context, code string, update func(link *Link) error) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[code]
+	if !ok {
+		return Link{}, fmt.Errorf("failed to get url: %w", ErrNotFound)
+	}
+
+	previous := link
+	link = cloneLink(link)
+	if err := update(&link); err != nil {
+		return Link{}, fmt.Errorf("failed to update url: %w", err)
+	}
+	link.Code, link.Host = previous.Code, previous.Host
+	link.Clicks = previous.Clicks
+	link.Tenant = previous.Tenant
+	link.UpdatedAt = time.Now()
+
+	if previous.targetKey() != link.targetKey() {
+		s.unindexTarget(code, previous)
+	}
+	s.links[code] = cloneLink(link)
+	s.indexTarget(code, link)
+
+	return link, nil
+}
+
+func (s *MemoryUrlRepository) IncrementClicks(ctx context.Context, clicks map[string]int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for code, count := range clicks {
+		link, ok := s.links[code]
+		if !ok {
+			continue
+		}
+		link.Clicks += count
+		s.links[code] = link
+	}
+
+	return nil
+}
+
+func (s *MemoryUrlRepository) ConsumeClick(ctx context.Context, code string, max int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[code]; !ok {
+		return fmt.Errorf("failed to consume click: %w", ErrNotFound)
+	}
+
+	if s.uses[code] >= max {
+		return fmt.Errorf("failed to consume click: %w", ErrExhausted)
+	}
+	s.uses[code]++
+
+	return nil
+}
+
+func (s *MemoryUrlRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for code, link := range s.links {
+		if !link.Expired(before) {
+			continue
+		}
+		s.remove(code)
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// cloneLink copies the link so callers can't change the stored one through
+// its slices and pointers.
+func cloneLink(link Link) Link {
+	if link.Tags != nil {
+		link.Tags = append([]string(nil), link.Tags...)
+	}
+	if link.ExpiresAt != nil {
+		expiresAt := *link.ExpiresAt
+		link.ExpiresAt = &expiresAt
+	}
+	link.Rules = targeting.Clone(link.Rules)
+	link.Variants = slices.Clone(link.Variants)
+	link.Query = maps.Clone(link.Query)
+	return link
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/internal/codegen"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestMigrateLegacyURLs_Redis(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	// layout written before links had metadata
+	require.NoError(t, rdb.HSet(ctx, urlsKey, "legacy", "https://legacy.com", "expiring", "https://expiring.com").Err())
+	require.NoError(t, rdb.ZAdd(ctx, expiryKey, redis.Z{Score: float64(expiresAt.Unix()), Member: "expiring"}).Err())
+
+	repo := NewUrlRepository(rdb, codegen.NewRandom(codegen.Base62, codegen.DefaultLength))
+	require.NoError(t, repo.SaveURLWithCode(ctx, "current", Link{URL: "https://current.com"}))
+
+	link, err := repo.GetURL(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "https://legacy.com", link.URL)
+
+	migrated, err := repo.(LegacyMigrator).MigrateLegacyURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), migrated)
+
+	value, err := rdb.HGet(ctx, urlsKey, "expiring").Result()
+	require.NoError(t, err)
+	assert.False(t, isLegacyValue(value))
+
+	link, err = repo.GetURL(ctx, "expiring")
+	require.NoError(t, err)
+	assert.Equal(t, "https://expiring.com", link.URL)
+	require.NotNil(t, link.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*link.ExpiresAt))
+
+	// legacy links have no creation date, they come first in the index
+	page, err := repo.ListURL(ctx, ListOptions{Sort: SortCreatedAsc})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 3)
+	assert.Equal(t, "current", page.Links[2].Code)
+
+	link, err = repo.FindURL(ctx, "", "", "https://legacy.com/")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", link.Code)
+
+	// once done it doesn't scan the links again
+	assert.True(t, mr.Exists(migratedKey))
+	require.NoError(t, rdb.HSet(ctx, urlsKey, "late", "https://late.com").Err())
+	migrated, err = repo.(LegacyMigrator).MigrateLegacyURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), migrated)
+	value, err = rdb.HGet(ctx, urlsKey, "late").Result()
+	require.NoError(t, err)
+	assert.True(t, isLegacyValue(value))
+}
+
+func TestMigrateLegacyURLs_Bolt(t *testing.T) {
+	ctx := context.Background()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewBoltUrlRepository(db, codegen.NewRandom(codegen.Base62, codegen.DefaultLength))
+	require.NoError(t, err)
+
+	// layout written before links had metadata
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltUrlsBucket).Put([]byte("legacy"), []byte("https://legacy.com"))
+	}))
+
+	migrated, err := repo.(LegacyMigrator).MigrateLegacyURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), migrated)
+
+	link, err := repo.GetURL(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "https://legacy.com", link.URL)
+}
+
+func TestFindURLSkipsLinksNoLongerIndexed_Redis(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	repo := NewUrlRepository(rdb, codegen.NewRandom(codegen.Base62, codegen.DefaultLength))
+
+	// indexed by a previous version, which indexed the redirect options
+	require.NoError(t, repo.SaveURLWithCode(ctx, "temporary", Link{URL: "https://temporary.com", RedirectStatus: 302}))
+	require.NoError(t, rdb.HSet(ctx, targetsKey, targetKey("", "", "https://temporary.com"), "temporary").Err())
+
+	_, err := repo.FindURL(ctx, "", "", "https://temporary.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
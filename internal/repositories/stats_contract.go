@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+	"time"
+)
+
+// Click is a single redirect event.
+type Click struct {
+	Code      string
+	Timestamp time.Time
+	Referrer  string
+	UserAgent string
+	Country   string
+	RequestID string
+	// VisitorID is an opaque identifier of the visitor, used to count unique visitors.
+	VisitorID string
+	// Variant is the name of the variant of a split link the visitor was
+	// sent to, empty for other links.
+	Variant string
+}
+
+type URLStats struct {
+	TotalClicks    int64            `json:"total_clicks"`
+	UniqueVisitors int64            `json:"unique_visitors"`
+	Daily          map[string]int64 `json:"daily"`
+	Hourly         map[string]int64 `json:"hourly"`
+	Referrers      map[string]int64 `json:"referrers"`
+	Countries      map[string]int64 `json:"countries"`
+	// Variants counts the clicks of each variant of a split link.
+	Variants map[string]int64 `json:"variants"`
+}
+
+type StatsContract interface {
+	RecordClicks(ctx context.Context, clicks []Click) error
+	GetStats(ctx context.Context, code string) (URLStats, error)
+}
@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	dayLayout  = "2006-01-02"
+	hourLayout = "2006-01-02T15"
+
+	// eventsMaxLen is the approximate number of raw click events kept per code.
+	eventsMaxLen = 1000
+
+	// hourlyRetention is how long the hourly buckets are kept, the daily
+	// ones being kept along with the link.
+	hourlyRetention = 7 * 24 * time.Hour
+	// maxReferrers bounds the referrers counted per code, the clicks of the
+	// ones past it counting as otherReferrer.
+	maxReferrers  = 100
+	otherReferrer = "other"
+)
+
+// statsSuffixes are the stats keys of a code, besides its hourly buckets.
+var statsSuffixes = []string{"clicks", "daily", "hourly", "visitors", "referrers", "countries", "variants", "events"}
+
+// incrReferrerScript counts the click of the referrer, or of otherReferrer
+// once the code has maxReferrers of them.
+var incrReferrerScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 or redis.call('HLEN', KEYS[1]) < tonumber(ARGV[2]) then
+	return redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[3], 1)
+`)
+
+type StatsRepository struct {
+	rdb *redis.Client
+}
+
+func NewStatsRepository(rdb *redis.Client) StatsContract {
+	return &StatsRepository{rdb: rdb}
+}
+
+func statsKey(code string, suffix string) string {
+	return "encurtador:stats:" + code + ":" + suffix
+}
+
+// hourlyKey is the key of the hourly buckets of the day, which expires
+// once they are all past hourlyRetention.
+func hourlyKey(code string, day time.Time) string {
+	return statsKey(code, "hourly:"+day.Format(dayLayout))
+}
+
+// hourlyDays returns the days whose hourly buckets are kept at now, from
+// the oldest one.
+func hourlyDays(now time.Time) []time.Time {
+	now = now.UTC()
+	var days []time.Time
+	for day := now.Add(-hourlyRetention).Truncate(24 * time.Hour); !day.After(now); day = day.Add(24 * time.Hour) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// hourlyCutoff is the oldest hour kept at now, in hourLayout.
+func hourlyCutoff(now time.Time) string {
+	return now.UTC().Add(-hourlyRetention).Format(hourLayout)
+}
+
+// deleteStats removes the stats of the code, so a link saved later under
+// it starts from none.
+func deleteStats(ctx context.Context, pipe redis.Pipeliner, code string) {
+	keys := make([]string, 0, len(statsSuffixes))
+	for _, suffix := range statsSuffixes {
+		keys = append(keys, statsKey(code, suffix))
+	}
+	for _, day := range hourlyDays(time.Now()) {
+		keys = append(keys, hourlyKey(code, day))
+	}
+	pipe.Del(ctx, keys...)
+}
+
+func (s *StatsRepository) RecordClicks(ctx context.Context, clicks []Click) error {
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, click := range clicks {
+			ts := click.Timestamp.UTC()
+
+			pipe.Incr(ctx, statsKey(click.Code, "clicks"))
+			pipe.HIncrBy(ctx, statsKey(click.Code, "daily"), ts.Format(dayLayout), 1)
+			hourly := hourlyKey(click.Code, ts)
+			pipe.HIncrBy(ctx, hourly, ts.Format(hourLayout), 1)
+			pipe.ExpireAt(ctx, hourly, ts.Truncate(24*time.Hour).Add(24*time.Hour+hourlyRetention))
+			if click.VisitorID != "" {
+				pipe.PFAdd(ctx, statsKey(click.Code, "visitors"), click.VisitorID)
+			}
+			if click.Referrer != "" {
+				// Eval instead of Run, a pipeline can't fall back from EVALSHA
+				incrReferrerScript.Eval(ctx, pipe, []string{statsKey(click.Code, "referrers")}, click.Referrer, maxReferrers, otherReferrer)
+			}
+			if click.Country != "" {
+				pipe.HIncrBy(ctx, statsKey(click.Code, "countries"), click.Country, 1)
+			}
+			if click.Variant != "" {
+				pipe.HIncrBy(ctx, statsKey(click.Code, "variants"), click.Variant, 1)
+			}
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: statsKey(click.Code, "events"),
+				MaxLen: eventsMaxLen,
+				Approx: true,
+				Values: map[string]any{
+					"timestamp":  ts.Unix(),
+					"referrer":   click.Referrer,
+					"user_agent": click.UserAgent,
+					"country":    click.Country,
+					"request_id": click.RequestID,
+					"visitor_id": click.VisitorID,
+					"variant":    click.Variant,
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record clicks: %w", err)
+	}
+
+	return nil
+}
+
+func (s *StatsRepository) GetStats(ctx context.Context, code string) (URLStats, error) {
+	pipe := s.rdb.Pipeline()
+	clicksCmd := pipe.Get(ctx, statsKey(code, "clicks"))
+	visitorsCmd := pipe.PFCount(ctx, statsKey(code, "visitors"))
+	dailyCmd := pipe.HGetAll(ctx, statsKey(code, "daily"))
+	days := hourlyDays(time.Now())
+	hourlyCmds := make([]*redis.MapStringStringCmd, len(days))
+	for i, day := range days {
+		hourlyCmds[i] = pipe.HGetAll(ctx, hourlyKey(code, day))
+	}
+	referrersCmd := pipe.HGetAll(ctx, statsKey(code, "referrers"))
+	countriesCmd := pipe.HGetAll(ctx, statsKey(code, "countries"))
+	variantsCmd := pipe.HGetAll(ctx, statsKey(code, "variants"))
+	// a code without clicks has no counter yet, so redis.Nil is expected there
+	_, _ = pipe.Exec(ctx)
+
+	clicks, err := clicksCmd.Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return URLStats{}, fmt.Errorf("failed to get clicks: %w", err)
+	}
+
+	visitors, err := visitorsCmd.Result()
+	if err != nil {
+		return URLStats{}, fmt.Errorf("failed to get unique visitors: %w", err)
+	}
+
+	stats := URLStats{
+		TotalClicks:    clicks,
+		UniqueVisitors: visitors,
+		Daily:          map[string]int64{},
+		Hourly:         map[string]int64{},
+		Referrers:      map[string]int64{},
+		Countries:      map[string]int64{},
+		Variants:       map[string]int64{},
+	}
+	type statsBucket struct {
+		cmd  *redis.MapStringStringCmd
+		dest map[string]int64
+	}
+	buckets := []statsBucket{
+		{dailyCmd, stats.Daily},
+		{referrersCmd, stats.Referrers},
+		{countriesCmd, stats.Countries},
+		{variantsCmd, stats.Variants},
+	}
+	for _, cmd := range hourlyCmds {
+		buckets = append(buckets, statsBucket{cmd, stats.Hourly})
+	}
+	for _, bucket := range buckets {
+		values, err := bucket.cmd.Result()
+		if err != nil {
+			return URLStats{}, fmt.Errorf("failed to get stats buckets: %w", err)
+		}
+
+		for key, value := range values {
+			count, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return URLStats{}, fmt.Errorf("invalid counter %q: %w", key, err)
+			}
+			bucket.dest[key] = count
+		}
+	}
+	pruneHours(stats.Hourly, time.Now())
+
+	return stats, nil
+}
+
+// pruneHours drops the hourly buckets past hourlyRetention at now.
+func pruneHours(hourly map[string]int64, now time.Time) {
+	cutoff := hourlyCutoff(now)
+	for hour := range hourly {
+		if hour < cutoff {
+			delete(hourly, hour)
+		}
+	}
+}
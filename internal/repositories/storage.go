@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"url-shortener/internal/codegen"
+
+	"github.com/redis/go-redis/v9"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+	BackendBolt   = "bolt"
+
+	// codeAttempts bounds how many generated codes are tried for a link
+	// before giving up with ErrCodeTaken.
+	codeAttempts = 5
+	// domainCacheTTL bounds how long the domain changes made by another
+	// instance take to show up, see CachedDomainRepository.
+	domainCacheTTL = 5 * time.Second
+)
+
+type StorageOptions struct {
+	// Backend is one of BackendRedis, BackendMemory or BackendBolt.
+	Backend  string
+	Redis    *redis.Options
+	BoltPath string
+	// Codes selects how the codes of new links are generated.
+	Codes codegen.Options
+}
+
+// Storage groups the repositories of the selected backend.
+type Storage struct {
+	Urls  UrlContract
+	Stats StatsContract
+	Keys  ApiKeyContract
+	// Tenants is served by Urls, which enforces their quotas.
+	Tenants TenantContract
+	Domains DomainContract
+	// Redis is the client of the redis backend, nil for the other ones.
+	Redis *redis.Client
+
+	close func() error
+}
+
+func NewStorage(opts StorageOptions) (*Storage, error) {
+	switch opts.Backend {
+	case BackendRedis:
+		rdb := redis.NewClient(opts.Redis)
+		codes, err := codegen.New(opts.Codes, redisCounter(rdb))
+		if err != nil {
+			rdb.Close()
+			return nil, err
+		}
+
+		urls := NewUrlRepository(rdb, codes)
+		return &Storage{
+			Urls:    urls,
+			Stats:   NewStatsRepository(rdb),
+			Keys:    NewApiKeyRepository(rdb),
+			Tenants: urls.(TenantContract),
+			Domains: NewCachedDomainRepository(NewDomainRepository(rdb), domainCacheTTL),
+			Redis:   rdb,
+			close:   rdb.Close,
+		}, nil
+
+	case BackendMemory:
+		codes, err := codegen.New(opts.Codes, memoryCounter())
+		if err != nil {
+			return nil, err
+		}
+
+		urls := NewMemoryUrlRepository(codes)
+		stats := NewMemoryStatsRepository()
+		// the stats are removed along with the links
+		urls.(*MemoryUrlRepository).stats = stats.(*MemoryStatsRepository)
+		return &Storage{
+			Urls:    urls,
+			Stats:   stats,
+			Keys:    NewMemoryApiKeyRepository(),
+			Tenants: urls.(TenantContract),
+			Domains: NewMemoryDomainRepository(),
+			close:   func() error { return nil },
+		}, nil
+
+	case BackendBolt:
+		if err := os.MkdirAll(filepath.Dir(opts.BoltPath), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create bolt directory: %w", err)
+		}
+
+		db, err := bolt.Open(opts.BoltPath, 0o600, &bolt.Options{Timeout: time.Second})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open bolt file: %w", err)
+		}
+
+		codes, err := codegen.New(opts.Codes, boltCounter(db))
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		urls, err := NewBoltUrlRepository(db, codes)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		stats, err := NewBoltStatsRepository(db)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		keys, err := NewBoltApiKeyRepository(db)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		domains, err := NewBoltDomainRepository(db)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		return &Storage{Urls: urls, Stats: stats, Keys: keys, Tenants: urls.(TenantContract), Domains: NewCachedDomainRepository(domains, domainCacheTTL), close: db.Close}, nil
+	}
+
+	return nil, fmt.Errorf("unknown storage backend %q", opts.Backend)
+}
+
+func (s *Storage) Close() error {
+	return s.close()
+}
+
+// saveGenerated saves the link under the first generated code save accepts.
+// save must only store the link if the code is free, atomically, returning
+// ErrCodeTaken otherwise, so taken codes are retried and never overwritten.
+func saveGenerated(ctx context.Context, codes codegen.Generator, link Link, save func(code string) error) (string, error) {
+	for attempt := range codeAttempts {
+		code, err := codes.Generate(ctx, link.URL, attempt)
+		if err != nil {
+			return "", err
+		}
+
+		err = save(code)
+		if err == nil {
+			return code, nil
+		}
+		if !errors.Is(err, ErrCodeTaken) {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("failed to generate a free code: %w", ErrCodeTaken)
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrTenantNotFound is returned when no tenant has the id.
+	ErrTenantNotFound = errors.New("tenant not found")
+	// ErrTenantExists is returned when saving a tenant whose id is taken.
+	ErrTenantExists = errors.New("tenant already exists")
+	// ErrQuotaExceeded is returned when saving a link would take its tenant
+	// past its MaxLinks.
+	ErrQuotaExceeded = errors.New("tenant link quota exceeded")
+)
+
+// Tenant is a workspace sharing the deployment. Its links are listed and
+// managed apart from the other tenants ones, through the API keys issued
+// to it. Codes are still unique across tenants, as short URLs don't say
+// which tenant they belong to.
+type Tenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// MaxLinks caps how many links the tenant holds, zero meaning unlimited.
+	MaxLinks int64 `json:"max_links"`
+	// Links is how many links the tenant holds, expired ones not removed yet
+	// included. It is counted when the tenant is read, never stored.
+	Links     int64     `json:"links"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TenantContract is the storage contract for tenants, implemented by the
+// URL repository of every storage backend, which enforces their quotas
+// when saving links.
+type TenantContract interface {
+	// SaveTenant stores a new tenant, returning ErrTenantExists if the id is taken.
+	SaveTenant(ctx context.Context, tenant Tenant) error
+	// GetTenant returns the tenant along with its count of links.
+	GetTenant(ctx context.Context, id string) (Tenant, error)
+	// ListTenants returns every tenant along with its count of links, by id.
+	ListTenants(ctx context.Context) ([]Tenant, error)
+	// UpdateTenant applies update to the stored tenant atomically and returns
+	// the updated tenant. An error returned by update aborts the change.
+	UpdateTenant(ctx context.Context, id string, update func(tenant *Tenant) error) (Tenant, error)
+}
+
+func encodeTenant(tenant Tenant) (string, error) {
+	tenant.Links = 0
+	data, err := json.Marshal(tenant)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tenant %q: %w", tenant.ID, err)
+	}
+	return string(data), nil
+}
+
+func decodeTenant(value string) (Tenant, error) {
+	var tenant Tenant
+	if err := json.Unmarshal([]byte(value), &tenant); err != nil {
+		return Tenant{}, fmt.Errorf("failed to decode tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// applyTenantUpdate runs update on a copy of the tenant, keeping its id and
+// creation time.
+func applyTenantUpdate(tenant Tenant, update func(tenant *Tenant) error, now time.Time) (Tenant, error) {
+	updated := tenant
+	if err := update(&updated); err != nil {
+		return Tenant{}, err
+	}
+	updated.ID = tenant.ID
+	updated.CreatedAt = tenant.CreatedAt
+	updated.UpdatedAt = now
+	return updated, nil
+}
+
+func sortTenants(tenants []Tenant) {
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+}
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// saveTenantScript stores the tenant and its quota only if the id is free.
+var saveTenantScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+return 1
+`)
+
+func (s *UrlRepository) SaveTenant(ctx context.Context, tenant Tenant) error {
+	value, err := encodeTenant(tenant)
+	if err != nil {
+		return err
+	}
+
+	ok, err := saveTenantScript.Run(ctx, s.rdb, []string{tenantsKey, tenantQuotasKey}, tenant.ID, value, tenant.MaxLinks).Bool()
+	if err != nil {
+		return fmt.Errorf("error setting on redis: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("failed to save tenant %q: %w", tenant.ID, ErrTenantExists)
+	}
+
+	return nil
+}
+
+func (s *UrlRepository) GetTenant(ctx context.Context, id string) (Tenant, error) {
+	pipe := s.rdb.Pipeline()
+	valueCmd := pipe.HGet(ctx, tenantsKey, id)
+	linksCmd := pipe.ZCard(ctx, tenantLinksKey(id))
+	// errors are checked per command below, redis.Nil included
+	_, _ = pipe.Exec(ctx)
+
+	value, err := valueCmd.Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return Tenant{}, fmt.Errorf("failed to get tenant: %w", ErrTenantNotFound)
+		}
+		return Tenant{}, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	tenant, err := decodeTenant(value)
+	if err != nil {
+		return Tenant{}, err
+	}
+
+	tenant.Links, err = linksCmd.Result()
+	if err != nil {
+		return Tenant{}, fmt.Errorf("failed to count tenant links: %w", err)
+	}
+
+	return tenant, nil
+}
+
+func (s *UrlRepository) ListTenants(ctx context.Context) ([]Tenant, error) {
+	values, err := s.rdb.HVals(ctx, tenantsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	tenants := make([]Tenant, 0, len(values))
+	for _, value := range values {
+		tenant, err := decodeTenant(value)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+
+	if len(tenants) > 0 {
+		cmds := make([]*redis.IntCmd, len(tenants))
+		_, err = s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, tenant := range tenants {
+				cmds[i] = pipe.ZCard(ctx, tenantLinksKey(tenant.ID))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count tenant links: %w", err)
+		}
+		for i, cmd := range cmds {
+			tenants[i].Links = cmd.Val()
+		}
+	}
+
+	sortTenants(tenants)
+	return tenants, nil
+}
+
+func (s *UrlRepository) UpdateTenant(ctx context.Context, id string, update func(tenant *Tenant) error) (Tenant, error) {
+	var updated Tenant
+	txf := func(tx *redis.Tx) error {
+		value, err := tx.HGet(ctx, tenantsKey, id).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return ErrTenantNotFound
+			}
+			return err
+		}
+
+		tenant, err := decodeTenant(value)
+		if err != nil {
+			return err
+		}
+
+		tenant, err = applyTenantUpdate(tenant, update, time.Now())
+		if err != nil {
+			return err
+		}
+
+		value, err = encodeTenant(tenant)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, tenantsKey, id, value)
+			pipe.HSet(ctx, tenantQuotasKey, id, strconv.FormatInt(tenant.MaxLinks, 10))
+			return nil
+		})
+		updated = tenant
+		return err
+	}
+
+	for range updateRetries {
+		err := s.rdb.Watch(ctx, txf, tenantsKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return Tenant{}, fmt.Errorf("failed to update tenant: %w", err)
+		}
+
+		updated.Links, err = s.rdb.ZCard(ctx, tenantLinksKey(id)).Result()
+		if err != nil {
+			return Tenant{}, fmt.Errorf("failed to count tenant links: %w", err)
+		}
+
+		return updated, nil
+	}
+
+	return Tenant{}, fmt.Errorf("failed to update tenant: %w", redis.TxFailedErr)
+}
@@ -1,11 +1,79 @@
 package repositories
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
+var (
+	// ErrNotFound is returned when no URL is bound to the code.
+	ErrNotFound = errors.New("url not found")
+	// ErrCodeTaken is returned when a code is already bound to another URL.
+	ErrCodeTaken = errors.New("code already exists")
+	// ErrExpired is returned when a code exists but its expiration time has passed.
+	ErrExpired = errors.New("url expired")
+	// ErrExhausted is returned when a link already took all its allowed clicks.
+	ErrExhausted = errors.New("url exhausted")
+	// ErrInvalidCursor is returned when a listing cursor can't be parsed.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// UrlContract is the storage contract for shortened URLs, implemented by
+// every storage backend. Links are saved under their code on their host,
+// the code taken by the other methods being the link key, see LinkKey.
 type UrlContract interface {
-	SaveShortenedURL(ctx context.Context, _url string) (string, error)
-	GetURL(ctx context.Context, code string) (string, error)
-	GetAllURL(ctx context.Context) (map[string]string, error)
+	// SaveShortenedURL stores the link under a newly generated code, free on
+	// the link host, and returns the code. The code of a link bound to a
+	// host is never the one of a link without a host either, as the legacy
+	// domain serves those first.
+	SaveShortenedURL(ctx context.Context, link Link) (string, error)
+	// SaveURLWithCode stores the link under the given code only if the code
+	// is free on the link host, returning ErrCodeTaken otherwise.
+	SaveURLWithCode(ctx context.Context, code string, link Link) error
+	// SaveURLs saves a batch of links, each under its Code, or a generated
+	// one when empty. A link failing doesn't fail the others, there is one
+	// result per link in the same order, and the error is only returned
+	// when the batch as a whole failed.
+	SaveURLs(ctx context.Context, links []Link) ([]SaveResult, error)
+	// GetURL returns the link bound to the code. An expired link is still
+	// returned, along with ErrExpired.
+	GetURL(ctx context.Context, code string) (Link, error)
+	// FindURL returns the link of the tenant and the host whose target is
+	// the URL, both compared in their normalized form, with the same errors
+	// as GetURL. When several links share a target only one of them is
+	// indexed, the first one saved while none was, and the links a reuse
+	// must skip, disabled, expiring or with redirect options, are never
+	// indexed.
+	FindURL(ctx context.Context, tenant, host, target string) (Link, error)
+	// ListURL returns a page of the links matching the options.
+	ListURL(ctx context.Context, opts ListOptions) (LinkPage, error)
 	DeleteURL(ctx context.Context, code string) error
-	UpdateURL(ctx context.Context, code string, newURL string) (string, error)
+	// UpdateURL applies update to the stored link atomically and returns the
+	// updated link. An error returned by update aborts the change.
+	UpdateURL(ctx context.Context, code string, update func(link *Link) error) (Link, error)
+	// IncrementClicks adds the given amount of clicks to each code, codes
+	// that no longer exist are ignored.
+	IncrementClicks(ctx context.Context, clicks map[string]int64) error
+	// ConsumeClick takes one of the max clicks allowed to the code,
+	// returning ErrExhausted once all of them were taken. The check and the
+	// count happen atomically, so concurrent redirects never exceed max.
+	ConsumeClick(ctx context.Context, code string, max int64) error
+	// DeleteExpired removes every URL that expired before the given time and
+	// returns how many were removed.
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SaveResult is the outcome of saving one link of a batch.
+type SaveResult struct {
+	Code string
+	Err  error
+}
+
+// LegacyMigrator is implemented by the persistent backends that may hold
+// URLs stored before links had metadata.
+type LegacyMigrator interface {
+	// MigrateLegacyURLs rewrites bare URL entries as links and returns how
+	// many were migrated.
+	MigrateLegacyURLs(ctx context.Context) (int64, error)
 }
@@ -4,83 +4,879 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"url-shortener/internal/utils"
+	"strconv"
+	"time"
+	"url-shortener/internal/codegen"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	urlsKey   = "encurtador"
+	expiryKey = "encurtador:expiry"
+	clicksKey = "encurtador:clicks"
+	// createdKey indexes the codes by creation time, in milliseconds, for
+	// the listing sorted by creation date.
+	createdKey = "encurtador:created"
+	// targetsKey maps the normalized target URLs, scoped by tenant and
+	// host, to a code, see FindURL.
+	targetsKey = "encurtador:targets"
+	// usesKey counts the redirects taken from links with a click limit, kept
+	// apart from the clicks, which are only counted asynchronously.
+	usesKey = "encurtador:uses"
+	// counterKey is the counter of the counter code strategy.
+	counterKey = "encurtador:counter"
+	// migratedKey is set once MigrateLegacyURLs went through every link.
+	migratedKey = "encurtador:migrated:v1"
+	// tenantsKey holds the tenants, their link quota being kept apart in
+	// tenantQuotasKey for saveWithCodeScript. The links of each tenant are
+	// indexed by creation time under tenantKeyPrefix, see tenantLinksKey,
+	// while the links themselves share urlsKey, codes being global.
+	tenantsKey      = "encurtador:tenants"
+	tenantQuotasKey = "encurtador:tenants:max_links"
+	tenantKeyPrefix = "encurtador:tenant:"
+
+	// expiredBatchSize bounds how many expired codes are removed per round trip.
+	expiredBatchSize = 500
+	// updateRetries bounds how many times an update is retried when the
+	// links hash changes while the update is in flight.
+	updateRetries = 10
+)
+
+// saveWithCodeScript sets the link only if the code is free and keeps the
+// expiry, creation, target and tenant indexes in sync within the same
+// atomic step. It returns 1 once saved, 0 when the code, or the hostless
+// key in ARGV[7], is taken and -1 when the tenant in ARGV[6] already holds
+// its quota of links.
+var saveWithCodeScript = redis.NewScript(`
+if ARGV[6] ~= '' then
+	local max = tonumber(redis.call('HGET', KEYS[7], ARGV[6]) or '0')
+	if max > 0 and redis.call('ZCARD', KEYS[8]) >= max then
+		return -1
+	end
+end
+if ARGV[7] ~= '' and redis.call('HEXISTS', KEYS[1], ARGV[7]) == 1 then
+	return 0
+end
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+if ARGV[3] == '' then
+	redis.call('ZREM', KEYS[2], ARGV[1])
+else
+	redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+end
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[6], ARGV[1])
+redis.call('ZADD', KEYS[4], ARGV[4], ARGV[1])
+if ARGV[5] ~= '' then
+	local current = redis.call('HGET', KEYS[5], ARGV[5])
+	if not current or redis.call('HEXISTS', KEYS[1], current) == 0 then
+		redis.call('HSET', KEYS[5], ARGV[5], ARGV[1])
+	end
+end
+if ARGV[6] ~= '' then
+	redis.call('ZADD', KEYS[8], ARGV[4], ARGV[1])
+end
+return 1
+`)
+
+// indexTargetScript points the target to the code, unless it already points
+// to another existing code, keeping the first link saved.
+var indexTargetScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], ARGV[1])
+if current and current ~= ARGV[2] and redis.call('HEXISTS', KEYS[2], current) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// unindexTargetScript removes the target only if it still points to the code.
+var unindexTargetScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('HDEL', KEYS[1], ARGV[1])
+return 1
+`)
+
+// incrementClicksScript only counts clicks of codes that still exist, so a
+// click recorded after a delete doesn't leak into a later link with that code.
+var incrementClicksScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+return redis.call('HINCRBY', KEYS[2], ARGV[1], ARGV[2])
+`)
+
+// consumeClickScript takes one use of the code unless the limit in ARGV[2]
+// is reached, returning -1 for missing codes and 0 once exhausted. Checking
+// and counting in the same step keeps concurrent redirects from overshooting.
+var consumeClickScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+local used = tonumber(redis.call('HGET', KEYS[2], ARGV[1]) or '0')
+if used >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('HINCRBY', KEYS[2], ARGV[1], 1)
+return 1
+`)
+
+// migrateScript rewrites the value in ARGV[2] as the one in ARGV[3] and
+// adds the link to the creation and target indexes, only if the value
+// wasn't changed meanwhile, as the writer keeps the indexes then. It
+// returns 1 once done and 0 when the value changed.
+var migrateScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+if ARGV[3] ~= ARGV[2] then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+end
+redis.call('ZADD', KEYS[2], 'NX', ARGV[4], ARGV[1])
+if ARGV[5] ~= '' then
+	local current = redis.call('HGET', KEYS[3], ARGV[5])
+	if not current or redis.call('HEXISTS', KEYS[1], current) == 0 then
+		redis.call('HSET', KEYS[3], ARGV[5], ARGV[1])
+	end
+end
+return 1
+`)
+
 type UrlRepository struct {
-	rdb *redis.Client
+	rdb   *redis.Client
+	codes codegen.Generator
 }
 
-func NewUrlRepository(rdb *redis.Client) UrlContract {
-	return &UrlRepository{rdb: rdb}
+func NewUrlRepository(rdb *redis.Client, codes codegen.Generator) UrlContract {
+	return &UrlRepository{rdb: rdb, codes: codes}
 }
 
-func (s *UrlRepository) SaveShortenedURL(ctx context.Context, _url string) (string, error) {
-	var code string
-	for range 5 {
-		code = utils.GenCode()
-		if err := s.rdb.HGet(ctx, "encurtador", code).Err(); err != nil {
-			if errors.Is(err, redis.Nil) {
-				break
+// redisCounter backs the counter code strategy with INCR, so replicas
+// sharing the redis never hand out the same value.
+func redisCounter(rdb *redis.Client) codegen.NextFunc {
+	return func(ctx context.Context) (uint64, error) {
+		return rdb.Incr(ctx, counterKey).Uint64()
+	}
+}
+
+// SaveShortenedURL saves through saveWithCodeScript, so a generated code
+// that is already taken is never overwritten but retried.
+func (s *UrlRepository) SaveShortenedURL(ctx context.Context, link Link) (string, error) {
+	return saveGenerated(ctx, s.codes, link, func(code string) error {
+		return s.saveWithCode(ctx, code, link, hostlessKey(link.Host, code))
+	})
+}
+
+func (s *UrlRepository) SaveURLWithCode(ctx context.Context, code string, link Link) error {
+	return s.saveWithCode(ctx, code, link, "")
+}
+
+// saveWithCode saves the link unless the code, or the hostless key when
+// set, is taken.
+func (s *UrlRepository) saveWithCode(ctx context.Context, code string, link Link, hostless string) error {
+	link = prepareNewLink(link, time.Now())
+	value, err := encodeLink(link)
+	if err != nil {
+		return err
+	}
+
+	var expiry string
+	if link.ExpiresAt != nil {
+		expiry = strconv.FormatInt(link.ExpiresAt.Unix(), 10)
+	}
+
+	saved, err := saveWithCodeScript.Run(ctx, s.rdb, saveKeys(link), LinkKey(link.Host, code), value, expiry, link.CreatedAt.UnixMilli(), link.targetKey(), link.Tenant, hostless).Int64()
+	if err != nil {
+		return fmt.Errorf("error setting on redis: %w", err)
+	}
+
+	return savedErr(code, saved)
+}
+
+// saveKeys are the keys saveWithCodeScript touches to save the link.
+func saveKeys(link Link) []string {
+	keys := []string{urlsKey, expiryKey, clicksKey, createdKey, targetsKey, usesKey}
+	if link.Tenant != "" {
+		keys = append(keys, tenantQuotasKey, tenantLinksKey(link.Tenant))
+	}
+	return keys
+}
+
+// savedErr turns the result of saveWithCodeScript into an error.
+func savedErr(code string, saved int64) error {
+	switch saved {
+	case 0:
+		return fmt.Errorf("failed to save code %q: %w", code, ErrCodeTaken)
+	case -1:
+		return fmt.Errorf("failed to save code %q: %w", code, ErrQuotaExceeded)
+	}
+	return nil
+}
+
+// tenantLinksKey indexes the codes of the tenant links by creation time,
+// in milliseconds, like createdKey.
+func tenantLinksKey(tenant string) string {
+	return tenantKeyPrefix + tenant + ":created"
+}
+
+// SaveURLs sends the whole batch in one pipeline of saveWithCodeScript, so
+// codes are never overwritten. Generated codes that collide are retried in
+// a new pipeline.
+func (s *UrlRepository) SaveURLs(ctx context.Context, links []Link) ([]SaveResult, error) {
+	now := time.Now()
+	results := make([]SaveResult, len(links))
+	values := make([]string, len(links))
+	prepared := make([]Link, len(links))
+
+	pending := make([]int, 0, len(links))
+	for i, link := range links {
+		prepared[i] = prepareNewLink(link, now)
+		value, err := encodeLink(prepared[i])
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		values[i] = value
+		pending = append(pending, i)
+	}
+
+	for attempt := 0; attempt < codeAttempts && len(pending) > 0; attempt++ {
+		codes := make([]string, 0, len(pending))
+		generated := make([]int, 0, len(pending))
+		for _, i := range pending {
+			code := prepared[i].Code
+			if code == "" {
+				var err error
+				if code, err = s.codes.Generate(ctx, prepared[i].URL, attempt); err != nil {
+					results[i].Err = err
+					continue
+				}
+			}
+			codes = append(codes, code)
+			generated = append(generated, i)
+		}
+		pending = generated
+
+		cmds := make([]*redis.Cmd, len(pending))
+		_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for j, i := range pending {
+				link := prepared[i]
+
+				var expiry string
+				if link.ExpiresAt != nil {
+					expiry = strconv.FormatInt(link.ExpiresAt.Unix(), 10)
+				}
+				var hostless string
+				if link.Code == "" {
+					hostless = hostlessKey(link.Host, codes[j])
+				}
+
+				// Eval instead of Run, a pipeline can't fall back from EVALSHA
+				cmds[j] = saveWithCodeScript.Eval(ctx, pipe, saveKeys(link), LinkKey(link.Host, codes[j]), values[i], expiry, link.CreatedAt.UnixMilli(), link.targetKey(), link.Tenant, hostless)
+			}
+			return nil
+		})
+		var redisErr redis.Error
+		if err != nil && !errors.As(err, &redisErr) {
+			return nil, fmt.Errorf("error setting on redis: %w", err)
+		}
+
+		var retry []int
+		for j, i := range pending {
+			saved, err := cmds[j].Int64()
+			if err != nil {
+				results[i].Err = fmt.Errorf("error setting on redis: %w", err)
+				continue
+			}
+
+			err = savedErr(codes[j], saved)
+			switch {
+			case err == nil:
+				results[i].Code = codes[j]
+			case errors.Is(err, ErrCodeTaken) && prepared[i].Code == "":
+				retry = append(retry, i)
+			default:
+				results[i].Err = err
 			}
-			return "", fmt.Errorf("code already exists: %w", err)
 		}
+		pending = retry
+	}
+
+	for _, i := range pending {
+		results[i].Err = fmt.Errorf("failed to generate a free code: %w", ErrCodeTaken)
+	}
+
+	return results, nil
+}
+
+func (s *UrlRepository) GetURL(ctx context.Context, code string) (Link, error) {
+	pipe := s.rdb.Pipeline()
+	valueCmd := pipe.HGet(ctx, urlsKey, code)
+	clicksCmd := pipe.HGet(ctx, clicksKey, code)
+	// errors are checked per command below, redis.Nil included
+	_, _ = pipe.Exec(ctx)
+
+	value, err := valueCmd.Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return Link{}, fmt.Errorf("failed to get url: %w", ErrNotFound)
+		}
+		return Link{}, fmt.Errorf("failed to get url: %w", err)
+	}
+
+	link, err := decodeLink(code, value)
+	if err != nil {
+		return Link{}, err
 	}
 
-	if err := s.rdb.HSet(ctx, "encurtador", code, _url).Err(); err != nil {
-		return "", fmt.Errorf("error setting on redis: %w", err)
+	link.Clicks, err = clicksCmd.Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return Link{}, fmt.Errorf("failed to get url clicks: %w", err)
+	}
+
+	if link.Expired(time.Now()) {
+		return link, fmt.Errorf("failed to get url: %w", ErrExpired)
+	}
+
+	return link, nil
+}
+
+func (s *UrlRepository) FindURL(ctx context.Context, tenant, host, target string) (Link, error) {
+	code, err := s.rdb.HGet(ctx, targetsKey, targetKey(tenant, host, target)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return Link{}, fmt.Errorf("failed to find url: %w", ErrNotFound)
+		}
+		return Link{}, fmt.Errorf("failed to find url: %w", err)
 	}
 
-	return code, nil
+	return foundLink(s.GetURL(ctx, code))
 }
 
-func (s *UrlRepository) GetURL(ctx context.Context, code string) (string, error) {
-	_url, err := s.rdb.HGet(ctx, "encurtador", code).Result()
+func (s *UrlRepository) ListURL(ctx context.Context, opts ListOptions) (LinkPage, error) {
+	opts = opts.withDefaults()
+
+	var (
+		page LinkPage
+		err  error
+	)
+	switch {
+	case opts.Sort == SortCreatedAsc || opts.Sort == SortCreatedDesc:
+		page, err = s.listByCreated(ctx, opts)
+	case opts.Tenant != "":
+		// the tenant index is the only one holding just the tenant links
+		page, err = s.listByCreated(ctx, opts)
+	default:
+		page, err = s.listByScan(ctx, opts)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get url: %w", err)
+		return LinkPage{}, fmt.Errorf("failed to list urls: %w", err)
+	}
+
+	if err := s.fillClicks(ctx, page.Links); err != nil {
+		return LinkPage{}, err
+	}
+
+	return page, nil
+}
+
+// listByScan pages through the links hash with HSCAN, the cursor being the
+// HSCAN one. Entries of a scan round are never split across pages, so a
+// page can hold a few more links than the limit.
+func (s *UrlRepository) listByScan(ctx context.Context, opts ListOptions) (LinkPage, error) {
+	var cursor uint64
+	if opts.Cursor != "" {
+		var err error
+		cursor, err = strconv.ParseUint(opts.Cursor, 10, 64)
+		if err != nil {
+			return LinkPage{}, fmt.Errorf("invalid cursor %q: %w", opts.Cursor, ErrInvalidCursor)
+		}
+	}
+
+	page := LinkPage{Links: []Link{}}
+	scanned := 0
+	for {
+		entries, next, err := s.rdb.HScan(ctx, urlsKey, cursor, "", int64(opts.Limit)).Result()
+		if err != nil {
+			return LinkPage{}, err
+		}
+
+		for i := 0; i+1 < len(entries); i += 2 {
+			link, err := decodeLink(entries[i], entries[i+1])
+			if err != nil {
+				return LinkPage{}, err
+			}
+			if opts.Matches(link) {
+				page.Links = append(page.Links, link)
+			}
+		}
+		scanned += len(entries) / 2
+		cursor = next
+
+		if cursor == 0 {
+			return page, nil
+		}
+		if len(page.Links) >= opts.Limit || scanned >= opts.scanBudget() {
+			page.NextCursor = strconv.FormatUint(cursor, 10)
+			return page, nil
+		}
+	}
+}
+
+// listByCreated pages through the creation index, or the tenant one, the
+// cursor being the score and key of the last link listed, so links saved or
+// deleted meanwhile never shift the next pages. The creation range filter is
+// applied on the index scores.
+func (s *UrlRepository) listByCreated(ctx context.Context, opts ListOptions) (LinkPage, error) {
+	index := createdKey
+	if opts.Tenant != "" {
+		index = tenantLinksKey(opts.Tenant)
+	}
+	desc := opts.Sort == SortCreatedDesc
+
+	var cursor *createdCursor
+	if opts.Cursor != "" {
+		parsed, err := parseCreatedCursor(opts.Cursor)
+		if err != nil {
+			return LinkPage{}, err
+		}
+		cursor = &parsed
+	}
+
+	min, max := "-inf", "+inf"
+	if !opts.CreatedFrom.IsZero() {
+		min = strconv.FormatInt(opts.CreatedFrom.UnixMilli(), 10)
+	}
+	if !opts.CreatedTo.IsZero() {
+		max = "(" + strconv.FormatInt(opts.CreatedTo.UnixMilli(), 10)
+	}
+
+	page := LinkPage{Links: []Link{}}
+	// skipped counts the entries tied with the cursor already passed over,
+	// when a whole round held nothing after it
+	skipped := 0
+	for scanned := 0; scanned < opts.scanBudget(); {
+		args := redis.ZRangeArgs{
+			Key:     index,
+			Start:   min,
+			Stop:    max,
+			ByScore: true,
+			Offset:  int64(skipped),
+			Count:   int64(opts.Limit),
+		}
+		if cursor != nil {
+			// the ties with the cursor score are sorted by member, the ones
+			// up to the cursor key are skipped below
+			if desc {
+				args.Stop = strconv.FormatInt(cursor.created, 10)
+			} else {
+				args.Start = strconv.FormatInt(cursor.created, 10)
+			}
+		}
+		if desc {
+			// go-redis swaps the bounds itself for REV BYSCORE
+			args.Rev = true
+		}
+
+		entries, err := s.rdb.ZRangeArgsWithScores(ctx, args).Result()
+		if err != nil {
+			return LinkPage{}, err
+		}
+		if len(entries) == 0 {
+			return page, nil
+		}
+
+		codes := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			code := entry.Member.(string)
+			if cursor != nil && !cursor.afterEntry(int64(entry.Score), code, desc) {
+				continue
+			}
+			codes = append(codes, code)
+		}
+		if len(codes) == 0 {
+			skipped += len(entries)
+			continue
+		}
+		skipped = 0
+
+		values, err := s.rdb.HMGet(ctx, urlsKey, codes...).Result()
+		if err != nil {
+			return LinkPage{}, err
+		}
+
+		scores := make(map[string]float64, len(entries))
+		for _, entry := range entries {
+			scores[entry.Member.(string)] = entry.Score
+		}
+		for i, code := range codes {
+			cursor = &createdCursor{created: int64(scores[code]), key: code}
+			scanned++
+
+			value, ok := values[i].(string)
+			if !ok {
+				// deleted between both reads
+				continue
+			}
+
+			link, err := decodeLink(code, value)
+			if err != nil {
+				return LinkPage{}, err
+			}
+			if !opts.Matches(link) {
+				continue
+			}
+
+			page.Links = append(page.Links, link)
+			if len(page.Links) == opts.Limit {
+				page.NextCursor = cursor.String()
+				return page, nil
+			}
+		}
+
+		if len(entries) < opts.Limit {
+			return page, nil
+		}
 	}
 
-	return _url, nil
+	page.NextCursor = cursor.String()
+	return page, nil
 }
 
-func (s *UrlRepository) GetAllURL(ctx context.Context) (map[string]string, error) {
-	urls, err := s.rdb.HGetAll(context.Background(), "encurtador").Result()
+func (s *UrlRepository) fillClicks(ctx context.Context, links []Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	codes := make([]string, len(links))
+	for i, link := range links {
+		codes[i] = link.Key()
+	}
+
+	clicks, err := s.rdb.HMGet(ctx, clicksKey, codes...).Result()
 	if err != nil {
-		return map[string]string{}, fmt.Errorf("failed to get all urls: %w", err)
+		return fmt.Errorf("failed to get url clicks: %w", err)
+	}
+
+	for i, count := range clicks {
+		value, ok := count.(string)
+		if !ok {
+			continue
+		}
+		links[i].Clicks, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid clicks of %q: %w", links[i].Code, err)
+		}
 	}
 
-	return urls, nil
+	return nil
 }
 
+// DeleteURL watches the links hash, so a link saved again with the code
+// between the read and the delete isn't removed with the indexes of the
+// previous one.
 func (s *UrlRepository) DeleteURL(ctx context.Context, code string) error {
-	if err := s.rdb.HGet(ctx, "encurtador", code).Err(); err != nil {
-		if errors.Is(err, redis.Nil) {
-			return fmt.Errorf("url not found: %w", err)
+	txf := func(tx *redis.Tx) error {
+		value, err := tx.HGet(ctx, urlsKey, code).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		link, err := decodeLink(code, value)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, urlsKey, code)
+			pipe.HDel(ctx, clicksKey, code)
+			pipe.HDel(ctx, usesKey, code)
+			pipe.ZRem(ctx, expiryKey, code)
+			pipe.ZRem(ctx, createdKey, code)
+			unindexTarget(ctx, pipe, code, link)
+			unindexTenant(ctx, pipe, code, link)
+			deleteStats(ctx, pipe, code)
+			return nil
+		})
+		return err
+	}
+
+	for range updateRetries {
+		err := s.rdb.Watch(ctx, txf, urlsKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete url: %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("failed to delete url: %w", redis.TxFailedErr)
+}
+
+func (s *UrlRepository) UpdateURL(ctx context.Context, code string, update func(link *Link) error) (Link, error) {
+	var updated Link
+	txf := func(tx *redis.Tx) error {
+		value, err := tx.HGet(ctx, urlsKey, code).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		link, err := decodeLink(code, value)
+		if err != nil {
+			return err
+		}
+		previous := link
+
+		if err := update(&link); err != nil {
+			return err
+		}
+		link.Code, link.Host = previous.Code, previous.Host
+		link.Tenant = previous.Tenant
+		link.UpdatedAt = time.Now()
+
+		value, err = encodeLink(link)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, urlsKey, code, value)
+			setExpiry(ctx, pipe, code, link.expiryTime())
+			if previous.targetKey() != link.targetKey() {
+				unindexTarget(ctx, pipe, code, previous)
+			}
+			indexTarget(ctx, pipe, code, link)
+			return nil
+		})
+		updated = link
+		return err
+	}
+
+	for range updateRetries {
+		err := s.rdb.Watch(ctx, txf, urlsKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return Link{}, fmt.Errorf("failed to update url: %w", err)
+		}
+
+		clicks, err := s.rdb.HGet(ctx, clicksKey, code).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return Link{}, fmt.Errorf("failed to get url clicks: %w", err)
+		}
+		updated.Clicks = clicks
+
+		return updated, nil
+	}
+
+	return Link{}, fmt.Errorf("failed to update url: %w", redis.TxFailedErr)
+}
+
+func (s *UrlRepository) IncrementClicks(ctx context.Context, clicks map[string]int64) error {
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for code, count := range clicks {
+			// Eval instead of Run, a pipeline can't fall back from EVALSHA
+			incrementClicksScript.Eval(ctx, pipe, []string{urlsKey, clicksKey}, code, count)
 		}
-		return fmt.Errorf("failed to get url: %w", err)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to increment clicks: %w", err)
+	}
+
+	return nil
+}
+
+func (s *UrlRepository) ConsumeClick(ctx context.Context, code string, max int64) error {
+	taken, err := consumeClickScript.Run(ctx, s.rdb, []string{urlsKey, usesKey}, code, max).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to consume click: %w", err)
 	}
 
-	if err := s.rdb.HDel(ctx, "encurtador", code).Err(); err != nil {
-		return fmt.Errorf("failed to delete url: %w", err)
+	switch taken {
+	case -1:
+		return fmt.Errorf("failed to consume click: %w", ErrNotFound)
+	case 0:
+		return fmt.Errorf("failed to consume click: %w", ErrExhausted)
 	}
 
 	return nil
 }
 
-func (s *UrlRepository) UpdateURL(ctx context.Context, code string, newURL string) (string, error) {
+// DeleteExpired removes the expired links in batches, each one read and
+// deleted under a watch of the links hash and the expiry index, so a link
+// renewed or saved again meanwhile is left alone.
+func (s *UrlRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for {
+		var batch, removed int
+		txf := func(tx *redis.Tx) error {
+			codes, err := tx.ZRangeByScore(ctx, expiryKey, &redis.ZRangeBy{
+				Min:   "-inf",
+				Max:   strconv.FormatInt(before.Unix(), 10),
+				Count: expiredBatchSize,
+			}).Result()
+			if err != nil {
+				return err
+			}
+			batch = len(codes)
+			if len(codes) == 0 {
+				return nil
+			}
+
+			members := make([]any, len(codes))
+			for i, code := range codes {
+				members[i] = code
+			}
 
-	if err := s.rdb.HGet(ctx, "encurtador", code).Err(); err != nil {
-		if errors.Is(err, redis.Nil) {
-			return "", fmt.Errorf("url not found: %w", err)
+			values, err := tx.HMGet(ctx, urlsKey, codes...).Result()
+			if err != nil {
+				return err
+			}
+
+			var hdel *redis.IntCmd
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				hdel = pipe.HDel(ctx, urlsKey, codes...)
+				pipe.HDel(ctx, clicksKey, codes...)
+				pipe.HDel(ctx, usesKey, codes...)
+				pipe.ZRem(ctx, expiryKey, members...)
+				pipe.ZRem(ctx, createdKey, members...)
+				for i, code := range codes {
+					deleteStats(ctx, pipe, code)
+					value, ok := values[i].(string)
+					if !ok {
+						continue
+					}
+					if link, err := decodeLink(code, value); err == nil {
+						unindexTarget(ctx, pipe, code, link)
+						unindexTenant(ctx, pipe, code, link)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			removed = int(hdel.Val())
+			return nil
+		}
+
+		var err error = redis.TxFailedErr
+		for attempt := 0; attempt < updateRetries && errors.Is(err, redis.TxFailedErr); attempt++ {
+			err = s.rdb.Watch(ctx, txf, urlsKey, expiryKey)
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete expired urls: %w", err)
+		}
+		deleted += int64(removed)
+
+		if batch < expiredBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// MigrateLegacyURLs rewrites the bare URL strings as links, carrying over
+// the expiration kept in the expiry index. Once every link went through,
+// migratedKey is set and later calls return at once, so the instances of
+// the previous version must be stopped first.
+func (s *UrlRepository) MigrateLegacyURLs(ctx context.Context) (int64, error) {
+	done, err := s.rdb.Exists(ctx, migratedKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check migration: %w", err)
+	}
+	if done > 0 {
+		return 0, nil
+	}
+
+	var migrated int64
+	iter := s.rdb.HScan(ctx, urlsKey, 0, "", 0).Iterator()
+	for iter.Next(ctx) {
+		code := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+		value := iter.Val()
+
+		// links saved before the creation and target indexes existed are
+		// only indexed
+		link, err := decodeLink(code, value)
+		if err != nil {
+			return migrated, err
+		}
+		legacy, encoded := isLegacyValue(value), value
+		if legacy {
+			expiry, err := s.rdb.ZScore(ctx, expiryKey, code).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return migrated, fmt.Errorf("failed to get url expiry: %w", err)
+			}
+			if err == nil {
+				expiresAt := time.Unix(int64(expiry), 0).UTC()
+				link.ExpiresAt = &expiresAt
+			}
+
+			if encoded, err = encodeLink(link); err != nil {
+				return migrated, err
+			}
+		}
+
+		keys := []string{urlsKey, createdKey, targetsKey}
+		ok, err := migrateScript.Run(ctx, s.rdb, keys, code, value, encoded, link.CreatedAt.UnixMilli(), link.targetKey()).Bool()
+		if err != nil {
+			return migrated, fmt.Errorf("failed to migrate url %q: %w", code, err)
 		}
-		return "", fmt.Errorf("failed to get url: %w", err)
+		if ok && legacy {
+			migrated++
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return migrated, fmt.Errorf("failed to scan urls: %w", err)
+	}
+
+	if err := s.rdb.Set(ctx, migratedKey, time.Now().UTC().Format(time.RFC3339), 0).Err(); err != nil {
+		return migrated, fmt.Errorf("failed to mark migration: %w", err)
+	}
+
+	return migrated, nil
+}
+
+// indexTarget and unindexTarget use Eval, as Run can't fall back from
+// EVALSHA within a pipeline.
+func indexTarget(ctx context.Context, pipe redis.Pipeliner, code string, link Link) {
+	if key := link.targetKey(); key != "" {
+		indexTargetScript.Eval(ctx, pipe, []string{targetsKey, urlsKey}, key, code)
 	}
+}
 
-	if err := s.rdb.HSet(ctx, "encurtador", code, newURL).Err(); err != nil {
-		return "", fmt.Errorf("failed to update url: %w", err)
+func unindexTarget(ctx context.Context, pipe redis.Pipeliner, code string, link Link) {
+	if key := link.targetKey(); key != "" {
+		unindexTargetScript.Eval(ctx, pipe, []string{targetsKey}, key, code)
 	}
+}
 
-	return code, nil
+func unindexTenant(ctx context.Context, pipe redis.Pipeliner, code string, link Link) {
+	if link.Tenant != "" {
+		pipe.ZRem(ctx, tenantLinksKey(link.Tenant), code)
+	}
+}
+
+func setExpiry(ctx context.Context, pipe redis.Pipeliner, code string, expiresAt time.Time) {
+	if expiresAt.IsZero() {
+		pipe.ZRem(ctx, expiryKey, code)
+		return
+	}
+	pipe.ZAdd(ctx, expiryKey, redis.Z{Score: float64(expiresAt.Unix()), Member: code})
 }
@@ -0,0 +1,85 @@
+package split
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+)
+
+const (
+	// MaxVariants bounds how many destinations a link can split across.
+	MaxVariants = 10
+	// MaxWeight bounds the weight of a single variant.
+	MaxWeight = 1000
+)
+
+// Variant is one of the destinations a split link spreads its visitors
+// across, in proportion to its weight among the weights of all variants.
+type Variant struct {
+	// Name identifies the variant in the sticky cookie and the stats.
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// Validate checks there are at least two variants with unique names and
+// positive weights. The URLs are left for the caller to validate.
+func Validate(variants []Variant) error {
+	if len(variants) < 2 {
+		return errors.New("at least two variants are required")
+	}
+	if len(variants) > MaxVariants {
+		return fmt.Errorf("at most %d variants are allowed", MaxVariants)
+	}
+
+	names := make(map[string]bool, len(variants))
+	for i, variant := range variants {
+		if !namePattern.MatchString(variant.Name) {
+			return fmt.Errorf("variant %d: name must be 1 to 32 letters, numbers, - or _", i+1)
+		}
+		if names[variant.Name] {
+			return fmt.Errorf("variant %d: duplicated name %q", i+1, variant.Name)
+		}
+		names[variant.Name] = true
+
+		if variant.URL == "" {
+			return fmt.Errorf("variant %d: url is required", i+1)
+		}
+		if variant.Weight < 1 || variant.Weight > MaxWeight {
+			return fmt.Errorf("variant %d: weight must be between 1 and %d", i+1, MaxWeight)
+		}
+	}
+
+	return nil
+}
+
+// Pick draws a variant at random in proportion to the weights. The
+// variants must be valid, see Validate.
+func Pick(variants []Variant) Variant {
+	var total int
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+
+	n := rand.IntN(total)
+	for _, variant := range variants {
+		if n < variant.Weight {
+			return variant
+		}
+		n -= variant.Weight
+	}
+	return variants[len(variants)-1]
+}
+
+// Find returns the variant with the name.
+func Find(variants []Variant, name string) (Variant, bool) {
+	for _, variant := range variants {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return Variant{}, false
+}
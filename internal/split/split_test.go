@@ -0,0 +1,59 @@
+package split
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []Variant{
+		{Name: "control", URL: "https://example.com/a", Weight: 70},
+		{Name: "new-page", URL: "https://example.com/b", Weight: 30},
+	}
+	assert.NoError(t, Validate(valid))
+
+	tests := map[string][]Variant{
+		"at least two variants are required":                       {valid[0]},
+		"at most 10 variants are allowed":                          make([]Variant, MaxVariants+1),
+		"variant 2: name must be 1 to 32 letters, numbers, - or _": {valid[0], {Name: "new page", URL: "https://example.com", Weight: 1}},
+		`variant 2: duplicated name "control"`:                     {valid[0], {Name: "control", URL: "https://example.com", Weight: 1}},
+		"variant 2: url is required":                               {valid[0], {Name: "b", Weight: 1}},
+		"variant 2: weight must be between 1 and 1000":             {valid[0], {Name: "b", URL: "https://example.com"}},
+	}
+
+	for expected, variants := range tests {
+		assert.EqualError(t, Validate(variants), expected)
+	}
+}
+
+func TestPick(t *testing.T) {
+	variants := []Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 70},
+		{Name: "b", URL: "https://example.com/b", Weight: 30},
+	}
+
+	counts := map[string]int{}
+	for range 10000 {
+		counts[Pick(variants).Name]++
+	}
+
+	assert.InDelta(t, 7000, counts["a"], 400)
+	assert.InDelta(t, 3000, counts["b"], 400)
+
+	only := []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 0}}
+	for range 100 {
+		assert.Equal(t, "a", Pick(only).Name)
+	}
+}
+
+func TestFind(t *testing.T) {
+	variants := []Variant{{Name: "a"}, {Name: "b"}}
+
+	variant, ok := Find(variants, "b")
+	assert.True(t, ok)
+	assert.Equal(t, "b", variant.Name)
+
+	_, ok = Find(variants, "c")
+	assert.False(t, ok)
+}
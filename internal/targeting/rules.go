@@ -0,0 +1,302 @@
+package targeting
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxRules bounds how many rules a link can have, as they are evaluated on
+// every redirect.
+const MaxRules = 50
+
+// Rule sends the visitors matching all of its conditions to URL, the
+// conditions left empty matching everyone. A rule needs at least one
+// condition, the link URL being the fallback for visitors no rule matches.
+type Rule struct {
+	URL string `json:"url"`
+	// Platforms are ios, android, windows, macos and linux, along with
+	// mobile and desktop matching all the platforms of the group.
+	Platforms []string `json:"platforms,omitempty"`
+	// Languages are matched against the preferred language of the visitor,
+	// a bare language like pt matching its regional variants like pt-BR.
+	Languages []string `json:"languages,omitempty"`
+	// Countries are ISO 3166-1 alpha-2 codes, matched against the country
+	// header set by the reverse proxy.
+	Countries []string `json:"countries,omitempty"`
+	Window    *Window  `json:"window,omitempty"`
+}
+
+// Window is a time frame, every set field having to match.
+type Window struct {
+	From  *time.Time `json:"from,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+	// Days are the weekdays, as mon, tue, wed, thu, fri, sat and sun.
+	Days []string `json:"days,omitempty"`
+	// Hours is a daily range as HH:MM-HH:MM, wrapping past midnight when it
+	// ends before it starts.
+	Hours string `json:"hours,omitempty"`
+	// Timezone is the IANA zone of Days and Hours, UTC when empty.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// platformGroups are the groups a rule can use instead of single platforms.
+var platformGroups = map[string][]string{
+	"mobile":  {PlatformIOS, PlatformAndroid},
+	"desktop": {PlatformWindows, PlatformMacOS, PlatformLinux},
+}
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+var (
+	languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+	countryPattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// Normalize validates the rules and returns them with the platforms, days
+// and languages lowercased and the countries uppercased. The URLs are left
+// for the caller to validate.
+func Normalize(rules []Rule) ([]Rule, error) {
+	if len(rules) > MaxRules {
+		return nil, fmt.Errorf("at most %d rules are allowed", MaxRules)
+	}
+
+	normalized := make([]Rule, len(rules))
+	for i, rule := range rules {
+		rule, err := normalizeRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		normalized[i] = rule
+	}
+
+	return normalized, nil
+}
+
+func normalizeRule(rule Rule) (Rule, error) {
+	if rule.URL == "" {
+		return rule, errors.New("url is required")
+	}
+	if len(rule.Platforms) == 0 && len(rule.Languages) == 0 && len(rule.Countries) == 0 && rule.Window == nil {
+		return rule, errors.New("at least one condition is required")
+	}
+
+	rule.Platforms = mapValues(rule.Platforms, strings.ToLower)
+	for _, platform := range rule.Platforms {
+		if !slices.Contains(Platforms, platform) && platformGroups[platform] == nil {
+			return rule, fmt.Errorf("unknown platform %q", platform)
+		}
+	}
+
+	rule.Languages = mapValues(rule.Languages, strings.ToLower)
+	for _, language := range rule.Languages {
+		if !languagePattern.MatchString(language) {
+			return rule, fmt.Errorf("invalid language %q", language)
+		}
+	}
+
+	rule.Countries = mapValues(rule.Countries, strings.ToUpper)
+	for _, country := range rule.Countries {
+		if !countryPattern.MatchString(country) {
+			return rule, fmt.Errorf("invalid country %q, expected a two letter code", country)
+		}
+	}
+
+	if rule.Window != nil {
+		window, err := normalizeWindow(*rule.Window)
+		if err != nil {
+			return rule, err
+		}
+		rule.Window = &window
+	}
+
+	return rule, nil
+}
+
+func normalizeWindow(window Window) (Window, error) {
+	if window.From != nil && window.Until != nil && !window.Until.After(*window.From) {
+		return window, errors.New("window until must be after from")
+	}
+
+	window.Days = mapValues(window.Days, strings.ToLower)
+	for _, day := range window.Days {
+		if !slices.Contains(weekdays, day) {
+			return window, fmt.Errorf("unknown day %q", day)
+		}
+	}
+
+	if window.Hours != "" {
+		start, end, ok := parseHours(window.Hours)
+		if !ok {
+			return window, fmt.Errorf("invalid hours %q, expected HH:MM-HH:MM", window.Hours)
+		}
+		if start == end {
+			return window, errors.New("window hours can't start and end at the same time")
+		}
+	}
+
+	if window.Timezone != "" {
+		if _, err := loadLocation(window.Timezone); err != nil {
+			return window, fmt.Errorf("unknown timezone %q", window.Timezone)
+		}
+	}
+
+	if window.From == nil && window.Until == nil && len(window.Days) == 0 && window.Hours == "" {
+		return window, errors.New("window needs from, until, days or hours")
+	}
+
+	return window, nil
+}
+
+// Match returns the URL of the first rule the visitor matches, false when
+// none does.
+func Match(rules []Rule, visitor Visitor) (string, bool) {
+	for _, rule := range rules {
+		if rule.matches(visitor) {
+			return rule.URL, true
+		}
+	}
+	return "", false
+}
+
+func (r Rule) matches(v Visitor) bool {
+	if len(r.Platforms) > 0 && !slices.ContainsFunc(r.Platforms, func(platform string) bool {
+		return platform == v.Platform || slices.Contains(platformGroups[platform], v.Platform)
+	}) {
+		return false
+	}
+
+	if len(r.Languages) > 0 && !slices.ContainsFunc(r.Languages, func(language string) bool {
+		return v.Language == language || strings.HasPrefix(v.Language, language+"-")
+	}) {
+		return false
+	}
+
+	if len(r.Countries) > 0 && !slices.Contains(r.Countries, v.Country) {
+		return false
+	}
+
+	return r.Window == nil || r.Window.contains(v.Time)
+}
+
+func (w Window) contains(t time.Time) bool {
+	if w.From != nil && t.Before(*w.From) {
+		return false
+	}
+	if w.Until != nil && !t.Before(*w.Until) {
+		return false
+	}
+
+	if len(w.Days) == 0 && w.Hours == "" {
+		return true
+	}
+
+	location := time.UTC
+	if w.Timezone != "" {
+		var err error
+		if location, err = loadLocation(w.Timezone); err != nil {
+			return false
+		}
+	}
+	t = t.In(location)
+
+	if len(w.Days) > 0 && !slices.Contains(w.Days, weekdays[t.Weekday()]) {
+		return false
+	}
+
+	if w.Hours != "" {
+		start, end, ok := parseHours(w.Hours)
+		if !ok {
+			return false
+		}
+		minute := t.Hour()*60 + t.Minute()
+		if start < end {
+			return minute >= start && minute < end
+		}
+		return minute >= start || minute < end
+	}
+
+	return true
+}
+
+// parseHours returns the range as minutes of the day.
+func parseHours(hours string) (start int, end int, ok bool) {
+	from, until, found := strings.Cut(hours, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	startTime, err := time.Parse("15:04", from)
+	if err != nil {
+		return 0, 0, false
+	}
+	endTime, err := time.Parse("15:04", until)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return startTime.Hour()*60 + startTime.Minute(), endTime.Hour()*60 + endTime.Minute(), true
+}
+
+// locations caches the loaded timezones, time.LoadLocation reading the
+// zone file on every call.
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if location, ok := locations.Load(name); ok {
+		return location.(*time.Location), nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, location)
+	return location, nil
+}
+
+// Clone copies the rules so changing the copy doesn't change them through
+// their slices and pointers.
+func Clone(rules []Rule) []Rule {
+	if rules == nil {
+		return nil
+	}
+
+	cloned := make([]Rule, len(rules))
+	for i, rule := range rules {
+		rule.Platforms = slices.Clone(rule.Platforms)
+		rule.Languages = slices.Clone(rule.Languages)
+		rule.Countries = slices.Clone(rule.Countries)
+		if rule.Window != nil {
+			window := *rule.Window
+			window.Days = slices.Clone(window.Days)
+			if window.From != nil {
+				from := *window.From
+				window.From = &from
+			}
+			if window.Until != nil {
+				until := *window.Until
+				window.Until = &until
+			}
+			rule.Window = &window
+		}
+		cloned[i] = rule
+	}
+	return cloned
+}
+
+func mapValues(values []string, f func(string) string) []string {
+	if values == nil {
+		return nil
+	}
+
+	mapped := make([]string, len(values))
+	for i, value := range values {
+		mapped[i] = f(strings.TrimSpace(value))
+	}
+	return mapped
+}
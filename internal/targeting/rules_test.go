@@ -0,0 +1,174 @@
+package targeting
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	iphoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	windowsUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	macUA     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Version/17.0 Safari/605.1.15"
+	linuxUA   = "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"
+)
+
+func TestPlatform(t *testing.T) {
+	tests := map[string]string{
+		iphoneUA:      PlatformIOS,
+		androidUA:     PlatformAndroid,
+		windowsUA:     PlatformWindows,
+		macUA:         PlatformMacOS,
+		linuxUA:       PlatformLinux,
+		"curl/8.4.0":  PlatformOther,
+		"":            PlatformOther,
+		"Mozilla/5.0": PlatformOther,
+	}
+
+	for ua, expected := range tests {
+		assert.Equal(t, expected, Platform(ua), ua)
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := map[string]string{
+		"pt-BR,pt;q=0.9,en;q=0.8": "pt-br",
+		"en;q=0.5, fr":            "fr",
+		"de;q=0.7, es;q=0.7":      "de",
+		"*, it;q=0.5":             "it",
+		"ja;q=0":                  "",
+		"":                        "",
+	}
+
+	for accept, expected := range tests {
+		assert.Equal(t, expected, PreferredLanguage(accept), accept)
+	}
+}
+
+func TestNewVisitor(t *testing.T) {
+	now := time.Now()
+	req := httptest.NewRequest("GET", "/api/abc", nil)
+	req.Header.Set("User-Agent", androidUA)
+	req.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+	req.Header.Set("X-Country-Code", "br")
+
+	assert.Equal(t, Visitor{Platform: PlatformAndroid, Language: "pt-br", Country: "BR", Time: now}, NewVisitor(req, "X-Country-Code", now))
+	assert.Empty(t, NewVisitor(req, "", now).Country)
+}
+
+func TestNormalize(t *testing.T) {
+	rules, err := Normalize([]Rule{{
+		URL:       "https://example.com",
+		Platforms: []string{"iOS", " Mobile"},
+		Languages: []string{"PT-br"},
+		Countries: []string{"br"},
+		Window:    &Window{Days: []string{"Mon"}, Hours: "22:00-02:00"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{{
+		URL:       "https://example.com",
+		Platforms: []string{"ios", "mobile"},
+		Languages: []string{"pt-br"},
+		Countries: []string{"BR"},
+		Window:    &Window{Days: []string{"mon"}, Hours: "22:00-02:00"},
+	}}, rules)
+
+	from := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	until := from.Add(-time.Hour)
+	tests := map[string]Rule{
+		"rule 1: url is required":                                   {Platforms: []string{"ios"}},
+		"rule 1: at least one condition is required":                {URL: "https://example.com"},
+		`rule 1: unknown platform "blackberry"`:                     {URL: "https://example.com", Platforms: []string{"blackberry"}},
+		`rule 1: invalid language "portuguese!"`:                    {URL: "https://example.com", Languages: []string{"portuguese!"}},
+		`rule 1: invalid country "BRA", expected a two letter code`: {URL: "https://example.com", Countries: []string{"BRA"}},
+		"rule 1: window until must be after from":                   {URL: "https://example.com", Window: &Window{From: &from, Until: &until}},
+		`rule 1: unknown day "someday"`:                             {URL: "https://example.com", Window: &Window{Days: []string{"someday"}}},
+		`rule 1: invalid hours "9-17", expected HH:MM-HH:MM`:        {URL: "https://example.com", Window: &Window{Hours: "9-17"}},
+		"rule 1: window hours can't start and end at the same time": {URL: "https://example.com", Window: &Window{Hours: "10:00-10:00"}},
+		`rule 1: unknown timezone "Mars/Olympus"`:                   {URL: "https://example.com", Window: &Window{Hours: "10:00-11:00", Timezone: "Mars/Olympus"}},
+		"rule 1: window needs from, until, days or hours":           {URL: "https://example.com", Window: &Window{Timezone: "UTC"}},
+	}
+
+	for expected, rule := range tests {
+		_, err := Normalize([]Rule{rule})
+		assert.EqualError(t, err, expected)
+	}
+
+	_, err = Normalize(make([]Rule, MaxRules+1))
+	assert.EqualError(t, err, "at most 50 rules are allowed")
+}
+
+func TestMatch(t *testing.T) {
+	// a monday
+	now := time.Date(2026, 1, 5, 15, 30, 0, 0, time.UTC)
+	later := now.Add(24 * time.Hour)
+
+	rules := []Rule{
+		{URL: "https://apps.apple.com/app", Platforms: []string{"ios"}},
+		{URL: "https://play.google.com/app", Platforms: []string{"android"}},
+		{URL: "https://example.com.br", Countries: []string{"BR"}, Languages: []string{"pt"}},
+		{URL: "https://example.de", Countries: []string{"DE", "AT"}},
+		{URL: "https://example.com/desktop-sale", Platforms: []string{"desktop"}, Window: &Window{Until: &later, Days: []string{"mon"}, Hours: "09:00-18:00"}},
+	}
+
+	tests := []struct {
+		name     string
+		visitor  Visitor
+		expected string
+	}{
+		{name: "ios", visitor: Visitor{Platform: PlatformIOS, Country: "BR", Time: now}, expected: "https://apps.apple.com/app"},
+		{name: "android", visitor: Visitor{Platform: PlatformAndroid, Time: now}, expected: "https://play.google.com/app"},
+		{name: "country and language", visitor: Visitor{Platform: PlatformOther, Country: "BR", Language: "pt-br", Time: now}, expected: "https://example.com.br"},
+		{name: "country without language", visitor: Visitor{Platform: PlatformOther, Country: "BR", Language: "en", Time: now}, expected: "https://fallback.com"},
+		{name: "language prefix only", visitor: Visitor{Platform: PlatformOther, Country: "BR", Language: "ptx", Time: now}, expected: "https://fallback.com"},
+		{name: "any country", visitor: Visitor{Platform: PlatformOther, Country: "AT", Time: now}, expected: "https://example.de"},
+		{name: "window", visitor: Visitor{Platform: PlatformLinux, Time: now}, expected: "https://example.com/desktop-sale"},
+		{name: "outside hours", visitor: Visitor{Platform: PlatformLinux, Time: now.Add(3 * time.Hour)}, expected: "https://fallback.com"},
+		{name: "other day", visitor: Visitor{Platform: PlatformLinux, Time: now.Add(-24 * time.Hour)}, expected: "https://fallback.com"},
+		{name: "window over", visitor: Visitor{Platform: PlatformLinux, Time: later.Add(7 * 24 * time.Hour)}, expected: "https://fallback.com"},
+		{name: "unknown visitor", visitor: Visitor{Platform: PlatformOther, Time: now}, expected: "https://fallback.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := Match(rules, tt.visitor)
+			if !ok {
+				target = "https://fallback.com"
+			}
+			assert.Equal(t, tt.expected, target)
+		})
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	// 23:30 in UTC is 20:30 in Sao Paulo, of the same day
+	now := time.Date(2026, 1, 5, 23, 30, 0, 0, time.UTC)
+
+	assert.True(t, Window{Hours: "22:00-02:00"}.contains(now))
+	assert.True(t, Window{Hours: "22:00-02:00"}.contains(now.Add(2*time.Hour)))
+	assert.False(t, Window{Hours: "22:00-02:00"}.contains(now.Add(3*time.Hour)))
+	assert.True(t, Window{From: &now}.contains(now))
+	assert.False(t, Window{Until: &now}.contains(now))
+
+	if _, err := time.LoadLocation("America/Sao_Paulo"); err != nil {
+		t.Skip("no timezone database")
+	}
+	assert.True(t, Window{Days: []string{"mon"}, Hours: "20:00-21:00", Timezone: "America/Sao_Paulo"}.contains(now))
+	assert.False(t, Window{Hours: "22:00-02:00", Timezone: "America/Sao_Paulo"}.contains(now))
+}
+
+func TestClone(t *testing.T) {
+	rules := []Rule{{URL: "https://example.com", Platforms: []string{"ios"}, Window: &Window{Days: []string{"mon"}}}}
+
+	cloned := Clone(rules)
+	cloned[0].Platforms[0] = "android"
+	cloned[0].Window.Days[0] = "tue"
+
+	assert.Equal(t, "ios", rules[0].Platforms[0])
+	assert.Equal(t, "mon", rules[0].Window.Days[0])
+	assert.Nil(t, Clone(nil))
+}
@@ -0,0 +1,92 @@
+package targeting
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+	PlatformOther   = "other"
+)
+
+// Platforms are the platforms told apart from the User-Agent.
+var Platforms = []string{PlatformIOS, PlatformAndroid, PlatformWindows, PlatformMacOS, PlatformLinux}
+
+// Visitor is what the rules are matched against.
+type Visitor struct {
+	Platform string
+	// Language is the preferred language, lowercased, empty when the
+	// visitor sent none.
+	Language string
+	// Country is the uppercased country code, empty when unknown.
+	Country string
+	Time    time.Time
+}
+
+// NewVisitor reads the visitor from the request, the country coming from
+// countryHeader, set by the reverse proxy.
+func NewVisitor(r *http.Request, countryHeader string, now time.Time) Visitor {
+	visitor := Visitor{
+		Platform: Platform(r.UserAgent()),
+		Language: PreferredLanguage(r.Header.Get("Accept-Language")),
+		Time:     now,
+	}
+	if countryHeader != "" {
+		visitor.Country = strings.ToUpper(strings.TrimSpace(r.Header.Get(countryHeader)))
+	}
+	return visitor
+}
+
+// Platform tells the platform from the User-Agent. The order matters, iOS
+// agents claim to be like Mac OS X and Android ones run on Linux.
+func Platform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return PlatformIOS
+	case strings.Contains(ua, "android"):
+		return PlatformAndroid
+	case strings.Contains(ua, "windows"):
+		return PlatformWindows
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		return PlatformMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"):
+		return PlatformLinux
+	}
+	return PlatformOther
+}
+
+// PreferredLanguage returns the language of the Accept-Language header with
+// the highest quality, the first one winning ties. Wildcards and refused
+// languages are skipped.
+func PreferredLanguage(accept string) string {
+	var best string
+	bestQuality := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = tag, quality
+		}
+	}
+	return best
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	AliasMinLength = 3
+	AliasMaxLength = 32
+)
+
+var (
+	ErrAliasInvalid  = errors.New("alias must be 3 to 32 characters long and contain only letters, numbers, '-' or '_'")
+	ErrAliasReserved = errors.New("alias is reserved")
+)
+
+// ReservedAliases holds the words that can't be used as codes, either
+// because they clash with the routes served next to the short links at the
+// root or could mislead users. Every top-level route must be listed.
+var ReservedAliases = []string{
+	"admin",
+	"all",
+	"api",
+	"docs",
+	"health",
+	"healthz",
+	"keys",
+	"login",
+	"logout",
+	"shorten",
+	"static",
+	"swagger",
+}
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ValidateAlias checks a custom alias against the character/length policy
+// and the reserved words list.
+func ValidateAlias(alias string) error {
+	if len(alias) < AliasMinLength || len(alias) > AliasMaxLength || !aliasPattern.MatchString(alias) {
+		return ErrAliasInvalid
+	}
+
+	if IsReserved(alias) {
+		return ErrAliasReserved
+	}
+
+	return nil
+}
+
+// IsReserved reports whether the code is one of the ReservedAliases, in any
+// case.
+func IsReserved(code string) bool {
+	for _, reserved := range ReservedAliases {
+		if strings.EqualFold(code, reserved) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +0,0 @@
-package utils
-
-import "math/rand"
-
-const characters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-
-func GenCode() string {
-	const n = 8
-	byts := make([]byte, n)
-	for i := range n {
-		byts[i] = characters[rand.Intn(len(characters))]
-	}
-
-	return string(byts)
-}
@@ -0,0 +1,32 @@
+package utils
+
+import "net/url"
+
+// MergeQuery adds params to the query of the target URL. The stored params
+// replace the ones of the target with the same name, while the forwarded
+// ones are only added when neither has them, so a visitor can't override
+// the params set on the link. The target is returned as is when there is
+// nothing to add or it can't be parsed.
+func MergeQuery(target string, stored map[string]string, forwarded url.Values) string {
+	if len(stored) == 0 && len(forwarded) == 0 {
+		return target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	query := u.Query()
+	for name, value := range stored {
+		query.Set(name, value)
+	}
+	for name, values := range forwarded {
+		if _, ok := query[name]; !ok {
+			query[name] = values
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeURL returns the form of a URL used to spot links to the same
+// target: lowercase scheme and host, no default port, "/" for an empty path
+// and the query params sorted by name. URLs without a host are returned as is.
+func NormalizeURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	if u.RawQuery != "" {
+		// Encode sorts by name, keeping the order of repeated params
+		u.RawQuery = u.Query().Encode()
+	}
+
+	return u.String()
+}
@@ -8,7 +8,10 @@ import (
 
 type ApiResponse struct {
 	Error string `json:"error,omitempty"`
-	Data  any    `json:"data,omitempty"`
+	// Reason is a machine readable code for some errors, like the rejected
+	// target URLs.
+	Reason string `json:"reason,omitempty"`
+	Data   any    `json:"data,omitempty"`
 }
 
 func SendJSON(w http.ResponseWriter, resp ApiResponse, status int) {
@@ -4,18 +4,83 @@ import (
 	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 )
 
-func TestGenCode(t *testing.T) {
-	code := GenCode()
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		alias       string
+		expectedErr error
+	}{
+		{alias: "launch-2026", expectedErr: nil},
+		{alias: "my_link", expectedErr: nil},
+		{alias: "ab", expectedErr: ErrAliasInvalid},
+		{alias: "-leading-dash", expectedErr: ErrAliasInvalid},
+		{alias: "with space", expectedErr: ErrAliasInvalid},
+		{alias: "abcdefghijklmnopqrstuvwxyz0123456", expectedErr: ErrAliasInvalid},
+		{alias: "shorten", expectedErr: ErrAliasReserved},
+		{alias: "ADMIN", expectedErr: ErrAliasReserved},
+	}
 
-	if code == "" {
-		t.Error("expected code to be non-empty")
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			err := ValidateAlias(tt.alias)
+			if err != tt.expectedErr {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+		})
 	}
+}
 
-	if len(code) != 8 {
-		t.Errorf("expected code to have length 8, got %d", len(code))
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "HTTPS://Example.COM/Path", expected: "https://example.com/Path"},
+		{url: "https://example.com:443/", expected: "https://example.com/"},
+		{url: "http://example.com:80", expected: "http://example.com/"},
+		{url: "http://example.com:8080/a", expected: "http://example.com:8080/a"},
+		{url: "https://example.com:80/", expected: "https://example.com:80/"},
+		{url: "https://example.com/?b=2&a=1&b=1", expected: "https://example.com/?a=1&b=2&b=1"},
+		{url: "https://[::1]:443/", expected: "https://[::1]/"},
+		{url: "https://example.com/#Section", expected: "https://example.com/#Section"},
+		{url: "mailto:someone@example.com", expected: "mailto:someone@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := NormalizeURL(tt.url); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMergeQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		stored    map[string]string
+		forwarded url.Values
+		expected  string
+	}{
+		{name: "nothing to add", target: "https://example.com/?b=2&a=1", expected: "https://example.com/?b=2&a=1"},
+		{name: "stored", target: "https://example.com/page", stored: map[string]string{"utm_source": "news", "utm_medium": "email"}, expected: "https://example.com/page?utm_medium=email&utm_source=news"},
+		{name: "stored replaces target", target: "https://example.com/?utm_source=old&id=1", stored: map[string]string{"utm_source": "news"}, expected: "https://example.com/?id=1&utm_source=news"},
+		{name: "forwarded", target: "https://example.com/", forwarded: url.Values{"ref": {"x"}, "tag": {"a", "b"}}, expected: "https://example.com/?ref=x&tag=a&tag=b"},
+		{name: "forwarded never replaces", target: "https://example.com/?id=1", stored: map[string]string{"utm_source": "news"}, forwarded: url.Values{"id": {"2"}, "utm_source": {"fake"}, "ref": {"x"}}, expected: "https://example.com/?id=1&ref=x&utm_source=news"},
+		{name: "fragment kept", target: "https://example.com/page#top", forwarded: url.Values{"ref": {"x"}}, expected: "https://example.com/page?ref=x#top"},
+		{name: "invalid target", target: "http://[::1", stored: map[string]string{"a": "1"}, expected: "http://[::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeQuery(tt.target, tt.stored, tt.forwarded); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
 	}
 }
 
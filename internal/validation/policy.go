@@ -0,0 +1,255 @@
+package validation
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultMaxLength = 2048
+)
+
+// DefaultSchemes are the schemes allowed when the policy sets none.
+var DefaultSchemes = []string{"http", "https"}
+
+// Reason tells, in a machine readable way, why a URL was rejected.
+type Reason string
+
+const (
+	ReasonRequired         Reason = "url_required"
+	ReasonTooLong          Reason = "url_too_long"
+	ReasonMalformed        Reason = "url_malformed"
+	ReasonSchemeNotAllowed Reason = "scheme_not_allowed"
+	ReasonHostRequired     Reason = "host_required"
+	ReasonPrivateAddress   Reason = "private_address"
+	ReasonOwnDomain        Reason = "own_domain"
+	ReasonDomainDenied     Reason = "domain_denied"
+	ReasonDomainNotAllowed Reason = "domain_not_allowed"
+	ReasonBlocked          Reason = "blocked"
+)
+
+// Error is returned for every URL the policy rejects.
+type Error struct {
+	Reason  Reason
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func reject(reason Reason, format string, args ...any) *Error {
+	return &Error{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// Blocklist reports whether a URL is blocked, and by which rule.
+type Blocklist interface {
+	Blocked(raw string) (rule string, blocked bool)
+}
+
+// Policy holds the rules a target URL must follow to be shortened. The zero
+// value allows http and https URLs up to DefaultMaxLength to any public host.
+type Policy struct {
+	// Schemes allowed, DefaultSchemes when empty.
+	Schemes []string
+	// MaxLength of the URL, DefaultMaxLength when zero.
+	MaxLength int
+	// OwnHosts are the hosts the short links are served from, rejected to
+	// avoid redirect loops.
+	OwnHosts []string
+	// Allow restricts the targets to these domains, subdomains included,
+	// when not empty.
+	Allow []string
+	// Deny rejects these domains, subdomains included.
+	Deny []string
+	// Blocklist rejects the URLs it blocks, when set.
+	Blocklist Blocklist
+}
+
+// ParseList splits a comma separated list, lowercasing and dropping the
+// empty entries.
+func ParseList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// WithOwnHosts returns a copy of the policy also rejecting the hosts, like
+// the domains registered at runtime.
+func (p Policy) WithOwnHosts(hosts ...string) Policy {
+	p.OwnHosts = append(slices.Clip(p.OwnHosts), hosts...)
+	return p
+}
+
+// Validate checks the URL against the policy, returning an *Error on
+// rejection.
+func (p Policy) Validate(raw string) error {
+	if raw == "" {
+		return reject(ReasonRequired, "URL is required")
+	}
+
+	maxLength := p.MaxLength
+	if maxLength == 0 {
+		maxLength = DefaultMaxLength
+	}
+	if len(raw) > maxLength {
+		return reject(ReasonTooLong, "URL must be at most %d characters long", maxLength)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return reject(ReasonMalformed, "invalid URL")
+	}
+
+	schemes := p.Schemes
+	if len(schemes) == 0 {
+		schemes = DefaultSchemes
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if !slices.Contains(schemes, scheme) {
+		return reject(ReasonSchemeNotAllowed, "URL scheme must be one of %s", strings.Join(schemes, ", "))
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return reject(ReasonHostRequired, "URL must have a host")
+	}
+
+	if isPrivateHost(host) {
+		return reject(ReasonPrivateAddress, "URL must not point to a private or local address")
+	}
+
+	for _, own := range p.OwnHosts {
+		if host == own {
+			return reject(ReasonOwnDomain, "URL must not point to this shortener")
+		}
+	}
+
+	for _, denied := range p.Deny {
+		if matchDomain(host, denied) {
+			return reject(ReasonDomainDenied, "URL domain %s is not allowed", host)
+		}
+	}
+
+	if len(p.Allow) > 0 && !slices.ContainsFunc(p.Allow, func(allowed string) bool {
+		return matchDomain(host, allowed)
+	}) {
+		return reject(ReasonDomainNotAllowed, "URL domain %s is not allowed", host)
+	}
+
+	if p.Blocklist != nil {
+		// the rule isn't told, so it can't be used to probe the blocklist
+		if _, blocked := p.Blocklist.Blocked(raw); blocked {
+			return reject(ReasonBlocked, "URL is blocked as potentially malicious")
+		}
+	}
+
+	return nil
+}
+
+// matchDomain reports whether the host is the domain or one of its
+// subdomains.
+func matchDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, private to the
+// providers but not covered by net.IP.IsPrivate.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPrivateHost reports whether the host is a loopback, private, shared,
+// link-local or unspecified IP address, or a localhost name. Names are not
+// resolved, a public name pointing to a private address goes through.
+func isPrivateHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		var numeric bool
+		ip, numeric = parseIPv4(host)
+		if numeric && ip == nil {
+			// browsers refuse such hosts, there's no point in keeping them
+			return true
+		}
+	}
+	if ip == nil {
+		return false
+	}
+
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		sharedAddressSpace.Contains(ip) ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+// parseIPv4 parses the host the way browsers do (WHATWG URL standard), where
+// the parts may be decimal, octal with a leading 0 or hexadecimal with a
+// leading 0x, and the last part fills the remaining bytes: 2130706433,
+// 0x7f.1 and 127.1 are all 127.0.0.1. It reports whether the host is numeric
+// at all, a numeric host out of range gives a nil IP.
+func parseIPv4(host string) (net.IP, bool) {
+	parts := strings.Split(host, ".")
+	if len(parts) > 1 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	if _, ok := parseIPv4Part(parts[len(parts)-1]); !ok {
+		return nil, false
+	}
+	if len(parts) > 4 {
+		return nil, true
+	}
+
+	var value uint64
+	for i, part := range parts {
+		n, ok := parseIPv4Part(part)
+		if !ok {
+			return nil, true
+		}
+		if i < len(parts)-1 {
+			if n > 0xff {
+				return nil, true
+			}
+			value |= n << (8 * (3 - i))
+			continue
+		}
+		if n >= 1<<(8*(5-len(parts))) {
+			return nil, true
+		}
+		value |= n
+	}
+
+	return net.IPv4(byte(value>>24), byte(value>>16), byte(value>>8), byte(value)), true
+}
+
+func parseIPv4Part(part string) (uint64, bool) {
+	base := 10
+	switch {
+	case len(part) >= 2 && (part[:2] == "0x" || part[:2] == "0X"):
+		part, base = part[2:], 16
+	case len(part) >= 2 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+	if part == "" {
+		// "0x" alone is zero
+		return 0, base == 16
+	}
+
+	n, err := strconv.ParseUint(part, base, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
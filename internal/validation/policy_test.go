@@ -0,0 +1,113 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	policy := Policy{
+		OwnHosts: []string{"sho.rt"},
+		Deny:     []string{"evil.com"},
+	}
+
+	tests := []struct {
+		url      string
+		expected Reason
+	}{
+		{url: "https://example.com/path?q=1"},
+		{url: "HTTP://Example.com."},
+		{url: "http://8.8.8.8/"},
+		{url: "", expected: ReasonRequired},
+		{url: "https://example.com/" + strings.Repeat("a", DefaultMaxLength), expected: ReasonTooLong},
+		{url: "http://[::1", expected: ReasonMalformed},
+		{url: "javascript:alert(1)", expected: ReasonSchemeNotAllowed},
+		{url: "ftp://example.com/file", expected: ReasonSchemeNotAllowed},
+		{url: "/relative/path", expected: ReasonSchemeNotAllowed},
+		{url: "bare", expected: ReasonSchemeNotAllowed},
+		{url: "http:///path", expected: ReasonHostRequired},
+		{url: "http://localhost:8080", expected: ReasonPrivateAddress},
+		{url: "http://api.localhost", expected: ReasonPrivateAddress},
+		{url: "http://127.0.0.1", expected: ReasonPrivateAddress},
+		{url: "http://10.1.2.3", expected: ReasonPrivateAddress},
+		{url: "http://192.168.0.1", expected: ReasonPrivateAddress},
+		{url: "http://169.254.169.254/latest/meta-data", expected: ReasonPrivateAddress},
+		{url: "http://[::1]/", expected: ReasonPrivateAddress},
+		{url: "http://[fe80::1]/", expected: ReasonPrivateAddress},
+		{url: "http://0.0.0.0", expected: ReasonPrivateAddress},
+		{url: "http://100.64.0.1", expected: ReasonPrivateAddress},
+		{url: "http://2130706433/", expected: ReasonPrivateAddress},
+		{url: "http://0x7f.1/", expected: ReasonPrivateAddress},
+		{url: "http://127.1/", expected: ReasonPrivateAddress},
+		{url: "http://0177.0.0.1/", expected: ReasonPrivateAddress},
+		{url: "http://0xa.0x10203/", expected: ReasonPrivateAddress},
+		{url: "http://0/", expected: ReasonPrivateAddress},
+		{url: "http://127.0.0.256/", expected: ReasonPrivateAddress},
+		{url: "http://134744072/"},
+		{url: "https://SHO.RT/abc", expected: ReasonOwnDomain},
+		{url: "https://evil.com", expected: ReasonDomainDenied},
+		{url: "https://www.evil.com", expected: ReasonDomainDenied},
+		{url: "https://notevil.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := policy.Validate(tt.url)
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *Error
+			require.True(t, errors.As(err, &validationErr), "expected a validation error, got %v", err)
+			assert.Equal(t, tt.expected, validationErr.Reason)
+		})
+	}
+}
+
+func TestPolicyValidate_Allow(t *testing.T) {
+	policy := Policy{
+		Schemes:   []string{"https"},
+		MaxLength: 40,
+		Allow:     []string{"example.com", "example.org"},
+	}
+
+	assert.NoError(t, policy.Validate("https://example.com"))
+	assert.NoError(t, policy.Validate("https://docs.example.org/a"))
+
+	var validationErr *Error
+	require.ErrorAs(t, policy.Validate("https://example.net"), &validationErr)
+	assert.Equal(t, ReasonDomainNotAllowed, validationErr.Reason)
+
+	require.ErrorAs(t, policy.Validate("http://example.com"), &validationErr)
+	assert.Equal(t, ReasonSchemeNotAllowed, validationErr.Reason)
+
+	require.ErrorAs(t, policy.Validate("https://example.com/a-rather-long-path-indeed"), &validationErr)
+	assert.Equal(t, ReasonTooLong, validationErr.Reason)
+}
+
+type stubBlocklist map[string]bool
+
+func (b stubBlocklist) Blocked(raw string) (string, bool) {
+	return "url:" + raw, b[raw]
+}
+
+func TestPolicyValidate_Blocklist(t *testing.T) {
+	policy := Policy{Blocklist: stubBlocklist{"https://phishing.example/login": true}}
+
+	assert.NoError(t, policy.Validate("https://example.com"))
+
+	var validationErr *Error
+	require.ErrorAs(t, policy.Validate("https://phishing.example/login"), &validationErr)
+	assert.Equal(t, ReasonBlocked, validationErr.Reason)
+	assert.NotContains(t, validationErr.Message, "url:")
+}
+
+func TestParseList(t *testing.T) {
+	assert.Equal(t, []string{"example.com", "example.org"}, ParseList(" Example.com, ,example.org,"))
+	assert.Nil(t, ParseList(""))
+}